@@ -1,3 +1,4 @@
+// hand written client. generate a typed one of your apis and data keys with codegen.WriteTypeScriptFile
 import axios from "axios";
 var msgpack = require('@ygoe/msgpack');
 const JwtRequest = (headers: any = {}) => {
@@ -37,37 +38,41 @@ const SignOut = (e: any) => {
     localStorage.setItem("jwt", JSON.stringify({ jwt, sub, id, LastGetJwtTime }));
 }
 export enum Action { GET, PUT, DELETE, }
-export enum RspType { json = "&RspType=application/json", jpeg = "&RspType=image/jpeg", ogg = "&RspType=audio/ogg", mpeg = "&RspType=video/mpeg", mp4 = "&RspType=video/mp4", none = "", text = "&RspType=text/plain", stream = "&RspType=application/octet-stream" }
+export enum RspType { json = "-!JSON", jpeg = "-!JPG", ogg = "-!OGG", mpeg = "-!MPEG", mp4 = "-!MP4", none = "", text = "-!TEXT", stream = "-!STREAM" }
 const Url = "https://api.iam26.com:3080/rSvc"
 export enum Cmd { HEXISTS = "HEXISTS", HGET = "HGET", HGETALL = "HGETALL", HMGET = "HMGET" }
-export const GetUrl = (cmd = Cmd.HGET, Key: string, Field: string = "", rspType: RspType = RspType.json, Queries: string = "") =>
-    `${Url}?Cmd=${cmd}&Key=${Key}&Field=${Field}&Queries=${Queries}${rspType}`
+// i.g. https://api.iam26.com:3080/rSvc/HGET-!UserAvatar-!JPG?F=fa4Y3oyQk2swURaJ
+const CmdUrl = (cmd: string, Key: string, rspType: RspType = RspType.json, params: { [key: string]: any } = {}) => {
+    let query = Object.keys(params).filter((k) => params[k] !== undefined && params[k] !== "").map((k) => `${k}=${encodeURIComponent(params[k])}`).join("&")
+    return `${Url}/${cmd}-!${encodeURIComponent(Key)}${rspType}${!!query ? "?" + query : ""}`
+}
+export const GetUrl = (cmd = Cmd.HGET, Key: string, Field: string = "", rspType: RspType = RspType.json) =>
+    CmdUrl(cmd, Key, rspType, { F: Field })
 
 export const HEXISTS = (Key: string, Field: string = "") =>
-    JwtRequest().get(`${Url}?Cmd=HEXISTS&Key=${Key}&Field=${Field}&Queries=*&RspType=application/json`)
+    JwtRequest().get(CmdUrl("HEXISTS", Key, RspType.json, { F: Field }))
 
-export const HSET = (Key: string, Field: string = "", data: any, rspType: RspType = RspType.json, Queries: string = "") =>
-    JwtRequest().put(`${Url}?Cmd=HSET&Key=${Key}&Field=${Field}&Queries=${Queries}${rspType}`, data)
+export const HSET = (Key: string, Field: string = "", data: any, rspType: RspType = RspType.json) =>
+    JwtRequest().put(CmdUrl("HSET", Key, rspType, { F: Field }), data)
 
-export const HGET = (Key: string, Field: string = "", Queries: string = "*", rspType: RspType = RspType.json) =>
-    JwtRequest().get(`${Url}?Cmd=HGET&Key=${Key}&Field=${Field}${!!Queries ? ("&Queries=" + Queries) : ""}${rspType}`)
-export const HGETALL = (Key: string, Queries: string = "*", rspType: RspType = RspType.json) =>
-    JwtRequest().get(`${Url}?Cmd=HGETALL&Key=${Key}${!!Queries ? ("&Queries=" + Queries) : ""}${rspType}`)
+export const HGET = (Key: string, Field: string = "", rspType: RspType = RspType.json) =>
+    JwtRequest().get(CmdUrl("HGET", Key, rspType, { F: Field }))
+export const HGETALL = (Key: string, rspType: RspType = RspType.json) =>
+    JwtRequest().get(CmdUrl("HGETALL", Key, rspType))
 export const HKEYS = (Key: string) =>
-    JwtRequest().get(`${Url}?Cmd=HKEYS&Key=${Key}&Queries=*&RspType=application/json`)
-export const HMGET = (Key: string, Fields: string[] = [], Queries: string = "*") =>
-    JwtRequest().get(`${Url}?Cmd=HMGET&Key=${Key}&Field=${Fields.join(",")}${!!Queries ? ("&Queries=" + Queries) : ""}&RspType=application/json`)
+    JwtRequest().get(CmdUrl("HKEYS", Key))
+export const HMGET = (Key: string, Fields: string[] = []) =>
+    JwtRequest().get(CmdUrl("HMGET", Key, RspType.json, { F: Fields.join(",") }))
 
 export const ZRange = (Key: string, Start: number, Stop: number, WITHSCORES: boolean) =>
-    JwtRequest().get(`${Url}?Cmd=ZRANGE&Key=${Key}&Start=${Start}&Stop=${Stop}&WITHSCORES=${WITHSCORES}&Queries=*&RspType=application/json`)
+    JwtRequest().get(CmdUrl("ZRANGE", Key, RspType.json, { Start, Stop, WITHSCORES }))
 export const ZRank = (Key: string, Member: string) =>
-    JwtRequest().get(`${Url}?Cmd=ZRANK&Key=${Key}&Member=${Member}&Queries=*&RspType=application/json`)
+    JwtRequest().get(CmdUrl("ZRANK", Key, RspType.json, { Member }))
 export const ZRANGEBYSCORE = (Key: string, Min: number, Max: number, WITHSCORES: boolean) =>
-    JwtRequest().get(`${Url}?Cmd=ZRANGEBYSCORE&Key=${Key}&Min=${Min}&Max=${Max}&WITHSCORES=${WITHSCORES}&Queries=*&RspType=application/json`)
-
-export const SISMEMBER = (Key: string, Member: string) => JwtRequest().get(`${Url}?Cmd=SISMEMBER&Key=${Key}&Member=${Member}`)
-export const HDEL = async (Key: string, Field: string = "", rspType: RspType = RspType.json, Queries: string = "") =>
-    JwtRequest().delete(`${Url}?Cmd=HDEL&Key=${Key}&Field=${Field}&Queries=${Queries}${rspType}`)
-export const Service = async (Service: string, data: any, Queries: string = "*", rspType: RspType = RspType.json) =>
-    JwtRequest().post(`${Url}?Service=${Service}&Queries=${Queries}${rspType}`, data)
+    JwtRequest().get(CmdUrl("ZRANGEBYSCORE", Key, RspType.json, { Min, Max, WITHSCORES }))
 
+export const SISMEMBER = (Key: string, Member: string) => JwtRequest().get(CmdUrl("SISMEMBER", Key, RspType.json, { Member }))
+export const HDEL = async (Key: string, Field: string = "", rspType: RspType = RspType.json) =>
+    JwtRequest().delete(CmdUrl("HDEL", Key, rspType, { F: Field }))
+export const Service = async (Service: string, data: any, rspType: RspType = RspType.json) =>
+    JwtRequest().post(CmdUrl("API", Service, rspType), data)
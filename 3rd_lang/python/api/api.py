@@ -2,9 +2,20 @@
 from .rds import rds
 import msgpack
 import datetime
+import hashlib
+import threading
 import time
 #__all__ = ['api']
 
+# stream protocol, see specification.ProtocolVersion of the go package, and test/testdata/protocol.json
+PROTOCOL_VERSION = 1
+# seconds the reply list lives, specification.ReplyExpire
+REPLY_EXPIRE = 20
+# seconds the result of a call with idempotency key is kept, config Api.IdempotencyRetention
+IDEMPOTENCY_RETENTION = 86400
+# seconds the map of an in-flight call with idempotency key lives, so that a crashed call does not block the key
+IDEMPOTENCY_IN_FLIGHT_TTL = 60
+
 
 class api():
     service_names = []
@@ -54,6 +65,7 @@ class api():
     def receiveJobs():
         global rds
         api.XGroupCreate()
+        api.delay_tasks_load()
         streams = dict(zip(api.service_names, [">"]*len(api.service_names)))
         while True:
             try:
@@ -69,11 +81,26 @@ class api():
                     for message in stream[1]:
                         id = message[0]
                         _messege = message[1]
-                        param = msgpack.unpackb(_messege[b'data'])
+                        data = _messege.get(b'data', b'')
                         if b"timeAt" in _messege:
-                            timeAtStr = _messege[b"timeAt"]
-                            api.rpcCallAtTaskAddOne(
-                                apiName, timeAtStr, param)
+                            # timeAt is unix nanoseconds. empty data cancels the task
+                            timeAtStr = _messege[b"timeAt"].decode("utf-8")
+                            if len(data) == 0:
+                                api.delay_task_cancel(apiName, timeAtStr)
+                            else:
+                                api.rpcCallAtTaskAddOne(apiName, timeAtStr, data)
+                            continue
+                        # placeholder message, which creates the stream
+                        if len(data) == 0:
+                            continue
+                        param = msgpack.unpackb(data)
+                        # W3C traceparent of the caller, pass it on to Do() to join the trace
+                        if b"traceparent" in _messege and isinstance(param, dict):
+                            param["HeaderTraceparent"] = _messege[b"traceparent"].decode("utf-8")
+                        # calls with idempotency key run once per api and key
+                        idemKey = _messege.get(b"idem", b"").decode("utf-8")
+                        if len(idemKey) > 0:
+                            api.call_idempotent(apiName, idemKey, id, param, data)
                         else:
                             api.ApiFunc[apiName](id, param, api.send_back)
                         api.task_num_in_60s[apiName]+=1
@@ -89,27 +116,76 @@ class api():
         packer = msgpack.Packer(
             use_single_float=use_single_float, use_bin_type=use_bin_type)
         pipe.rpush(id, packer.pack(output))
-        pipe.expire(id, 6)
+        pipe.expire(id, REPLY_EXPIRE)
         pipe.execute()
 
+    def call_idempotent(serviceName, idemKey, id, param, data):
+        # see idempotency of specification.ProtocolVersion. input is the sha256 of the data field
+        global rds
+        key = serviceName + ":idem:" + idemKey
+        digest = hashlib.sha256(data).hexdigest()
+        if rds.set(key, msgpack.packb({"input": digest}), nx=True, ex=IDEMPOTENCY_IN_FLIGHT_TTL):
+            def send_back_kept(id, output, use_single_float=False, use_bin_type=True):
+                packer = msgpack.Packer(
+                    use_single_float=use_single_float, use_bin_type=use_bin_type)
+                rds.set(key, packer.pack({"input": digest, "done": True, "result": output}), ex=IDEMPOTENCY_RETENTION)
+                api.send_back(id, output, use_single_float, use_bin_type)
+            try:
+                api.ApiFunc[serviceName](id, param, send_back_kept)
+            except Exception:
+                # a failed call is not kept, so that it can be retried with the same key
+                rds.delete(key)
+                raise
+            return
+        # the call is done or in flight. wait in another thread, not to block reading the streams
+        threading.Thread(target=api.wait_idempotent, args=(serviceName, idemKey, id, param, data), daemon=True).start()
+
+    def wait_idempotent(serviceName, idemKey, id, param, data):
+        global rds
+        key = serviceName + ":idem:" + idemKey
+        digest = hashlib.sha256(data).hexdigest()
+        deadline = time.time() + REPLY_EXPIRE
+        while time.time() < deadline:
+            stored = rds.get(key)
+            if stored == None:
+                # the in-flight call failed, take it over
+                api.call_idempotent(serviceName, idemKey, id, param, data)
+                return
+            call = msgpack.unpackb(stored)
+            if call.get("input") != digest:
+                # the key is reused with another input, which is not run
+                return
+            if call.get("done"):
+                api.send_back(id, call.get("result"))
+                return
+            time.sleep(0.05)
+
     def rpcCallAtTaskAddOne(serviceName, timeAtStr, bytesValue):
         global rds
         rds.hset(serviceName+":delay", timeAtStr, bytesValue)
-        api.rpcCallAtTaskDoOne(serviceName, timeAtStr)
+        api.delay_task_schedule(serviceName, timeAtStr)
+
+    def delay_task_schedule(serviceName, timeAtStr):
+        # timeAt is unix nanoseconds
+        delay = (int(timeAtStr) - time.time_ns()) / 1e9
+        threading.Timer(max(delay, 0), api.delay_task_do_one, args=(serviceName, timeAtStr)).start()
+
+    def delay_task_cancel(serviceName, timeAtStr):
+        global rds
+        rds.hdel(serviceName+":delay", timeAtStr)
 
     def delay_task_do_one(serviceName, timeAtStr):
         global rds
-        nowUnixMilliSecond = time.time() * 1000
-        timeAtUnixMilliSecond = int(timeAtStr)
-        time.sleep((timeAtUnixMilliSecond-nowUnixMilliSecond) / 1000)
-        rds.hget(serviceName+":delay", timeAtStr)
-        api.rds.hdel(serviceName+":delay", timeAtStr)
-        if bytes != None:
-            api.ApiFun[serviceName](None, bytes, api.send_back)
+        pipe = rds.pipeline()
+        pipe.hget(serviceName+":delay", timeAtStr)
+        pipe.hdel(serviceName+":delay", timeAtStr)
+        data, _ = pipe.execute()
+        # None if canceled, or run by another worker
+        if data:
+            api.ApiFunc[serviceName](None, msgpack.unpackb(data), api.send_back)
 
     def delay_tasks_load():
-        services = api.ApiFun.keys()
-        for service in services:
-            timeAtStrs = api.rds.hkeys(service+":delay")
-            for timeAtStr in timeAtStrs:
-                api.delay_task_do_one(service, timeAtStr)
+        global rds
+        for service in api.ApiFunc.keys():
+            for timeAtStr in rds.hkeys(service+":delay"):
+                api.delay_task_schedule(service, timeAtStr.decode("utf-8"))
@@ -3,11 +3,14 @@ from .rds import rds
 #__all__ = ["Do","DoAt"]
 
 
-def DoAt(ServiceKey, paramIn, timeAt):
+def DoAt(ServiceKey, paramIn, timeAt, traceparent=None):
     global rds
     _fields = {"data": msgpack.packb(paramIn)}
     if timeAt != 0:
         _fields.update({"timeAt": str(timeAt)})
+    # W3C traceparent, i.g. paramIn["HeaderTraceparent"] received by the calling api
+    if traceparent:
+        _fields.update({"traceparent": traceparent})
 
     # ServiceKey
     if ServiceKey[:4] != "api:":
@@ -23,5 +26,5 @@ def DoAt(ServiceKey, paramIn, timeAt):
     return msgpack.unpackb(results[1])
 
 
-def Do(ServiceKey, paramIn):
-    return DoAt(ServiceKey, paramIn, 0)
+def Do(ServiceKey, paramIn, traceparent=None):
+    return DoAt(ServiceKey, paramIn, 0, traceparent)
@@ -0,0 +1,4 @@
+
+def api_conformanceEcho(id, i, send_back):
+    # replies the input, for: saavuuctl conformance conformanceEcho
+    send_back(id, i)
@@ -42,6 +42,7 @@ func Api[i any, o any](f func(InParameter i) (ret o, err error), options ...ApiO
 	if _, ok := specification.DisAllowedServiceNames[option.Name]; ok {
 		log.Error().Str("service misnamed", option.Name).Send()
 	}
+	option.Name = option.namespaced(option.Name)
 
 	log.Debug().Str("Api service create start. name", option.Name).Send()
 	NonEmptyOrZeroToCheck = fieldsToCheck(reflect.TypeOf(new(i)).Elem())
@@ -88,15 +89,18 @@ func Api[i any, o any](f func(InParameter i) (ret o, err error), options ...ApiO
 		Name:                      option.Name,
 		DataSource:                option.DataSource,
 		WithHeader:                HeaderFieldsUsed(new(i)),
-		ApiFuncWithMsgpackedParam: ProcessOneJob,
+		Coalesce:                  option.Coalesce,
+		MaxLen:                    option.MaxLen,
+		ApproxTrim:                option.ApproxTrim,
+		HighWaterMark:             option.HighWaterMark,
+		ApiFuncWithMsgpackedParam: observed(option.Name, ProcessOneJob),
 		Ctx:                       context.Background(),
+		InType:                    reflect.TypeOf((*i)(nil)).Elem(),
+		OutType:                   reflect.TypeOf((*o)(nil)).Elem(),
 	}
-	ApiServices.Set(option.Name, apiInfo)
+	registerApi(apiInfo)
 	funcPtr := reflect.ValueOf(f).Pointer()
 	fun2ApiInfoMap.Store(funcPtr, apiInfo)
-	APIGroupByDataSource.Upsert(option.DataSource, []string{}, func(exist bool, valueInMap, newValue []string) []string {
-		return append(valueInMap, option.Name)
-	})
 	log.Debug().Str("ApiNamed service created completed!", option.Name).Send()
 	//return Api context
 	return f
@@ -6,24 +6,47 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
 	"github.com/yangkequn/saavuu/specification"
+	"github.com/yangkequn/saavuu/tracing"
 )
 
-func CallByHTTP(ServiceName string, paramIn map[string]interface{}, req *http.Request) (ret interface{}, err error) {
+// HeaderRequestId is the input field that receives the X-Request-ID of the http request, for apis using header fields
+const HeaderRequestId = "HeaderRequestId"
+
+// options are optional, used to pass call level settings such as the idempotency key
+func CallByHTTP(ServiceName string, paramIn map[string]interface{}, req *http.Request, options ...*ApiOption) (ret interface{}, err error) {
 	var (
 		apiInfo *ApiInfo
 		ok      bool
 		buf     []byte
+		keyBuf  []byte
+		rds     *redis.Client
+		option  *ApiOption = &ApiOption{}
 	)
+	if len(options) > 0 && options[0] != nil {
+		option = options[0]
+	}
 	if ServiceName = specification.ApiName(ServiceName); len(ServiceName) == 0 {
 		return nil, fmt.Errorf("service misnamed %s", ServiceName)
 	}
+	ServiceName = option.namespaced(ServiceName)
 	//if function is stored locally, call it directly. This is alias monolithic mode
 	if apiInfo, ok = ApiServices.Get(ServiceName); !ok {
 		//if function is not stored locally, call it remotely (RPC). This is alias microservice mode
-		var rpc = Rpc[interface{}, interface{}](Option.WithName(ServiceName).WithDataSource(apiInfo.DataSource))
+		var rpc = Rpc[interface{}, interface{}](option.WithName(ServiceName))
+		if rpc == nil {
+			return nil, fmt.Errorf("DataSource not defined in enviroment %s", option.DataSource)
+		}
 		return rpc(paramIn)
 	}
+	//identical calls coalesce, and retries match, whatever the headers of the requests are, as calls through the stream do
+	if len(option.IdempotencyKey) > 0 || apiInfo.Coalesce {
+		if keyBuf, err = specification.MarshalApiInput(paramIn); err != nil {
+			return nil, err
+		}
+	}
 	if apiInfo.WithHeader {
 		//copy fields from req to paramIn
 		for key, value := range req.Header {
@@ -39,13 +62,29 @@ func CallByHTTP(ServiceName string, paramIn map[string]interface{}, req *http.Re
 		paramIn["Header"+"Method"] = req.Method
 		paramIn["Header"+"Path"] = req.URL.Path
 		paramIn["Header"+"Query"] = req.URL.RawQuery
-
+		paramIn[HeaderRequestId] = req.Header.Get("X-Request-ID")
+	}
+	span := tracing.Start(option.TraceParent, "handler "+ServiceName)
+	defer func() { span.Finish(err) }()
+	if apiInfo.WithHeader {
+		paramIn[HeaderTraceparent] = span.TraceParent()
 	}
 	//if function is stored locally, call it directly. This is alias monolithic mode
 	if buf, err = specification.MarshalApiInput(paramIn); err != nil {
 		return nil, err
 	}
-	return apiInfo.ApiFuncWithMsgpackedParam(buf)
+	if len(option.IdempotencyKey) == 0 && !apiInfo.Coalesce {
+		return apiInfo.ApiFuncWithMsgpackedParam(buf)
+	}
+	if rds, ok = config.Rds[apiInfo.DataSource]; !ok {
+		return nil, fmt.Errorf("DataSource not defined in enviroment %s", apiInfo.DataSource)
+	}
+	call := func() (interface{}, error) { return apiInfo.ApiFuncWithMsgpackedParam(buf) }
+	if len(option.IdempotencyKey) > 0 {
+		return callIdempotent(rds, ServiceName, option.IdempotencyKey, keyBuf, call)
+	}
+	ret, _, err = callCoalesced(rds, ServiceName, keyBuf, "", call)
+	return ret, err
 }
 
 func HeaderFieldsUsed[i any](param i) bool {
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	cmap "github.com/orcaman/concurrent-map/v2"
@@ -14,9 +15,18 @@ type ApiInfo struct {
 	Name       string
 	DataSource string
 	WithHeader bool
-	Ctx        context.Context
+	// Coalesce is true if identical in-flight calls share one result
+	Coalesce bool
+	// MaxLen and ApproxTrim are the capacity of the stream of the api. 0 means config.Cfg.Api.StreamMaxLen
+	MaxLen     int64
+	ApproxTrim bool
+	// HighWaterMark is the backlog beyond which calls are rejected with ErrOverloaded. 0 means config.Cfg.Api.HighWaterMark
+	HighWaterMark int64
+	Ctx           context.Context
 	// ApiFuncWithMsgpackedParam is the function of the service
 	ApiFuncWithMsgpackedParam func(s []byte) (ret interface{}, err error)
+	// InType and OutType are the parameter and return types of the api function. used by code generators
+	InType, OutType reflect.Type
 }
 
 var ApiServices cmap.ConcurrentMap[string, *ApiInfo] = cmap.New[*ApiInfo]()
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var ErrCoalescedCallFailed = errors.New("the in-flight call shared by identical calls failed")
+
+// the marker lives no longer than the reply list of a rpc call, so that a crashed leader does not hold followers for long
+const coalescingMarkerTTL = time.Second * 20
+
+// register the caller to the waiting list, only if the leader is still in flight
+var coalescingJoinScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then return 0 end
+redis.call("RPUSH", KEYS[2], ARGV[1])
+redis.call("PEXPIRE", KEYS[2], ARGV[2])
+return 1`)
+
+// release the marker, and take away all the callers waiting for the result
+var coalescingFinishScript = redis.NewScript(`
+local waiters = redis.call("LRANGE", KEYS[2], 0, -1)
+redis.call("DEL", KEYS[1], KEYS[2])
+return waiters`)
+
+type coalescingFlight struct {
+	done chan struct{}
+	ret  interface{}
+	err  error
+}
+
+var coalescingFlights = map[string]*coalescingFlight{}
+var coalescingMut sync.Mutex
+
+// identical param should have identical key, so map keys are sorted before hashing
+func coalescingRedisKey(apiName string, param []byte) string {
+	var (
+		obj interface{}
+		buf bytes.Buffer
+	)
+	if err := msgpack.Unmarshal(param, &obj); err == nil {
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetSortMapKeys(true)
+		if err = enc.Encode(obj); err == nil {
+			param = buf.Bytes()
+		}
+	}
+	sum := sha1.Sum(param)
+	return apiName + ":sf:" + hex.EncodeToString(sum[:])
+}
+
+// callCoalesced runs f once for identical param in flight.
+// backToID is the reply list of the caller from the stream. if the call joins an in-flight one, the result is sent back to backToID by the leader, and coalesced is true.
+// if backToID is empty, the call is local, and it waits for the result of the in-flight one.
+func callCoalesced(rds *redis.Client, apiName string, param []byte, backToID string, f func() (ret interface{}, err error)) (ret interface{}, coalesced bool, err error) {
+	var key = coalescingRedisKey(apiName, param)
+	if len(backToID) > 0 {
+		return callCoalescedAcrossInstances(rds, key, backToID, f)
+	}
+	//local calls in the same process share one flight, without visiting redis
+	coalescingMut.Lock()
+	if flight, ok := coalescingFlights[key]; ok {
+		coalescingMut.Unlock()
+		<-flight.done
+		return flight.ret, false, flight.err
+	}
+	flight := &coalescingFlight{done: make(chan struct{})}
+	coalescingFlights[key] = flight
+	coalescingMut.Unlock()
+
+	flight.ret, _, flight.err = callCoalescedAcrossInstances(rds, key, "", f)
+
+	coalescingMut.Lock()
+	delete(coalescingFlights, key)
+	coalescingMut.Unlock()
+	close(flight.done)
+	return flight.ret, false, flight.err
+}
+
+func callCoalescedAcrossInstances(rds *redis.Client, key, backToID string, f func() (ret interface{}, err error)) (ret interface{}, coalesced bool, err error) {
+	var (
+		ctx     = context.Background()
+		leader  bool
+		joined  int64
+		waiters []string
+		err2    error
+		results []string
+		b       []byte
+	)
+	for deadline := time.Now().Add(coalescingMarkerTTL); time.Now().Before(deadline); {
+		//redis not available, coalescing is not possible
+		if leader, err = rds.SetNX(ctx, key, "", coalescingMarkerTTL).Result(); err != nil {
+			ret, err = f()
+			return ret, false, err
+		}
+		if leader {
+			ret, err = f()
+			if waiters, err2 = coalescingFinishScript.Run(ctx, rds, []string{key, key + ":w"}).StringSlice(); err2 != nil {
+				log.Info().AnErr("coalescing finish", err2).Str("key", key).Send()
+			} else if len(waiters) > 0 {
+				//empty result tells the waiters that the call failed
+				if b = []byte{}; err == nil {
+					b, _ = msgpack.Marshal(ret)
+				}
+				pipeline := rds.Pipeline()
+				for _, waiter := range waiters {
+					pipeline.RPush(ctx, waiter, b)
+					pipeline.Expire(ctx, waiter, specification.ReplyExpire)
+				}
+				pipeline.Exec(ctx)
+			}
+			return ret, false, err
+		}
+
+		//join the in-flight call. the result will be pushed to the reply list
+		replyTo := backToID
+		if len(replyTo) == 0 {
+			replyTo = key + ":" + strconv.FormatInt(rand.Int63(), 36)
+		}
+		if joined, err = coalescingJoinScript.Run(ctx, rds, []string{key, key + ":w"}, replyTo, coalescingMarkerTTL.Milliseconds()).Int64(); err != nil {
+			return nil, false, err
+		} else if joined == 0 {
+			//the leader finished just now, try to lead
+			continue
+		}
+		if len(backToID) > 0 {
+			return nil, true, nil
+		}
+		if results, err = rds.BLPop(ctx, time.Until(deadline), replyTo).Result(); err != nil {
+			return nil, false, err
+		}
+		if len(results) != 2 || len(results[1]) == 0 {
+			return nil, false, ErrCoalescedCallFailed
+		}
+		return ret, false, msgpack.Unmarshal([]byte(results[1]), &ret)
+	}
+	return nil, false, ErrCoalescedCallFailed
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var ErrApiNotFound = errors.New("api not found")
+
+// DeadLetter is a call read from the stream, whose handler returned an error
+type DeadLetter struct {
+	// ID is the id in the dead letter stream
+	ID string
+	// CallID is the id of the call in the api stream
+	CallID      string
+	Error       string
+	Data        []byte
+	IdemKey     string
+	TraceParent string
+	FailedAt    string
+}
+
+// sendToDeadLetter keeps the failed call in the dead letter stream of the api, so that it can be inspected and replayed
+func sendToDeadLetter(rds *redis.Client, apiName, callID, idemKey, traceParent string, s []byte, callErr error) {
+	values := []string{specification.FieldData, string(s), "callId", callID, "error", callErr.Error(), "failedAt", time.Now().Format(time.RFC3339Nano)}
+	if len(idemKey) > 0 {
+		values = append(values, specification.FieldIdempotencyKey, idemKey)
+	}
+	if len(traceParent) > 0 {
+		values = append(values, specification.FieldTraceParent, traceParent)
+	}
+	if cmd := rds.XAdd(context.Background(), xAddArgs(nil, specification.ApiDeadLetterStreamName(apiName), values)); cmd.Err() != nil {
+		log.Info().AnErr("dead letter XAdd", cmd.Err()).Str("api", apiName).Send()
+	}
+}
+
+// DeadLetters returns the latest failed calls of the api, newest first. count <= 0 means all
+func DeadLetters(apiName string, count int64) (letters []*DeadLetter, err error) {
+	var (
+		rds      *redis.Client
+		messages []redis.XMessage
+	)
+	if rds, err = apiRds(apiName); err != nil {
+		return nil, err
+	}
+	stream := specification.ApiDeadLetterStreamName(apiName)
+	if count > 0 {
+		messages, err = rds.XRevRangeN(context.Background(), stream, "+", "-", count).Result()
+	} else {
+		messages, err = rds.XRevRange(context.Background(), stream, "+", "-").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		letters = append(letters, deadLetterOf(message))
+	}
+	return letters, nil
+}
+
+func deadLetterOf(message redis.XMessage) *DeadLetter {
+	str := func(field string) string {
+		s, _ := message.Values[field].(string)
+		return s
+	}
+	return &DeadLetter{ID: message.ID, CallID: str("callId"), Error: str("error"), Data: []byte(str(specification.FieldData)),
+		IdemKey: str(specification.FieldIdempotencyKey), TraceParent: str(specification.FieldTraceParent), FailedAt: str("failedAt")}
+}
+
+// ReplayDeadLetters puts the failed calls back to the api stream, and removes them from the dead letter stream.
+// the results of replayed calls are not waited for
+func ReplayDeadLetters(apiName string, ids ...string) (replayed int, err error) {
+	var (
+		rds      *redis.Client
+		messages []redis.XMessage
+		ctx      = context.Background()
+	)
+	if rds, err = apiRds(apiName); err != nil {
+		return 0, err
+	}
+	stream := specification.ApiDeadLetterStreamName(apiName)
+	for _, id := range ids {
+		if messages, err = rds.XRange(ctx, stream, id, id).Result(); err != nil {
+			return replayed, err
+		}
+		for _, message := range messages {
+			letter := deadLetterOf(message)
+			values := specification.CallFields(letter.Data, letter.IdemKey, letter.TraceParent)
+			if err = rds.XAdd(ctx, xAddArgs(apiLimits(apiName, nil), apiName, values)).Err(); err != nil {
+				return replayed, err
+			}
+			if err = rds.XDel(ctx, stream, message.ID).Err(); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+// apiRds returns the redis client of the data source of the api
+func apiRds(apiName string) (rds *redis.Client, err error) {
+	apiInfo, ok := ApiServices.Get(apiName)
+	if !ok {
+		return nil, ErrApiNotFound
+	}
+	return config.GetRdsClientByName(apiInfo.DataSource)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var (
+	ErrIdempotentCallInFlight = errors.New("call with the same idempotency key is still in flight")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key is reused with another input")
+)
+
+// HeaderIdempotencyKey is the input field that Rpc sends as the idempotency key of the call, instead of ApiOption.IdempotencyKey.
+// unlike the option, fixed when the Rpc func is created, it can differ call by call
+const HeaderIdempotencyKey = "HeaderIdempotencyKey"
+
+// the placeholder of an in-flight call expires quickly, so that a crashed call does not block the key for the whole retention window
+const idempotencyInFlightTTL = time.Minute
+
+// wait no longer than the reply list of a rpc call lives
+const idempotencyWaitTimeout = time.Second * 20
+const idempotencyPollInterval = time.Millisecond * 50
+
+// idempotentCall is stored msgpack encoded at the idempotency key: the hash of the input, and the msgpacked result once done.
+// see idempotency of specification.ProtocolVersion
+type idempotentCall struct {
+	Input  string             `msgpack:"input"`
+	Done   bool               `msgpack:"done"`
+	Result msgpack.RawMessage `msgpack:"result,omitempty"`
+}
+
+// inputHash is the sha256 of the msgpack encoded input, with map keys sorted, so that the same input has the same hash.
+// JWT_ and Header fields are left out, as they vary between retries of the same call, i.g. by a refreshed token
+func inputHash(input []byte) string {
+	var value interface{}
+	if err := msgpack.Unmarshal(input, &value); err == nil {
+		if m, ok := value.(map[string]interface{}); ok {
+			for field := range m {
+				if strings.HasPrefix(field, "JWT_") || strings.HasPrefix(field, "Header") {
+					delete(m, field)
+				}
+			}
+		}
+		var buf bytes.Buffer
+		encoder := msgpack.NewEncoder(&buf)
+		encoder.SetSortMapKeys(true)
+		if err = encoder.Encode(value); err == nil {
+			input = buf.Bytes()
+		}
+	}
+	sum := sha256.Sum256(input)
+	return hex.EncodeToString(sum[:])
+}
+
+// callIdempotent runs f at most once for the same api and idempotency key.
+// the first caller holds a placeholder with the hash of the input while f is running, and replaces it with the result once done.
+// later callers with the same input get the stored result, or wait for the in-flight one. callers with another input get ErrIdempotencyKeyReused.
+// a failed call is not stored, so that it can be retried with the same key
+func callIdempotent(rds *redis.Client, apiName, key string, input []byte, f func() (ret interface{}, err error)) (ret interface{}, err error) {
+	var (
+		ctx       = context.Background()
+		redisKey  = specification.ApiIdempotencyKeyName(apiName, key)
+		retention = time.Duration(config.Cfg.Api.IdempotencyRetention) * time.Second
+		hash      = inputHash(input)
+		stored    []byte
+		first     bool
+		call      idempotentCall
+	)
+	placeholder, err := msgpack.Marshal(&idempotentCall{Input: hash})
+	if err != nil {
+		return nil, err
+	}
+	for deadline := time.Now().Add(idempotencyWaitTimeout); time.Now().Before(deadline); {
+		if first, err = rds.SetNX(ctx, redisKey, placeholder, idempotencyInFlightTTL).Result(); err != nil {
+			return nil, err
+		}
+		if first {
+			if ret, err = f(); err != nil {
+				rds.Del(ctx, redisKey)
+				return nil, err
+			}
+			if call.Result, err = msgpack.Marshal(ret); err != nil {
+				return ret, err
+			}
+			call.Input, call.Done = hash, true
+			if stored, err = msgpack.Marshal(&call); err != nil {
+				return ret, err
+			}
+			return ret, rds.Set(ctx, redisKey, stored, retention).Err()
+		}
+		//wait for the in-flight call. if it fails, the placeholder is removed and this caller takes over
+		for ; time.Now().Before(deadline); time.Sleep(idempotencyPollInterval) {
+			if stored, err = rds.Get(ctx, redisKey).Bytes(); err == redis.Nil {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if err = msgpack.Unmarshal(stored, &call); err != nil {
+				return nil, err
+			} else if call.Input != hash {
+				return nil, ErrIdempotencyKeyReused
+			} else if call.Done {
+				return ret, msgpack.Unmarshal(call.Result, &ret)
+			}
+		}
+	}
+	return nil, ErrIdempotentCallInFlight
+}
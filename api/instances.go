@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+)
+
+// instancesKey is the hash of live instances, field is the instance id
+const instancesKey = "saavuu:instances"
+
+// an instance is live if its heartbeat is newer than instanceTTL
+const (
+	heartbeatInterval = time.Second * 10
+	instanceTTL       = time.Second * 30
+)
+
+// Instance is a running process serving apis
+type Instance struct {
+	ID        string
+	Host      string
+	Pid       int
+	StartedAt time.Time
+	LastSeen  time.Time
+	Apis      []string
+}
+
+// InstanceID identifies this process in the instance list
+var InstanceID string = func() string {
+	host, _ := os.Hostname()
+	random := make([]byte, 4)
+	rand.Read(random)
+	return host + "-" + strconv.Itoa(os.Getpid()) + "-" + hex.EncodeToString(random)
+}()
+
+var instanceStartedAt = time.Now()
+
+// heartbeat reports this instance to every data source, until ctx is done. the instance is removed on stop
+func heartbeat(ctx context.Context) {
+	host, _ := os.Hostname()
+	for {
+		instance := &Instance{ID: InstanceID, Host: host, Pid: os.Getpid(), StartedAt: instanceStartedAt, LastSeen: time.Now(), Apis: apiServiceNames()}
+		if b, err := msgpack.Marshal(instance); err == nil {
+			for _, rds := range config.Rds {
+				rds.HSet(context.Background(), instancesKey, InstanceID, b)
+			}
+		}
+		if !sleepWithContext(ctx, heartbeatInterval) {
+			break
+		}
+	}
+	for _, rds := range config.Rds {
+		rds.HDel(context.Background(), instancesKey, InstanceID)
+	}
+}
+
+// LiveInstances returns the instances reporting to the data source. instances not seen within instanceTTL are removed
+func LiveInstances(dataSource string) (instances []*Instance, err error) {
+	var (
+		rds     *redis.Client
+		entries map[string]string
+		ctx     = context.Background()
+	)
+	if rds, err = config.GetRdsClientByName(dataSource); err != nil {
+		return nil, err
+	}
+	if entries, err = rds.HGetAll(ctx, instancesKey).Result(); err != nil {
+		return nil, err
+	}
+	for id, entry := range entries {
+		instance := &Instance{}
+		if err := msgpack.Unmarshal([]byte(entry), instance); err != nil || time.Since(instance.LastSeen) > instanceTTL {
+			rds.HDel(ctx, instancesKey, id)
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
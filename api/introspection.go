@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+// StreamGroupState is the state of a consumer group of a stream
+type StreamGroupState struct {
+	Name            string
+	Consumers       int64
+	Pending         int64
+	Lag             int64
+	LastDeliveredID string
+}
+
+// StreamState is the state of a priority lane of an api, or its dead letter stream
+type StreamState struct {
+	Api    string
+	Lane   int
+	Stream string
+	Length int64
+	Groups []StreamGroupState
+}
+
+// StreamStates returns the length and consumer groups of every lane of the api, and of its dead letter stream (lane -1)
+func StreamStates(apiName string) (states []*StreamState, err error) {
+	var (
+		rds *redis.Client
+		ctx = context.Background()
+	)
+	if rds, err = apiRds(apiName); err != nil {
+		return nil, err
+	}
+	for lane, streams := range laneStreams([]string{apiName}) {
+		states = append(states, &StreamState{Api: apiName, Lane: lane, Stream: streams[0]})
+	}
+	states = append(states, &StreamState{Api: apiName, Lane: -1, Stream: specification.ApiDeadLetterStreamName(apiName)})
+	pipeline := rds.Pipeline()
+	lenCmds, groupCmds := make([]*redis.IntCmd, len(states)), make([]*redis.XInfoGroupsCmd, len(states))
+	for i, state := range states {
+		lenCmds[i], groupCmds[i] = pipeline.XLen(ctx, state.Stream), pipeline.XInfoGroups(ctx, state.Stream)
+	}
+	//stream not exists is not an error here
+	pipeline.Exec(ctx)
+	for i, state := range states {
+		state.Length = lenCmds[i].Val()
+		for _, group := range groupCmds[i].Val() {
+			state.Groups = append(state.Groups, StreamGroupState{Name: group.Name, Consumers: group.Consumers, Pending: group.Pending, Lag: group.Lag, LastDeliveredID: group.LastDeliveredID})
+		}
+	}
+	return states, nil
+}
+
+// ScheduledTasks returns the delayed tasks waiting to be dispatched by this instance, earliest first
+func ScheduledTasks() (tasks []*TaskAtFuture) {
+	mut.Lock()
+	defer mut.Unlock()
+	for _, task := range TasksAtFutureList {
+		tasks = append(tasks, &TaskAtFuture{ServiceName: task.ServiceName, TimeAtUnixNs: task.TimeAtUnixNs})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TimeAtUnixNs < tasks[j].TimeAtUnixNs })
+	return tasks
+}
+
+// CancelScheduledTask cancels the delayed task of the api at timeAt, as CallAtCancel does.
+// if the api is not registered in this process, the data source is taken from options
+func CancelScheduledTask(apiName string, timeAt time.Time, options ...*ApiOption) (err error) {
+	var rds *redis.Client
+	if rds, err = apiRds(apiName); errors.Is(err, ErrApiNotFound) && len(options) > 0 && options[0] != nil {
+		rds, err = config.GetRdsClientByName(options[0].DataSource)
+	}
+	if err != nil {
+		return err
+	}
+	return cancelCallAt(rds, apiName, timeAt)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrAlreadyStarted = errors.New("api workers already started")
+
+var (
+	lifecycleMut sync.Mutex
+	// stopWorkers cancels the context of stream readers, scheduler and reporter
+	stopWorkers context.CancelFunc
+	// workers are the long running loops: stream readers, scheduler and reporter
+	workers sync.WaitGroup
+	// inFlight are the handlers running for calls read from streams
+	inFlight sync.WaitGroup
+)
+
+// Start loads the delayed tasks, then starts reading api streams and dispatching delayed tasks.
+// workers stop when ctx is done or Shutdown is called
+func Start(ctx context.Context) (err error) {
+	lifecycleMut.Lock()
+	defer lifecycleMut.Unlock()
+	if stopWorkers != nil {
+		return ErrAlreadyStarted
+	}
+	ctx, stopWorkers = context.WithCancel(ctx)
+
+	log.Info().Msg("Step Last: API is starting")
+	rpcCallAtTasksLoad()
+	goWorker(func() { reportApiStates(ctx) })
+	goWorker(func() { rpcCallAtDispatcher(ctx) })
+	goWorker(func() { heartbeat(ctx) })
+	goWorker(func() { rpcReceive(ctx) })
+	return nil
+}
+
+// Shutdown stops reading streams and dispatching delayed tasks, then waits for in-flight handlers to send back their results.
+// delayed tasks not yet due stay in redis, and will be loaded by the next Start.
+// it returns ctx.Err() if the deadline of ctx comes first
+func Shutdown(ctx context.Context) (err error) {
+	lifecycleMut.Lock()
+	defer lifecycleMut.Unlock()
+	if stopWorkers == nil {
+		return nil
+	}
+	stopWorkers()
+	stopWorkers = nil
+	log.Info().Msg("API is shutting down, waiting for stream readers to stop")
+	//readers are blocked by XREADGROUP for at most the Block duration
+	if err = waitWithContext(ctx, &workers); err != nil {
+		return err
+	}
+	log.Info().Msg("API stream readers stopped, waiting for in-flight calls")
+	if err = waitWithContext(ctx, &inFlight); err != nil {
+		return err
+	}
+	log.Info().Msg("API shutdown completed")
+	return nil
+}
+
+func goWorker(f func()) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		f()
+	}()
+}
+
+func waitWithContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleepWithContext returns false if ctx is done before d passes
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
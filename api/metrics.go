@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/metrics"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var (
+	apiCalls     = metrics.NewCounterVec("saavuu_api_calls_total", "Number of api handler executions.", "api")
+	apiErrors    = metrics.NewCounterVec("saavuu_api_errors_total", "Number of api handler executions that returned an error.", "api")
+	apiDuration  = metrics.NewHistogramVec("saavuu_api_duration_seconds", "Execution time of api handlers.", nil, "api")
+	apiQueueWait = metrics.NewHistogramVec("saavuu_api_queue_wait_seconds", "Time from adding a call to the stream to starting its handler.", nil, "api")
+	// streamTrimmedUnread is an estimate, from the backlog seen before XADD
+	streamTrimmedUnread = metrics.NewCounterVec("saavuu_stream_trimmed_unread_total", "Number of calls trimmed from the api stream before being read.", "api")
+)
+
+func init() {
+	metrics.NewGaugeFunc("saavuu_stream_length", "Number of entries in the api stream.", "gauge", func() []metrics.Sample { return collectStreams(false) }, "api", "lane")
+	metrics.NewGaugeFunc("saavuu_stream_lag", "Number of entries in the api stream not yet read by the consumer group. needs redis 7.", "gauge", func() []metrics.Sample { return collectStreams(true) }, "api", "lane")
+	metrics.NewGaugeFunc("saavuu_scheduled_tasks", "Number of delayed tasks waiting to be dispatched.", "gauge", collectScheduledTasks, "api")
+	metrics.NewGaugeFunc("saavuu_redis_pool_hits_total", "Number of times a free connection was found in the pool.", "counter", collectRedisPool(func(s *redis.PoolStats) uint32 { return s.Hits }), "datasource")
+	metrics.NewGaugeFunc("saavuu_redis_pool_misses_total", "Number of times a free connection was not found in the pool.", "counter", collectRedisPool(func(s *redis.PoolStats) uint32 { return s.Misses }), "datasource")
+	metrics.NewGaugeFunc("saavuu_redis_pool_timeouts_total", "Number of times a wait for a connection timed out.", "counter", collectRedisPool(func(s *redis.PoolStats) uint32 { return s.Timeouts }), "datasource")
+	metrics.NewGaugeFunc("saavuu_redis_pool_total_conns", "Number of connections in the pool.", "gauge", collectRedisPool(func(s *redis.PoolStats) uint32 { return s.TotalConns }), "datasource")
+	metrics.NewGaugeFunc("saavuu_redis_pool_idle_conns", "Number of idle connections in the pool.", "gauge", collectRedisPool(func(s *redis.PoolStats) uint32 { return s.IdleConns }), "datasource")
+}
+
+// observed counts the executions of the api handler, and measures their duration
+func observed(apiName string, f func(s []byte) (ret interface{}, err error)) func(s []byte) (ret interface{}, err error) {
+	return func(s []byte) (ret interface{}, err error) {
+		start := time.Now()
+		ret, err = f(s)
+		apiDuration.Observe(time.Since(start).Seconds(), apiName)
+		if apiCalls.Inc(apiName); err != nil {
+			apiErrors.Inc(apiName)
+		}
+		return ret, err
+	}
+}
+
+// observeQueueWait measures the time since the message was added
+func observeQueueWait(apiName string, queuedAt time.Time) {
+	if wait := time.Since(queuedAt).Seconds(); wait >= 0 {
+		apiQueueWait.Observe(wait, apiName)
+	}
+}
+
+// collectStreams returns the length, or the consumer group lag, of every lane of every api
+func collectStreams(lag bool) (samples []metrics.Sample) {
+	ctx := context.Background()
+	for _, dataSource := range APIGroupByDataSource.Keys() {
+		rds, ok := config.Rds[dataSource]
+		if !ok {
+			continue
+		}
+		var (
+			apiNames = apiNamesOfDataSource(dataSource)
+			cmds     = make([][]redis.Cmder, len(apiNames))
+			pipeline = rds.Pipeline()
+		)
+		for i, apiName := range apiNames {
+			for _, streams := range laneStreams([]string{apiName}) {
+				if lag {
+					cmds[i] = append(cmds[i], pipeline.XInfoGroups(ctx, streams[0]))
+				} else {
+					cmds[i] = append(cmds[i], pipeline.XLen(ctx, streams[0]))
+				}
+			}
+		}
+		//errors of single commands, such as stream not exists, are checked below
+		pipeline.Exec(ctx)
+		for i, apiName := range apiNames {
+			for lane, cmd := range cmds[i] {
+				if cmd.Err() != nil {
+					continue
+				}
+				sample := metrics.Sample{LabelValues: []string{apiName, strconv.Itoa(lane)}}
+				if groupsCmd, ok := cmd.(*redis.XInfoGroupsCmd); ok {
+					for _, group := range groupsCmd.Val() {
+						if group.Name == specification.ConsumerGroup {
+							sample.Value = float64(group.Lag)
+						}
+					}
+				} else {
+					sample.Value = float64(cmd.(*redis.IntCmd).Val())
+				}
+				samples = append(samples, sample)
+			}
+		}
+	}
+	return samples
+}
+
+func collectScheduledTasks() (samples []metrics.Sample) {
+	var counts = map[string]int{}
+	mut.Lock()
+	for _, task := range TasksAtFutureList {
+		counts[task.ServiceName]++
+	}
+	mut.Unlock()
+	for apiName, count := range counts {
+		samples = append(samples, metrics.Sample{LabelValues: []string{apiName}, Value: float64(count)})
+	}
+	return samples
+}
+
+func collectRedisPool(stat func(s *redis.PoolStats) uint32) func() []metrics.Sample {
+	return func() (samples []metrics.Sample) {
+		for dataSource, rds := range config.Rds {
+			samples = append(samples, metrics.Sample{LabelValues: []string{dataSource}, Value: float64(stat(rds.PoolStats()))})
+		}
+		return samples
+	}
+}
@@ -1,9 +1,30 @@
 package api
 
+import (
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
 // ApiOption is parameter to create an API, RPC, or CallAt
 type ApiOption struct {
 	Name       string
 	DataSource string
+	// IdempotencyKey is the key to deduplicate retried calls. empty means no deduplication. HeaderIdempotencyKey of the input overrides it
+	IdempotencyKey string
+	// Coalesce makes identical calls arriving while one is in flight wait for the result of the first one
+	Coalesce bool
+	// Priority is the lane of the call. 0 is the most urgent one, and the default one
+	Priority int
+	// MaxLen is the capacity of the stream. 0 means config.Cfg.Api.StreamMaxLen
+	MaxLen int64
+	// ApproxTrim trims the stream with "~". default is config.Cfg.Api.StreamApproxTrim
+	ApproxTrim bool
+	// HighWaterMark is the backlog beyond which calls are rejected with ErrOverloaded. 0 means config.Cfg.Api.HighWaterMark
+	HighWaterMark int64
+	// TraceParent is the W3C traceparent of the caller. spans of the call are its children
+	TraceParent string
+	// Namespace prefixes the name of the api, so its streams and delayed tasks. empty means config.Cfg.Namespace
+	Namespace string
 }
 
 var Option *ApiOption
@@ -24,3 +45,83 @@ func (o *ApiOption) WithDataSource(DataSource string) (out *ApiOption) {
 	out.DataSource = DataSource
 	return out
 }
+
+// WithIdempotencyKey makes calls with the same key take effect only once within the retention window.
+// later calls with the same key get the stored result, or wait for the in-flight one.
+// every call of the Rpc func created with it carries the key, so create one per call, or set HeaderIdempotencyKey of the input instead
+func (o *ApiOption) WithIdempotencyKey(key string) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.IdempotencyKey = key
+	return out
+}
+
+// WithCoalescing makes identical calls of the Api arriving while one is in flight, share the result of the first one.
+// works within a process and across instances
+func (o *ApiOption) WithCoalescing() (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.Coalesce = true
+	return out
+}
+
+// WithPriority puts the call to the stream lane of the priority. 0 is the most urgent one.
+// priority beyond config.Cfg.Api.PriorityLanes falls into the last lane
+func (o *ApiOption) WithPriority(priority int) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.Priority = priority
+	return out
+}
+
+// WithStreamCapacity sets the capacity of the stream of the api, and whether to trim it approximately.
+// it is a setting of the api registered by Api, applied to every call of it, whatever the caller is.
+// Rpc of an api not registered in this process applies its own setting
+func (o *ApiOption) WithStreamCapacity(maxLen int64, approxTrim bool) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.MaxLen, out.ApproxTrim = maxLen, approxTrim
+	return out
+}
+
+// WithHighWaterMark rejects calls with ErrOverloaded, when the backlog of the stream exceeds highWaterMark.
+// it is kept below the stream capacity, 3/4 of it if not, so that unprocessed calls are not trimmed.
+// like WithStreamCapacity, it is a setting of the api registered by Api
+func (o *ApiOption) WithHighWaterMark(highWaterMark int64) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.HighWaterMark = highWaterMark
+	return out
+}
+
+// WithTraceParent makes spans of the call children of the W3C traceparent.
+// Rpc prefers the HeaderTraceparent field of the input, if there is one
+func (o *ApiOption) WithTraceParent(traceParent string) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.TraceParent = traceParent
+	return out
+}
+
+// WithNamespace puts the api in the namespace, i.g. "acme:api:demo", apart from the same api of other namespaces
+func (o *ApiOption) WithNamespace(namespace string) (out *ApiOption) {
+	if out = o; o == Option {
+		out = &ApiOption{}
+	}
+	out.Namespace = namespace
+	return out
+}
+
+// namespaced prefixes the api name with the namespace of the option, or config.Cfg.Namespace
+func (o *ApiOption) namespaced(apiName string) string {
+	if len(o.Namespace) > 0 {
+		return specification.Namespaced(o.Namespace, apiName)
+	}
+	return specification.Namespaced(config.Cfg.Namespace, apiName)
+}
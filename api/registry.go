@@ -0,0 +1,82 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	registryMut sync.Mutex
+	// registryVersion increases on every registration or removal of api
+	registryVersion uint64
+	// registryChanged is closed and replaced on every registration or removal of api
+	registryChanged = make(chan struct{})
+)
+
+// registerApi makes the api callable, both locally and from its stream.
+// stream readers pick up the new stream at once, even if workers are already started
+func registerApi(apiInfo *ApiInfo) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+	if old, ok := ApiServices.Get(apiInfo.Name); ok && old.DataSource != apiInfo.DataSource {
+		removeFromDataSourceGroup(old.DataSource, old.Name)
+	}
+	ApiServices.Set(apiInfo.Name, apiInfo)
+	APIGroupByDataSource.Upsert(apiInfo.DataSource, []string{}, func(exist bool, valueInMap, newValue []string) []string {
+		for _, name := range valueInMap {
+			if name == apiInfo.Name {
+				return valueInMap
+			}
+		}
+		return append(valueInMap, apiInfo.Name)
+	})
+	notifyRegistryChanged()
+	log.Info().Str("api registered", apiInfo.Name).Str("dataSource", apiInfo.DataSource).Send()
+}
+
+// Unregister removes the api. its stream is no longer read, and calls to it are left to other instances
+func Unregister(apiName string) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+	apiInfo, ok := ApiServices.Get(apiName)
+	if !ok {
+		return
+	}
+	ApiServices.Remove(apiName)
+	removeFromDataSourceGroup(apiInfo.DataSource, apiName)
+	notifyRegistryChanged()
+	log.Info().Str("api unregistered", apiName).Send()
+}
+
+func removeFromDataSourceGroup(dataSource, apiName string) {
+	APIGroupByDataSource.Upsert(dataSource, []string{}, func(exist bool, valueInMap, newValue []string) []string {
+		names := make([]string, 0, len(valueInMap))
+		for _, name := range valueInMap {
+			if name != apiName {
+				names = append(names, name)
+			}
+		}
+		return names
+	})
+}
+
+// should be called with registryMut locked
+func notifyRegistryChanged() {
+	registryVersion++
+	close(registryChanged)
+	registryChanged = make(chan struct{})
+}
+
+// registryState returns the current version, and the channel closed on next change
+func registryState() (version uint64, changed <-chan struct{}) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+	return registryVersion, registryChanged
+}
+
+// apiNamesOfDataSource returns the apis registered with the data source
+func apiNamesOfDataSource(dataSource string) (names []string) {
+	names, _ = APIGroupByDataSource.Get(dataSource)
+	return append([]string{}, names...)
+}
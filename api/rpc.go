@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"reflect"
-	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/vmihailenco/msgpack/v5"
 	"github.com/yangkequn/saavuu/config"
 	"github.com/yangkequn/saavuu/specification"
+	"github.com/yangkequn/saavuu/tracing"
 )
 
 // create Api context.
@@ -36,30 +36,49 @@ func Rpc[i any, o any](options ...*ApiOption) (retf func(InParam i) (ret o, err
 	if len(option.Name) == 0 {
 		log.Error().Str("service misnamed", option.Name).Send()
 	}
+	option.Name = option.namespaced(option.Name)
 
 	if db, ok = config.Rds[option.DataSource]; !ok {
 		log.Info().Str("DataSource not defined in enviroment", option.DataSource).Send()
 		return nil
 	}
 
+	rpcInfo := &ApiInfo{
+		DataSource:    option.DataSource,
+		Name:          option.Name,
+		WithHeader:    HeaderFieldsUsed(new(i)),
+		MaxLen:        option.MaxLen,
+		ApproxTrim:    option.ApproxTrim,
+		HighWaterMark: option.HighWaterMark,
+	}
 	retf = func(InParam i) (out o, err error) {
 		var (
 			b       []byte
 			results []string
 			cmd     *redis.StringCmd
 			Values  []string
+			lag     int64
 		)
 		if b, err = specification.MarshalApiInput(InParam); err != nil {
 			return out, err
 		}
-		Values = []string{"data", string(b)}
+		//spans of the callee are children of the enqueue span
+		span := tracing.Start(headerFieldOf(InParam, HeaderTraceparent, option.TraceParent), "enqueue "+option.Name)
+		Values = specification.CallFields(b, headerFieldOf(InParam, HeaderIdempotencyKey, option.IdempotencyKey), span.TraceParent())
 		// if hashCallAt {
 		// 	Values = []string{"timeAt", strconv.FormatInt(ops.CallAt.UnixMilli(), 10), "data", string(b)}
 		// } else {
-		// 	Values = []string{"data", string(b)}
+		// 	Values = []string{specification.FieldData, string(b)}
 		// }
-		args := &redis.XAddArgs{Stream: option.Name, Values: Values, MaxLen: 4096}
-		if cmd = db.XAdd(ctx, args); cmd.Err() != nil {
+		stream := specification.ApiStreamName(option.Name, priorityLane(option.Priority))
+		limits := apiLimits(option.Name, rpcInfo)
+		//reject explicitly rather than letting the stream trim unprocessed calls
+		if lag, err = admit(ctx, db, limits, stream); err != nil {
+			span.Finish(err)
+			return out, err
+		}
+		cmd = xAdd(ctx, db, limits, stream, Values, lag)
+		if span.SetAttr("saavuu.stream", stream).Finish(cmd.Err()); cmd.Err() != nil {
 			log.Info().AnErr("Do XAdd", cmd.Err()).Send()
 			return out, cmd.Err()
 		}
@@ -69,14 +88,17 @@ func Rpc[i any, o any](options ...*ApiOption) (retf func(InParam i) (ret o, err
 
 		//BLPop 返回结果 [key1,value1,key2,value2]
 		//cmd.Val() is the stream id, the result will be poped from the list with this id
-		if results, err = db.BLPop(ctx, time.Second*6, cmd.Val()).Result(); err != nil {
+		if results, err = db.BLPop(ctx, specification.CallTimeout, cmd.Val()).Result(); err != nil {
 			return out, err
 		}
 
 		if len(results) != 2 {
 			return out, errors.New("BLPop result length error")
 		}
-		b = []byte(results[1])
+		//empty result is sent back when the in-flight call shared by identical calls failed
+		if b = []byte(results[1]); len(b) == 0 {
+			return out, ErrCoalescedCallFailed
+		}
 
 		oType := reflect.TypeOf((*o)(nil)).Elem()
 		//if o type is a pointer, use reflect.New to create a new pointer
@@ -87,15 +109,7 @@ func Rpc[i any, o any](options ...*ApiOption) (retf func(InParam i) (ret o, err
 		oValueWithPointer := reflect.New(oType).Interface().(*o)
 		return *oValueWithPointer, msgpack.Unmarshal(b, oValueWithPointer)
 	}
-	rpcInfo := &ApiInfo{
-		DataSource: option.DataSource,
-		Name:       option.Name,
-		WithHeader: HeaderFieldsUsed(new(i)),
-	}
 	funcPtr := reflect.ValueOf(retf).Pointer()
 	fun2ApiInfoMap.Store(funcPtr, rpcInfo)
-	APIGroupByDataSource.Upsert(option.DataSource, []string{}, func(exist bool, valueInMap, newValue []string) []string {
-		return append(valueInMap, option.Name)
-	})
 	return retf
 }
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var ErrOverloaded = errors.New("api overloaded: stream backlog exceeds high-water mark")
+
+// backlog is cached for a short while, so that admission control costs no extra round trip for most calls
+const backlogCacheTTL = time.Millisecond * 200
+
+type streamBacklog struct {
+	lag       int64
+	checkedAt time.Time
+}
+
+var backlogCache sync.Map
+
+// apiLimits is the ApiInfo whose stream capacity and high-water mark apply to every call of the api:
+// the api registered by Api in this process, or else declared, the ApiInfo of Rpc or CallAt. nil means config values
+func apiLimits(apiName string, declared *ApiInfo) *ApiInfo {
+	if apiInfo, ok := ApiServices.Get(apiName); ok {
+		return apiInfo
+	}
+	return declared
+}
+
+// streamCapacity is the capacity of the stream of the api. limits can be nil, then config values are used
+func streamCapacity(limits *ApiInfo) (maxLen int64, approxTrim bool) {
+	if limits != nil && limits.MaxLen > 0 {
+		return limits.MaxLen, limits.ApproxTrim
+	}
+	return config.Cfg.Api.StreamMaxLen, config.Cfg.Api.StreamApproxTrim
+}
+
+// highWaterMarkOf is the backlog beyond which calls of the api are rejected. 0 means no limit.
+// it is kept below the stream capacity, so that calls are rejected before XADD trims entries not yet read
+func highWaterMarkOf(limits *ApiInfo) (highWaterMark int64) {
+	if highWaterMark = config.Cfg.Api.HighWaterMark; limits != nil && limits.HighWaterMark > 0 {
+		highWaterMark = limits.HighWaterMark
+	}
+	if maxLen, _ := streamCapacity(limits); maxLen > 0 && highWaterMark >= maxLen {
+		if highWaterMark = maxLen * 3 / 4; highWaterMark < 1 {
+			highWaterMark = 1
+		}
+	}
+	return highWaterMark
+}
+
+// xAddArgs applies the stream capacity of the api. limits can be nil, then config values are used
+func xAddArgs(limits *ApiInfo, stream string, values interface{}) *redis.XAddArgs {
+	maxLen, approxTrim := streamCapacity(limits)
+	return &redis.XAddArgs{Stream: stream, Values: values, MaxLen: maxLen, Approx: approxTrim}
+}
+
+// xAdd adds the call to the stream. lag is the backlog returned by admit.
+// if the stream is full of entries not yet read, XADD trims some of them, which is logged and counted
+func xAdd(ctx context.Context, rds *redis.Client, limits *ApiInfo, stream string, values interface{}, lag int64) (cmd *redis.StringCmd) {
+	cmd = rds.XAdd(ctx, xAddArgs(limits, stream, values))
+	if maxLen, _ := streamCapacity(limits); cmd.Err() == nil && maxLen > 0 && lag >= maxLen {
+		apiName, _ := specification.ApiNameOfStream(stream)
+		streamTrimmedUnread.Add(float64(lag+1-maxLen), apiName)
+		log.Warn().Str("stream", stream).Int64("backlog", lag).Int64("maxLen", maxLen).Msg("stream full, unprocessed calls trimmed. set a high-water mark below the stream capacity")
+	}
+	return cmd
+}
+
+// streamBacklogOf is the number of entries not yet read by the consumer group.
+// it relies on the lag of XINFO GROUPS, which is available since redis 7.0
+func streamBacklogOf(ctx context.Context, rds *redis.Client, stream string) (lag int64, err error) {
+	var groups []redis.XInfoGroup
+	if cached, ok := backlogCache.Load(stream); ok && time.Since(cached.(*streamBacklog).checkedAt) < backlogCacheTTL {
+		return cached.(*streamBacklog).lag, nil
+	}
+	if groups, err = rds.XInfoGroups(ctx, stream).Result(); err != nil && err != redis.Nil {
+		return 0, err
+	}
+	for _, group := range groups {
+		if group.Name == specification.ConsumerGroup {
+			lag = group.Lag
+		}
+	}
+	backlogCache.Store(stream, &streamBacklog{lag: lag, checkedAt: time.Now()})
+	return lag, nil
+}
+
+// admit rejects the call with ErrOverloaded if the backlog of the stream exceeds the high-water mark of the api.
+// lag is the backlog, for xAdd to tell whether the call trims unprocessed ones
+func admit(ctx context.Context, rds *redis.Client, limits *ApiInfo, stream string) (lag int64, err error) {
+	var (
+		highWaterMark = highWaterMarkOf(limits)
+		maxLen, _     = streamCapacity(limits)
+	)
+	if highWaterMark <= 0 && maxLen <= 0 {
+		return 0, nil
+	}
+	//fail open: admission control should not make the api unavailable when backlog is unknown
+	if lag, err = streamBacklogOf(ctx, rds, stream); err != nil {
+		return 0, nil
+	}
+	if highWaterMark > 0 && lag >= highWaterMark {
+		return lag, ErrOverloaded
+	}
+	return lag, nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -19,10 +18,11 @@ import (
 // timeAt is ID of the task. if you want's to cancel the task, you should provide the same timeAt
 func CallAt[i any, o any](f func(InParam i) (ret o, err error), timeAt time.Time) (retf func(InParam i) (err error)) {
 	var (
-		db     *redis.Client
-		ok     bool
-		ctx               = context.Background()
-		option *ApiOption = &ApiOption{}
+		db       *redis.Client
+		ok       bool
+		ctx                 = context.Background()
+		option   *ApiOption = &ApiOption{}
+		declared *ApiInfo
 	)
 	funcPtr := reflect.ValueOf(f).Pointer()
 	if apiInfo, ok := fun2ApiInfoMap.Load(funcPtr); !ok {
@@ -31,6 +31,7 @@ func CallAt[i any, o any](f func(InParam i) (ret o, err error), timeAt time.Time
 		_apiInfo := apiInfo.(*ApiInfo)
 		option.Name = _apiInfo.Name
 		option.DataSource = _apiInfo.DataSource
+		declared = _apiInfo
 	}
 
 	if db, ok = config.Rds[option.DataSource]; !ok {
@@ -43,14 +44,18 @@ func CallAt[i any, o any](f func(InParam i) (ret o, err error), timeAt time.Time
 			b      []byte
 			cmd    *redis.StringCmd
 			Values []string
+			lag    int64
+			limits = apiLimits(option.Name, declared)
 		)
 		if b, err = specification.MarshalApiInput(InParam); err != nil {
 			return err
 		}
+		if lag, err = admit(ctx, db, limits, option.Name); err != nil {
+			return err
+		}
 		fmt.Println("CallAt", option.Name, timeAt.UnixNano())
-		Values = []string{"timeAt", strconv.FormatInt(timeAt.UnixNano(), 10), "data", string(b)}
-		args := &redis.XAddArgs{Stream: option.Name, Values: Values, MaxLen: 4096}
-		if cmd = db.XAdd(ctx, args); cmd.Err() != nil {
+		Values = specification.DelayedTaskFields(timeAt, b)
+		if cmd = xAdd(ctx, db, limits, option.Name, Values, lag); cmd.Err() != nil {
 			log.Info().AnErr("Do XAdd", cmd.Err()).Send()
 			return cmd.Err()
 		}
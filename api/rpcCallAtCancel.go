@@ -3,7 +3,6 @@ package api
 import (
 	"context"
 	"reflect"
-	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,7 +15,6 @@ func CallAtCancel[i any, o any](f func(InParam i) (ret o, err error), timeAt tim
 	var (
 		Rds     *redis.Client
 		apiInfo *ApiInfo
-		Values  []string
 	)
 	funcPtr := reflect.ValueOf(f).Pointer()
 	if _apiInfo, ok := fun2ApiInfoMap.Load(funcPtr); !ok {
@@ -28,12 +26,16 @@ func CallAtCancel[i any, o any](f func(InParam i) (ret o, err error), timeAt tim
 		log.Info().Str("DataSource not defined in enviroment", apiInfo.DataSource).Send()
 		return false
 	}
-	Values = []string{"timeAt", strconv.FormatInt(timeAt.UnixNano(), 10), "data", ""}
-	args := &redis.XAddArgs{Stream: apiInfo.Name, Values: Values, MaxLen: 4096}
+	return cancelCallAt(Rds, apiInfo.Name, timeAt) == nil
+}
+
+// cancelCallAt sends a task with empty data, which removes the task at timeAt
+func cancelCallAt(Rds *redis.Client, apiName string, timeAt time.Time) (err error) {
+	Values := specification.CancelFields(timeAt)
 	//use Rds.XAdd rather than Rds.HSet, to prevent Hset before receiing the result of  XAdd
-	if cmd := Rds.XAdd(context.Background(), args); cmd.Err() != nil {
+	if cmd := Rds.XAdd(context.Background(), xAddArgs(apiLimits(apiName, nil), apiName, Values)); cmd.Err() != nil {
 		log.Info().AnErr("Do XAdd", cmd.Err()).Send()
-		return false
+		return cmd.Err()
 	}
-	return true
+	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
 )
 
 type TaskAtFuture struct {
@@ -40,7 +41,7 @@ func rpcCallAtTaskRemoveOne(serviceName string, timeAtStr string) {
 		mut.Lock()
 		TasksAtFutureList = append(TasksAtFutureList[:index], TasksAtFutureList[index+1:]...)
 		mut.Unlock()
-		go rds.HDel(context.Background(), serviceName+":delay", timeAtStr)
+		go rds.HDel(context.Background(), specification.ApiDelayHashName(serviceName), timeAtStr)
 	}
 }
 
@@ -55,7 +56,7 @@ func rpcCallAtTaskAddOne(serviceName string, timeAtStr string, bytesValue string
 		log.Info().Err(err).Send()
 		return
 	}
-	if cmd := rds.HSet(context.Background(), serviceName+":delay", timeAtStr, bytesValue); cmd.Err() != nil {
+	if cmd := rds.HSet(context.Background(), specification.ApiDelayHashName(serviceName), timeAtStr, bytesValue); cmd.Err() != nil {
 		log.Info().Err(cmd.Err()).Send()
 		return
 	}
@@ -66,16 +67,18 @@ func rpcCallAtTaskAddOne(serviceName string, timeAtStr string, bytesValue string
 	TasksAtFutureList = append(TasksAtFutureList[:index], append([]*TaskAtFuture{task}, TasksAtFutureList[index:]...)...)
 	mut.Unlock()
 }
-func rpcCallAtDispatcher() {
+
+// rpcCallAtDispatcher runs due tasks until ctx is done
+func rpcCallAtDispatcher(ctx context.Context) {
 	var (
 		data                  string
 		TaskAtFutureNs, nowNs int64
 		err                   error
 		cmd                   []redis.Cmder
 	)
-	for {
+	for ctx.Err() == nil {
 		if len(TasksAtFutureList) == 0 {
-			time.Sleep(time.Millisecond * 100)
+			sleepWithContext(ctx, time.Millisecond*100)
 			continue
 		}
 		nowNs = time.Now().UnixNano()
@@ -88,15 +91,17 @@ func rpcCallAtDispatcher() {
 			if timeSpan > 100*1000*1000 {
 				timeSpan = 100 * 1000 * 1000
 			}
-			time.Sleep(time.Duration(timeSpan))
+			sleepWithContext(ctx, time.Duration(timeSpan))
 			continue
 		}
+		mut.Lock()
 		TasksAtFutureList = TasksAtFutureList[1:]
+		mut.Unlock()
 		strTime := strconv.FormatInt(TaskAtFutureNs, 10)
 		rds := GetServiceDB(task.ServiceName)
 		pipeline := rds.Pipeline()
-		pipeline.HGet(context.Background(), task.ServiceName+":delay", strTime)
-		pipeline.HDel(context.Background(), task.ServiceName+":delay", strTime)
+		pipeline.HGet(context.Background(), specification.ApiDelayHashName(task.ServiceName), strTime)
+		pipeline.HDel(context.Background(), specification.ApiDelayHashName(task.ServiceName), strTime)
 		if cmd, err = pipeline.Exec(context.Background()); err != nil {
 			log.Info().Err(err).Send()
 			continue
@@ -124,10 +129,13 @@ func rpcCallAtTasksLoad() {
 		if !ok {
 			continue
 		}
-		rds := config.Rds[dataSource]
+		rds, ok := config.Rds[dataSource]
+		if !ok {
+			continue
+		}
 		pipeline := rds.Pipeline()
 		for _, service := range services {
-			pipeline.HKeys(context.Background(), service+":delay")
+			pipeline.HKeys(context.Background(), specification.ApiDelayHashName(service))
 		}
 		if cmd, err = pipeline.Exec(context.Background()); err != nil {
 			log.Info().AnErr("err LoadDelayApiTask, ", err).Send()
@@ -155,11 +163,3 @@ func rpcCallAtTasksLoad() {
 	mut.Unlock()
 	log.Info().Msg("rpcCallAtTasksLoading completed")
 }
-func init() {
-	go func() {
-		rpcCallAtTasksLoad()
-
-		rpcCallAtDispatcher()
-	}()
-
-}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+func priorityLanes() int {
+	if config.Cfg.Api.PriorityLanes < 1 {
+		return 1
+	}
+	return int(config.Cfg.Api.PriorityLanes)
+}
+
+// priorityLane limits the priority to the lanes available
+func priorityLane(priority int) int {
+	if priority < 0 {
+		return 0
+	}
+	if lanes := priorityLanes(); priority >= lanes {
+		return lanes - 1
+	}
+	return priority
+}
+
+// laneStreams returns streams of the services, grouped by lane. lane 0 first
+func laneStreams(serviceNames []string) (lanes [][]string) {
+	lanes = make([][]string, priorityLanes())
+	for lane := range lanes {
+		for _, serviceName := range serviceNames {
+			lanes[lane] = append(lanes[lane], specification.ApiStreamName(serviceName, lane))
+		}
+	}
+	return lanes
+}
+
+// apiOfStreams maps the streams of lanes to their api names, the inverse of laneStreams
+func apiOfStreams(serviceNames []string, lanes [][]string) map[string]string {
+	apiOf := make(map[string]string, len(serviceNames)*len(lanes))
+	for _, streams := range lanes {
+		for i, stream := range streams {
+			apiOf[stream] = serviceNames[i]
+		}
+	}
+	return apiOf
+}
+
+// laneBatchSize is the number of tasks read from the lane at a time.
+// with "weighted" policy, the batch is shared by lanes in proportion to their weights. otherwise every lane reads a full batch
+func laneBatchSize(lane int) (batchSize int64) {
+	var (
+		lanes       = priorityLanes()
+		weights     = config.Cfg.Api.PriorityWeights
+		totalWeight int64
+	)
+	if config.Cfg.Api.PriorityPolicy != "weighted" || lanes == 1 {
+		return config.Cfg.Api.ServiceBatchSize
+	}
+	if len(weights) != lanes {
+		weights = make([]int64, lanes)
+		for i := range weights {
+			weights[i] = 1 << (lanes - 1 - i)
+		}
+	}
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	if totalWeight <= 0 {
+		return config.Cfg.Api.ServiceBatchSize
+	}
+	if batchSize = config.Cfg.Api.ServiceBatchSize * weights[lane] / totalWeight; batchSize < 1 {
+		batchSize = 1
+	}
+	return batchSize
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,112 +10,231 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/vmihailenco/msgpack/v5"
 	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+	"github.com/yangkequn/saavuu/tracing"
 )
 
-// ensure all apis can be called by rpc
-// because rpc receive needs to know all api names to create stream reading
-var ApiStartingWaiter func() = func() func() {
-	LastApiCnt := -1
-	//if the count of apis is not changing, then all apis are loaded
-	Checker := func() {
-		//if ApiServices.Count() no longer changed, then all apis are loaded
-		for _cnt := ApiServices.Count(); _cnt == 0 || LastApiCnt != _cnt; _cnt = ApiServices.Count() {
-			time.Sleep(time.Millisecond * 30)
-			LastApiCnt = _cnt
-		}
-	}
-	return Checker
-}()
+// Deprecated: apis are registered explicitly by Api and Rpc. stream readers pick up apis registered after Start,
+// so there is no need to wait for apis to load
+var ApiStartingWaiter func() = func() {}
 
-func rpcReceive() {
+// rpcReceive supervises the stream readers. one reader per data source,
+// started when the first api of the data source is registered
+func rpcReceive(ctx context.Context) {
 	var (
-		rds      *redis.Client
-		services []string
-		ok       bool
+		readers = map[string]bool{}
+		rds     *redis.Client
+		ok      bool
 	)
-	for _, dataSource := range APIGroupByDataSource.Keys() {
-		if services, ok = APIGroupByDataSource.Get(dataSource); !ok {
-			log.Error().Str("dataSource", dataSource).Msg("dataSource not found")
-			continue
+	for {
+		_, changed := registryState()
+		for _, dataSource := range APIGroupByDataSource.Keys() {
+			if readers[dataSource] {
+				continue
+			}
+			if rds, ok = config.Rds[dataSource]; !ok {
+				log.Error().Str("dataSource", dataSource).Msg("dataSource not found")
+				continue
+			}
+			readers[dataSource] = true
+			dataSource, rds := dataSource, rds
+			goWorker(func() { rpcReceiveOneDatasource(ctx, dataSource, rds) })
 		}
-
-		if rds, ok = config.Rds[dataSource]; !ok {
-			log.Error().Str("dataSource", dataSource).Msg("dataSource not found")
-			continue
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			//apis registered after Start may have delayed tasks
+			rpcCallAtTasksLoad()
 		}
-		go rpcReceiveOneDatasource(services, rds)
 	}
 }
-func rpcReceiveOneDatasource(serviceNames []string, rds *redis.Client) {
+func rpcReceiveOneDatasource(c context.Context, dataSource string, rds *redis.Client) {
 	var (
-		apiName, data string
-		cmd           *redis.XStreamSliceCmd
+		cmd        *redis.XStreamSliceCmd
+		lanes      [][]string
+		streams    []string
+		apiOf      map[string]string
+		args       *redis.XReadGroupArgs
+		received   bool
+		version    uint64
+		changed    <-chan struct{}
+		readingVer uint64 = ^uint64(0)
+		//a dedicated connection, so that the blocking read can be unblocked when apis change
+		conn      = rds.Conn()
+		stopWaker = make(chan struct{})
 	)
-
-	//wait for all rpc services ready, so that rpc results can be received
-	ApiStartingWaiter()
-
-	c := context.Background()
-	XGroupEnsureCreated(c, serviceNames, rds)
+	defer conn.Close()
+	defer close(stopWaker)
+	clientID, err := conn.ClientID(c).Result()
+	if err != nil && c.Err() == nil {
+		log.Error().AnErr("rpcReceive ClientID", err).Send()
+	}
+	//unblock the blocking read once apis change or c is done
+	go func() {
+		for {
+			_, changed := registryState()
+			select {
+			case <-stopWaker:
+				return
+			case <-c.Done():
+			case <-changed:
+			}
+			if clientID > 0 {
+				rds.ClientUnblock(context.Background(), clientID)
+			}
+			if c.Err() != nil {
+				return
+			}
+		}
+	}()
 
 	//deprecate using list command LRange, to avoid continually query consumption
 	//use xreadgroup to receive data ,2023-01-31
-	for args := defaultXReadGroupArgs(serviceNames); ; {
-		if cmd = rds.XReadGroup(c, args); cmd.Err() == redis.Nil {
+	//stop reading once c is done. tasks already read are still processed, because they are acknowledged on read
+	for c.Err() == nil {
+		//rebuild the streams to read, if apis are registered or removed
+		if version, changed = registryState(); version != readingVer {
+			readingVer, streams = version, nil
+			apiNames := apiNamesOfDataSource(dataSource)
+			lanes = laneStreams(apiNames)
+			apiOf = apiOfStreams(apiNames, lanes)
+			for _, laneStreams := range lanes {
+				streams = append(streams, laneStreams...)
+			}
+			XGroupEnsureCreated(c, streams, rds)
+			args = defaultXReadGroupArgs(streams)
+		}
+		if len(streams) == 0 {
+			select {
+			case <-c.Done():
+			case <-changed:
+			}
 			continue
-		} else if cmd.Err() != nil {
-			time.Sleep(time.Second)
-			log.Error().AnErr("rpcReceive", cmd.Err()).Send()
 		}
-
-		for _, stream := range cmd.Val() {
-			apiName = stream.Stream
-			for _, message := range stream.Messages {
-				timeAtStr, atOk := message.Values["timeAt"]
-				//skip case of placeholder stream while not atOk
-				//but if timeAt is setted, then empty data is allowed, used to clear the task
-				if data = message.Values["data"].(string); len(data) == 0 && !atOk {
+		//with multiple lanes, poll the lanes without blocking, urgent lanes first
+		//"strict" policy starts over from lane 0 once a lane has tasks. "weighted" policy reads every lane in proportion to its weight
+		if received = false; len(lanes) > 1 {
+			for lane, laneStreams := range lanes {
+				laneArgs := defaultXReadGroupArgs(laneStreams)
+				laneArgs.Block, laneArgs.Count = -1, laneBatchSize(lane)
+				if cmd = conn.XReadGroup(c, laneArgs); cmd.Err() != nil || len(cmd.Val()) == 0 {
 					continue
 				}
-				//the delay calling will lost if the app is down
-				if atOk {
-					if len(data) == 0 {
-						rpcCallAtTaskRemoveOne(apiName, timeAtStr.(string))
-					} else {
-						rpcCallAtTaskAddOne(apiName, timeAtStr.(string), data)
-					}
+				rpcReceiveStreams(cmd.Val(), apiOf)
+				if received = true; config.Cfg.Api.PriorityPolicy != "weighted" {
+					break
+				}
+			}
+		}
+		if received {
+			continue
+		}
+		//no task in any lane, block until tasks arrives, or apis change
+		if cmd = conn.XReadGroup(c, args); cmd.Err() == redis.Nil {
+			continue
+		} else if cmd.Err() != nil && c.Err() == nil {
+			sleepWithContext(c, time.Second)
+			log.Error().AnErr("rpcReceive", cmd.Err()).Send()
+		}
+		rpcReceiveStreams(cmd.Val(), apiOf)
+	}
+}
+
+// rpcReceiveStreams processes the messages read. apiOf maps the streams read to their api names
+func rpcReceiveStreams(streams []redis.XStream, apiOf map[string]string) {
+	var (
+		apiName, data string
+		ok            bool
+	)
+	for _, stream := range streams {
+		if apiName, ok = apiOf[stream.Stream]; !ok {
+			apiName, _ = specification.ApiNameOfStream(stream.Stream)
+		}
+		for _, message := range stream.Messages {
+			timeAtStr, atOk := message.Values[specification.FieldTimeAt]
+			//skip case of placeholder stream while not atOk
+			//but if timeAt is setted, then empty data is allowed, used to clear the task
+			if data = message.Values[specification.FieldData].(string); len(data) == 0 && !atOk {
+				continue
+			}
+			//the delay calling will lost if the app is down
+			if atOk {
+				if len(data) == 0 {
+					rpcCallAtTaskRemoveOne(apiName, timeAtStr.(string))
 				} else {
-					go CallApiLocallyAndSendBackResult(apiName, message.ID, []byte(data))
+					rpcCallAtTaskAddOne(apiName, timeAtStr.(string), data)
 				}
-				apiCounter.Add(apiName, 1)
+			} else {
+				idemKey, _ := message.Values[specification.FieldIdempotencyKey].(string)
+				traceParent, _ := message.Values[specification.FieldTraceParent].(string)
+				inFlight.Add(1)
+				go func(apiName, BackToID, idemKey, traceParent string, s []byte) {
+					defer inFlight.Done()
+					if queuedAt, ok := messageTime(BackToID); ok {
+						observeQueueWait(apiName, queuedAt)
+						tracing.StartAt(traceParent, "queue "+apiName, queuedAt).SetAttr("saavuu.stream_id", BackToID).Finish(nil)
+					}
+					callApiLocallyAndSendBackResult(apiName, BackToID, idemKey, traceParent, s)
+				}(apiName, message.ID, idemKey, traceParent, []byte(data))
 			}
+			apiCounter.Add(apiName, 1)
 		}
 	}
 }
 func CallApiLocallyAndSendBackResult(apiName, BackToID string, s []byte) (err error) {
+	return callApiLocallyAndSendBackResult(apiName, BackToID, "", "", s)
+}
+
+// idemKey is the idempotency key carried by the stream message. empty means no deduplication.
+// traceParent is the W3C traceparent carried by the stream message. empty starts a new trace
+func callApiLocallyAndSendBackResult(apiName, BackToID, idemKey, traceParent string, s []byte) (err error) {
 	var (
 		msgPackResult []byte
 		ret           interface{}
 		service       *ApiInfo
 		ok            bool
 		rds           *redis.Client
+		in            []byte = s
 	)
 	if service, ok = ApiServices.Get(apiName); !ok {
 		return fmt.Errorf("service %s not found", apiName)
 	}
-	if ret, err = service.ApiFuncWithMsgpackedParam(s); err != nil {
+	ctx := context.Background()
+	if rds, ok = config.Rds[service.DataSource]; !ok {
+		return fmt.Errorf("DataSource not defined in enviroment %s", service.DataSource)
+	}
+	span := tracing.Start(traceParent, "handler "+apiName)
+	//s, without traceparent, is still the key of coalescing
+	if service.WithHeader && len(traceParent) > 0 {
+		in = withTraceParent(s, span.TraceParent())
+	}
+	if len(idemKey) > 0 {
+		ret, err = callIdempotent(rds, apiName, idemKey, s, func() (interface{}, error) { return service.ApiFuncWithMsgpackedParam(in) })
+	} else if service.Coalesce {
+		var coalesced bool
+		//if coalesced, the result is sent back by the instance running the in-flight call
+		if ret, coalesced, err = callCoalesced(rds, apiName, s, BackToID, func() (interface{}, error) { return service.ApiFuncWithMsgpackedParam(in) }); coalesced {
+			span.SetAttr("saavuu.coalesced", "true").Finish(err)
+			return err
+		}
+	} else {
+		ret, err = service.ApiFuncWithMsgpackedParam(in)
+	}
+	if span.Finish(err); err != nil {
+		if !errors.Is(err, ErrIdempotentCallInFlight) && !errors.Is(err, ErrIdempotencyKeyReused) {
+			sendToDeadLetter(rds, apiName, BackToID, idemKey, traceParent, s, err)
+		}
 		return err
 	}
 	if msgPackResult, err = msgpack.Marshal(ret); err != nil {
 		return
 	}
-	ctx := context.Background()
-	if rds, ok = config.Rds[service.DataSource]; !ok {
-		return fmt.Errorf("DataSource not defined in enviroment %s", service.DataSource)
-	}
+	replySpan := tracing.Start(traceParent, "reply "+apiName)
 	pipline := rds.Pipeline()
 	pipline.RPush(ctx, BackToID, msgPackResult)
-	pipline.Expire(ctx, BackToID, time.Second*20)
+	pipline.Expire(ctx, BackToID, specification.ReplyExpire)
 	_, err = pipline.Exec(ctx)
+	replySpan.Finish(err)
 	return err
 }
@@ -2,12 +2,14 @@ package api
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
 )
 
 func defaultXReadGroupArgs(serviceNames []string) *redis.XReadGroupArgs {
@@ -22,7 +24,7 @@ func defaultXReadGroupArgs(serviceNames []string) *redis.XReadGroupArgs {
 	}
 
 	//ServiceBatchSize is the number of tasks that a service can read from redis at the same time
-	args := &redis.XReadGroupArgs{Streams: streams, Block: time.Second * 20, Count: config.Cfg.Api.ServiceBatchSize, NoAck: true, Group: "group0", Consumer: "saavuu"}
+	args := &redis.XReadGroupArgs{Streams: streams, Block: time.Second * 20, Count: config.Cfg.Api.ServiceBatchSize, NoAck: true, Group: specification.ConsumerGroup, Consumer: specification.Consumer}
 	return args
 }
 func XGroupEnsureCreated(c context.Context, ServiceNames []string, rds *redis.Client) (err error) {
@@ -40,9 +42,10 @@ func XGroupEnsureCreated(c context.Context, ServiceNames []string, rds *redis.Cl
 		//if stream key does not exist, create a placeholder stream
 		//other wise, NOGROUP No such key will be returned
 		if cmdStream = rds.XInfoStream(c, serviceName); cmdStream.Err() != nil {
-			if cmdStream.Err() == redis.Nil {
+			//redis replies "ERR no such key" to XINFO STREAM of a missing stream, rather than nil
+			if err := cmdStream.Err(); err == redis.Nil || strings.Contains(err.Error(), "no such key") {
 				//create a placeholder stream
-				if cmd := rds.XAdd(c, &redis.XAddArgs{Stream: serviceName, MaxLen: 4096, Values: []string{"data", ""}}); cmd.Err() != nil {
+				if cmd := rds.XAdd(c, xAddArgs(nil, serviceName, []string{specification.FieldData, ""})); cmd.Err() != nil {
 					log.Info().AnErr("XAdd", cmd.Err()).Send()
 					return cmd.Err()
 				}
@@ -56,7 +59,7 @@ func XGroupEnsureCreated(c context.Context, ServiceNames []string, rds *redis.Cl
 			return nil
 		}
 		//create a group if none exists
-		if cmd := rds.XGroupCreateMkStream(c, serviceName, "group0", "$"); cmd.Err() != nil {
+		if cmd := rds.XGroupCreateMkStream(c, serviceName, specification.ConsumerGroup, "$"); cmd.Err() != nil {
 			log.Info().AnErr("XGroupCreateOne", cmd.Err()).Send()
 			return cmd.Err()
 		}
@@ -1,7 +1,8 @@
 package api
 
 import (
-	"fmt"
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -10,38 +11,53 @@ import (
 
 var apiCounter tools.Counter = tools.Counter{}
 
-func reportApiStates() {
-	//wait till all apis are loaded
-	if ApiServices.Count() == 0 {
-		log.Info().Msg("waiting for apis to load")
-	}
-	for i, lastCnt := 0, 0; (ApiServices.Count() == 0 || lastCnt != ApiServices.Count()) && i < 100; i++ {
-		lastCnt = ApiServices.Count()
-		fmt.Print(".")
-		time.Sleep(time.Millisecond * 100)
-	}
-
+func reportApiStates(ctx context.Context) {
 	// all keys of ServiceMap to []string serviceNames
 	var serviceNames []string = apiServiceNames()
 	log.Info().Any("cnt", len(serviceNames)).Strs("apis are load:", serviceNames).Send()
-	for {
-		time.Sleep(time.Second * 60)
+	for sleepWithContext(ctx, time.Second*60) {
 		serviceNames = apiServiceNames()
 		for _, serviceName := range serviceNames {
 			if num, _ := apiCounter.Get(serviceName); num > 0 {
 				log.Info().Any("serviceName", serviceName).Any("proccessed", num).Msg("Tasks processed.")
 			}
 			apiCounter.DeleteAndGetLastValue(serviceName)
+			reportLaneLengths(serviceName)
 		}
 	}
 }
-func init() {
-	go reportApiStates()
-	go StarApis()
+
+// reportLaneLengths logs the backlog of each priority lane of the api, the consumer group lag as admit sees it.
+// stream length would count the entries already processed, which are kept until trimmed
+func reportLaneLengths(serviceName string) {
+	var (
+		ctx   = context.Background()
+		rds   = GetServiceDB(serviceName)
+		lanes = laneStreams([]string{serviceName})
+		lags  = make([]int64, len(lanes))
+		total int64
+		err   error
+	)
+	if rds == nil {
+		return
+	}
+	for lane, streams := range lanes {
+		if lags[lane], err = streamBacklogOf(ctx, rds, streams[0]); err != nil {
+			return
+		}
+		total += lags[lane]
+	}
+	if total == 0 {
+		return
+	}
+	event := log.Info().Any("serviceName", serviceName)
+	for lane, lag := range lags {
+		event = event.Int64("p"+strconv.Itoa(lane), lag)
+	}
+	event.Msg("Stream backlog per lane.")
 }
 
+// Deprecated: use Start, which can be stopped by Shutdown
 func StarApis() {
-	log.Info().Msg("Step Last: API is starting")
-	rpcCallAtTasksLoad()
-	rpcReceive()
+	Start(context.Background())
 }
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// HeaderTraceparent is the input field that receives the W3C traceparent of the call, for apis using header fields.
+// pass it on to Rpc calls made by the api, so that they join the trace
+const HeaderTraceparent = "HeaderTraceparent"
+
+// headerFieldOf returns the string field of the input if there is one, otherwise defaultValue.
+// the input is a struct or a map, or a pointer to one
+func headerFieldOf(in interface{}, fieldName string, defaultValue string) string {
+	v := reflect.ValueOf(in)
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if value := v.MapIndex(reflect.ValueOf(fieldName)); value.IsValid() {
+			if s, ok := value.Interface().(string); ok && len(s) > 0 {
+				return s
+			}
+		}
+	case reflect.Struct:
+		if field := v.FieldByName(fieldName); field.IsValid() && field.Kind() == reflect.String && field.Len() > 0 {
+			return field.String()
+		}
+	}
+	return defaultValue
+}
+
+// withTraceParent sets HeaderTraceparent of the msgpacked input
+func withTraceParent(s []byte, traceParent string) []byte {
+	var _map map[string]interface{} = map[string]interface{}{}
+	if err := msgpack.Unmarshal(s, &_map); err != nil {
+		return s
+	}
+	_map[HeaderTraceparent] = traceParent
+	if b, err := msgpack.Marshal(_map); err == nil {
+		return b
+	}
+	return s
+}
+
+// messageTime is the time the message was added to the stream. the stream ID is "<unix ms>-<seq>"
+func messageTime(messageID string) (t time.Time, ok bool) {
+	ms, err := strconv.ParseInt(strings.SplitN(messageID, "-", 2)[0], 10, 64)
+	if err != nil {
+		return t, false
+	}
+	return time.UnixMilli(ms), true
+}
@@ -0,0 +1,106 @@
+// saavuu is the standalone server. without any api defined, it serves redis over http, with JWT and permission checking.
+//
+// configuration comes from env (see config package), and command line flags override it:
+//
+//	saavuu -port 8080 -redis redis://:password@127.0.0.1:6379/0 -jwt-secret secret -grant "user::hget"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/permission"
+)
+
+// version is set at build time: go build -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// listFlag is a flag that can be given more than once
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+// splitRule splits "dataKey::operation"
+func splitRule(rule string) (dataKey, operation string, err error) {
+	ind := strings.LastIndex(rule, "::")
+	if ind <= 0 || ind+2 >= len(rule) {
+		return "", "", fmt.Errorf("invalid permission rule %q, format: dataKey::operation", rule)
+	}
+	return rule[:ind], rule[ind+2:], nil
+}
+
+func main() {
+	var (
+		redisURLs, grants, denies listFlag
+		showVersion               = flag.Bool("version", false, "print version and exit")
+		port                      = flag.Int64("port", config.Cfg.Http.Port, "http port")
+		path                      = flag.String("path", config.Cfg.Http.Path, "http path of redis commands and apis")
+		cors                      = flag.String("cors", config.Cfg.Http.CORES, "Access-Control-Allow-Origin, empty to disable CORS")
+		metricsPath               = flag.String("metrics-path", config.Cfg.Http.MetricsPath, "http path of prometheus metrics, empty to disable")
+		adminPath                 = flag.String("admin-path", config.Cfg.Http.AdminPath, "http path of admin endpoints, empty to disable")
+		healthPath                = flag.String("health-path", config.Cfg.Http.HealthPath, "http path of health probes (live, ready), empty to disable")
+		jwtSecret                 = flag.String("jwt-secret", config.Cfg.Jwt.Secret, "secret of HS256 JWT")
+		jwtFields                 = flag.String("jwt-fields", config.Cfg.Jwt.Fields, "JWT claims passed to apis, comma separated. * for all")
+		autoAuth                  = flag.Bool("auto-auth", config.Cfg.Data.AutoAuth, "permit and record every operation not in permission table. never in production")
+	)
+	flag.Var(&redisURLs, "redis", "redis data source, [name=]redis://[user:password@]host:port[/db]. repeatable. overrides env Redis_name")
+	flag.Var(&grants, "grant", "allow operation on data key, dataKey::operation, i.g. user::hget. repeatable")
+	flag.Var(&denies, "deny", "disallow operation on data key, dataKey::operation, i.g. user::hget. repeatable")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "saavuu %s, redis over http with permission, and api server\n\nUsage of saavuu:\n", version)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *showVersion {
+		fmt.Println("saavuu", version)
+		return
+	}
+
+	config.Cfg.Http.Enable, config.Cfg.Http.Port, config.Cfg.Http.Path, config.Cfg.Http.CORES = true, *port, *path, *cors
+	config.Cfg.Http.MetricsPath, config.Cfg.Http.AdminPath, config.Cfg.Http.HealthPath = *metricsPath, *adminPath, *healthPath
+	config.Cfg.Jwt.Secret, config.Cfg.Jwt.Fields, config.Cfg.Data.AutoAuth = *jwtSecret, strings.ToLower(*jwtFields), *autoAuth
+	for _, redisURL := range redisURLs {
+		rdsCfg, err := config.ParseRedisURL(redisURL)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+		if err = config.ConnectRedis(rdsCfg); err != nil {
+			log.Fatal().Err(err).Str("redis", rdsCfg.Host+":"+rdsCfg.Port).Msg("redis server not reachable")
+		}
+		config.Cfg.Redis = append(config.Cfg.Redis, rdsCfg)
+	}
+	if _, ok := config.Rds[""]; !ok {
+		log.Fatal().Msg("default redis data source is required, by -redis redis://host:port or env Redis")
+	}
+	//the default data source may be connected just now
+	permission.LoadPermissionTable()
+	for _, rules := range []struct {
+		rules listFlag
+		apply func(dataKey, operation string) error
+	}{{grants, permission.Grant}, {denies, permission.Deny}} {
+		for _, rule := range rules.rules {
+			dataKey, operation, err := splitRule(rule)
+			if err == nil {
+				err = rules.apply(dataKey, operation)
+			}
+			if err != nil {
+				log.Fatal().Err(err).Str("rule", rule).Send()
+			}
+		}
+	}
+
+	log.Info().Str("version", version).Int64("port", *port).Str("path", *path).Msg("saavuu starting")
+	if err := saavuu.Start(context.Background()); err != nil {
+		log.Error().Err(err).Msg("saavuu start failed")
+		os.Exit(1)
+	}
+	//SIGINT / SIGTERM shuts down gracefully
+	saavuu.Wait()
+}
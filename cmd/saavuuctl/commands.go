@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/conformance"
+	"github.com/yangkequn/saavuu/data"
+	"github.com/yangkequn/saavuu/permission"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var errUsage = errors.New("invalid arguments")
+
+// decoded is the msgpack value decoded, or the raw string if it is not msgpack
+func decoded(raw string) (value interface{}) {
+	if err := msgpack.Unmarshal([]byte(raw), &value); err != nil {
+		return raw
+	}
+	return value
+}
+
+// integral converts integral float64 from json to int64, so that apis with integer fields accept them
+func integral(v interface{}) interface{} {
+	switch value := v.(type) {
+	case float64:
+		if value == float64(int64(value)) {
+			return int64(value)
+		}
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = integral(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = integral(item)
+		}
+	}
+	return v
+}
+
+func runCall(args []string) (err error) {
+	var (
+		flags              = flag.NewFlagSet("call", flag.ContinueOnError)
+		dataSource         = flags.String("ds", "", "data source of the api")
+		namespace          = flags.String("ns", config.Cfg.Namespace, "namespace of the api")
+		priority           = flags.Int("priority", 0, "priority lane, 0 is the most urgent one")
+		idemKey            = flags.String("idem", "", "idempotency key")
+		traceParent        = flags.String("traceparent", "", "W3C traceparent of the call")
+		input       []byte = []byte("{}")
+		paramIn     map[string]interface{}
+	)
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		return errUsage
+	}
+	if flags.NArg() > 1 {
+		if input = []byte(flags.Arg(1)); flags.Arg(1) == "-" {
+			if input, err = io.ReadAll(os.Stdin); err != nil {
+				return err
+			}
+		}
+	}
+	if err = json.Unmarshal(input, &paramIn); err != nil {
+		return fmt.Errorf("input should be a json object: %w", err)
+	}
+	option := api.Option.WithName(flags.Arg(0)).WithDataSource(*dataSource).WithPriority(*priority).WithIdempotencyKey(*idemKey).WithTraceParent(*traceParent).WithNamespace(*namespace)
+	rpc := api.Rpc[map[string]interface{}, interface{}](option)
+	if rpc == nil {
+		return fmt.Errorf("data source %q not defined", *dataSource)
+	}
+	ret, err := rpc(integral(paramIn).(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+	return printJson(ret)
+}
+
+// streamMessage is a message of api stream, with data decoded
+type streamMessage struct {
+	ID     string
+	Time   time.Time
+	Fields map[string]interface{}
+}
+
+func messageOf(message redis.XMessage) *streamMessage {
+	out := &streamMessage{ID: message.ID, Fields: map[string]interface{}{}}
+	if ms, err := strconv.ParseInt(strings.SplitN(message.ID, "-", 2)[0], 10, 64); err == nil {
+		out.Time = time.UnixMilli(ms)
+	}
+	for field, value := range message.Values {
+		if s, ok := value.(string); ok && field == "data" {
+			out.Fields[field] = decoded(s)
+		} else {
+			out.Fields[field] = value
+		}
+	}
+	return out
+}
+
+// runTail prints messages added to the api stream, without consuming them, until interrupted
+func runTail(args []string) (err error) {
+	var (
+		flags      = flag.NewFlagSet("tail", flag.ContinueOnError)
+		dataSource = flags.String("ds", "", "data source of the api")
+		namespace  = flags.String("ns", config.Cfg.Namespace, "namespace of the api")
+		lane       = flags.Int("lane", 0, "priority lane")
+		rds        *redis.Client
+		streams    []redis.XStream
+	)
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	if rds, err = config.GetRdsClientByName(*dataSource); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	stream := specification.ApiStreamName(specification.Namespaced(*namespace, specification.ApiName(flags.Arg(0))), *lane)
+	fmt.Fprintln(os.Stderr, "tailing", stream, "press Ctrl+C to stop")
+	//XREAD rather than XREADGROUP, so that messages are left to the workers
+	for lastID := "$"; ctx.Err() == nil; {
+		if streams, err = rds.XRead(ctx, &redis.XReadArgs{Streams: []string{stream, lastID}, Block: time.Second * 2}).Result(); err == redis.Nil {
+			continue
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, s := range streams {
+			for _, message := range s.Messages {
+				lastID = message.ID
+				if err = printJson(messageOf(message)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scheduledTask is a delayed task in the "api:name:delay" hash
+type scheduledTask struct {
+	Api    string
+	TimeAt int64
+	At     time.Time
+	Data   interface{}
+}
+
+func runTasks(args []string) (err error) {
+	var (
+		flags      = flag.NewFlagSet("tasks", flag.ContinueOnError)
+		dataSource = flags.String("ds", "", "data source of the api")
+		namespace  = flags.String("ns", config.Cfg.Namespace, "namespace of the api. tasks of every namespace are listed if both the namespace and the api are empty")
+		rds        *redis.Client
+	)
+	if len(args) < 1 {
+		return errUsage
+	}
+	if err = flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if rds, err = config.GetRdsClientByName(*dataSource); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		var tasks []*scheduledTask
+		if tasks, err = listTasks(rds, *namespace, flags.Arg(0)); err != nil {
+			return err
+		}
+		return printJson(tasks)
+	case "cancel":
+		if flags.NArg() != 2 {
+			return errUsage
+		}
+		timeAt, err := strconv.ParseInt(flags.Arg(1), 10, 64)
+		if err != nil {
+			return err
+		}
+		if err = api.CancelScheduledTask(specification.Namespaced(*namespace, specification.ApiName(flags.Arg(0))), time.Unix(0, timeAt), api.Option.WithDataSource(*dataSource)); err != nil {
+			return err
+		}
+		fmt.Println("canceled")
+		return nil
+	}
+	return errUsage
+}
+
+// listTasks reads the delayed tasks of the api, or of all apis of the namespace if apiName is empty, earliest first.
+// apis of every namespace, and of none, are listed if both are empty
+func listTasks(rds *redis.Client, namespace, apiName string) (tasks []*scheduledTask, err error) {
+	var (
+		ctx    = context.Background()
+		keys   []string
+		fields map[string]string
+	)
+	if len(apiName) > 0 {
+		keys = []string{specification.ApiDelayHashName(specification.Namespaced(namespace, specification.ApiName(apiName)))}
+	} else {
+		pattern := "*api:*:delay"
+		if len(namespace) > 0 {
+			pattern = namespace + ":api:*:delay"
+		}
+		iter := rds.Scan(ctx, 0, pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			//i.g. "api:demo:delay" or "acme:api:demo:delay", but not "xapi:demo:delay"
+			if key := iter.Val(); strings.HasPrefix(key, "api:") || len(specification.ApiNamespace(key)) > 0 {
+				keys = append(keys, key)
+			}
+		}
+		if err = iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range keys {
+		if fields, err = rds.HGetAll(ctx, key).Result(); err != nil {
+			return nil, err
+		}
+		for timeAtStr, value := range fields {
+			timeAt, err := strconv.ParseInt(timeAtStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			tasks = append(tasks, &scheduledTask{Api: strings.TrimSuffix(key, ":delay"), TimeAt: timeAt, At: time.Unix(0, timeAt), Data: decoded(value)})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TimeAt < tasks[j].TimeAt })
+	return tasks, nil
+}
+
+func runPerm(args []string) (err error) {
+	if len(args) == 1 && args[0] == "list" {
+		rules, err := permission.Rules()
+		if err != nil {
+			return err
+		}
+		return printJson(rules)
+	}
+	if len(args) != 3 {
+		return errUsage
+	}
+	switch args[0] {
+	case "grant":
+		err = permission.Grant(args[1], args[2])
+	case "deny":
+		err = permission.Deny(args[1], args[2])
+	case "revoke":
+		err = permission.Revoke(args[1], args[2])
+	default:
+		return errUsage
+	}
+	if err == nil {
+		fmt.Println(args[0], args[1], args[2])
+	}
+	return err
+}
+
+// runDump prints the key as json, with msgpack values decoded
+func runDump(args []string) (err error) {
+	var (
+		flags      = flag.NewFlagSet("dump", flag.ContinueOnError)
+		dataSource = flags.String("ds", "", "data source of the key")
+		rds        *redis.Client
+		keyType    string
+		ctx        = context.Background()
+		out        interface{}
+	)
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	if rds, err = config.GetRdsClientByName(*dataSource); err != nil {
+		return err
+	}
+	key := flags.Arg(0)
+	if keyType, err = rds.Type(ctx, key).Result(); err != nil {
+		return err
+	}
+	switch keyType {
+	case "none":
+		return fmt.Errorf("key %s not exists", key)
+	case "string":
+		var value string
+		if value, err = rds.Get(ctx, key).Result(); err == nil {
+			out = decoded(value)
+		}
+	case "hash":
+		var fields map[string]string
+		if fields, err = rds.HGetAll(ctx, key).Result(); err == nil {
+			values := map[string]interface{}{}
+			for field, value := range fields {
+				values[field] = decoded(value)
+			}
+			out = values
+		}
+	case "list", "set":
+		var members []string
+		if keyType == "list" {
+			members, err = rds.LRange(ctx, key, 0, -1).Result()
+		} else {
+			members, err = rds.SMembers(ctx, key).Result()
+		}
+		values := make([]interface{}, len(members))
+		for i, member := range members {
+			values[i] = decoded(member)
+		}
+		out = values
+	case "zset":
+		var members []redis.Z
+		if members, err = rds.ZRangeWithScores(ctx, key, 0, -1).Result(); err == nil {
+			values := make([]map[string]interface{}, len(members))
+			for i, member := range members {
+				values[i] = map[string]interface{}{"Member": decoded(member.Member.(string)), "Score": member.Score}
+			}
+			out = values
+		}
+	default:
+		return fmt.Errorf("type %s of key %s not supported", keyType, key)
+	}
+	if err != nil {
+		return err
+	}
+	return printJson(map[string]interface{}{"Key": key, "Type": keyType, "Value": out})
+}
+
+// runMigrate upgrades every value of the hash with data.UpgradeSchema: renames, drops and sets fields of the values
+func runMigrate(args []string) (err error) {
+	var (
+		renames, drops, sets listFlag
+		migrateFlags         = flag.NewFlagSet("migrate", flag.ContinueOnError)
+		dataSource           = migrateFlags.String("ds", "", "data source of the key")
+		setValues            = map[string]interface{}{}
+		rds                  *redis.Client
+	)
+	migrateFlags.Var(&renames, "rename", "rename field, old=new. repeatable")
+	migrateFlags.Var(&drops, "drop", "remove field. repeatable")
+	migrateFlags.Var(&sets, "set", "set field to json value, field=json. repeatable")
+	if err = migrateFlags.Parse(args); err != nil {
+		return err
+	}
+	if migrateFlags.NArg() != 1 || len(renames)+len(drops)+len(sets) == 0 {
+		return errUsage
+	}
+	for _, rename := range renames {
+		if !strings.Contains(rename, "=") {
+			return fmt.Errorf("invalid rename %q, format: old=new", rename)
+		}
+	}
+	for _, set := range sets {
+		var value interface{}
+		field, valueJson, ok := strings.Cut(set, "=")
+		if !ok {
+			return fmt.Errorf("invalid set %q, format: field=json", set)
+		}
+		if err = json.Unmarshal([]byte(valueJson), &value); err != nil {
+			return fmt.Errorf("invalid json of set %q: %w", set, err)
+		}
+		setValues[field] = integral(value)
+	}
+	if rds, err = config.GetRdsClientByName(*dataSource); err != nil {
+		return err
+	}
+	db := data.Ctx[string, map[string]interface{}]{Ctx: context.Background(), Rds: rds, Key: migrateFlags.Arg(0)}
+	upgraded := 0
+	err = db.UpgradeSchema(func(in map[string]interface{}) (out map[string]interface{}) {
+		if out = in; out == nil {
+			out = map[string]interface{}{}
+		}
+		for _, rename := range renames {
+			oldName, newName, _ := strings.Cut(rename, "=")
+			if value, ok := out[oldName]; ok {
+				delete(out, oldName)
+				out[newName] = value
+			}
+		}
+		for _, drop := range drops {
+			delete(out, drop)
+		}
+		for field, value := range setValues {
+			out[field] = value
+		}
+		upgraded++
+		return out
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("migrated", upgraded, "values of", migrateFlags.Arg(0))
+	return nil
+}
+
+// runConformance drives a running worker of the api through redis, and verifies it follows the stream protocol
+func runConformance(args []string) (err error) {
+	var (
+		flags      = flag.NewFlagSet("conformance", flag.ContinueOnError)
+		dataSource = flags.String("ds", "", "data source of the api")
+		rds        *redis.Client
+		apiName    string = conformance.EchoApiName
+		failed     int
+	)
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errUsage
+	} else if flags.NArg() == 1 {
+		apiName = flags.Arg(0)
+	}
+	if rds, err = config.GetRdsClientByName(*dataSource); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("stream protocol version %d, api %s\n", specification.ProtocolVersion, specification.ApiName(apiName))
+	for _, check := range conformance.Run(ctx, rds, apiName) {
+		if check.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s (%v): %v\n", check.Name, check.Elapsed.Round(time.Millisecond), check.Err)
+		} else {
+			fmt.Printf("ok   %s (%v)\n", check.Name, check.Elapsed.Round(time.Millisecond))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// saavuuctl calls apis, inspects streams and delayed tasks, manages permissions, migrates data and verifies workers, with the packages of saavuu.
+//
+// redis data sources come from env (see config package), or -redis flags before the command:
+//
+//	saavuuctl -redis redis://127.0.0.1:6379/0 call demo '{"Id":"1"}'
+//	saavuuctl tail demo
+//	saavuuctl tasks list
+//	saavuuctl perm grant user hget
+//	saavuuctl dump user
+//	saavuuctl migrate -rename Name=FullName -drop Age user
+//	saavuuctl conformance conformanceEcho
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/permission"
+)
+
+// version is set at build time: go build -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// listFlag is a flag that can be given more than once
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]*command{
+	"call":        {"call [-ds name] [-ns namespace] [-priority p] [-idem key] [-traceparent tp] <api> [json input, - for stdin]", runCall},
+	"tail":        {"tail [-ds name] [-ns namespace] [-lane n] <api>", runTail},
+	"tasks":       {"tasks list [-ds name] [-ns namespace] [api] | tasks cancel [-ds name] [-ns namespace] <api> <timeAt unix ns>", runTasks},
+	"perm":        {"perm list | perm grant|deny|revoke <dataKey> <operation>", runPerm},
+	"dump":        {"dump [-ds name] <key>", runDump},
+	"migrate":     {"migrate [-ds name] [-rename old=new]... [-drop field]... [-set field=json]... <hash key>", runMigrate},
+	"conformance": {"conformance [-ds name] [api, default conformanceEcho]", runConformance},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "saavuuctl %s\n\nUsage: saavuuctl [-redis url]... [-v] <command> [arguments]\n\nCommands:\n", version)
+	for _, name := range []string{"call", "tail", "tasks", "perm", "dump", "migrate", "conformance"} {
+		fmt.Fprintf(out, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var (
+		redisURLs   listFlag
+		verbose     = flag.Bool("v", false, "log at info level")
+		showVersion = flag.Bool("version", false, "print version and exit")
+	)
+	flag.Var(&redisURLs, "redis", "redis data source, [name=]redis://[user:password@]host:port[/db]. repeatable. overrides env Redis_name")
+	flag.Usage = usage
+	flag.Parse()
+	if *showVersion {
+		fmt.Println("saavuuctl", version)
+		return
+	}
+	if !*verbose {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	for _, redisURL := range redisURLs {
+		rdsCfg, err := config.ParseRedisURL(redisURL)
+		if err == nil {
+			err = config.ConnectRedis(rdsCfg)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Str("redis", redisURL).Msg("redis server not reachable")
+		}
+	}
+	//the default data source may be connected just now
+	permission.LoadPermissionTable()
+	if err := cmd.run(flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		fmt.Fprintln(os.Stderr, "usage: saavuuctl", cmd.usage)
+		os.Exit(1)
+	}
+}
+
+// printJson writes v to stdout as indented json
+func printJson(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
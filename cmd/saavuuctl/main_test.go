@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestListFlag(t *testing.T) {
+	var (
+		renames listFlag
+		flags   = flag.NewFlagSet("migrate", flag.ContinueOnError)
+	)
+	flags.Var(&renames, "rename", "")
+	if err := flags.Parse([]string{"-rename", "a=b", "-rename=c=d", "user"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(renames) != 2 || renames[0] != "a=b" || renames[1] != "c=d" || renames.String() != "a=b,c=d" {
+		t.Error("repeated flag should be kept in order, but", renames)
+	}
+	if flags.Arg(0) != "user" {
+		t.Error("argument after the flags should be left, but", flags.Args())
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	for name, cmd := range commands {
+		if !strings.HasPrefix(cmd.usage, name+" ") {
+			t.Errorf("usage of %s should start with the command, but %q", name, cmd.usage)
+		}
+	}
+}
+
+// TestCommandFlags runs the commands with arguments that fail before anything is sent to redis.
+// "-ds undefined" fails only after flags and arguments are accepted
+func TestCommandFlags(t *testing.T) {
+	const notFound = "redis client with name undefined not found"
+	for _, c := range []struct {
+		cmd  string
+		args []string
+		// err is errUsage, or a substring of the error
+		err interface{}
+	}{
+		{"call", nil, errUsage},
+		{"call", []string{"-priority", "x", "demo"}, "invalid value"},
+		{"call", []string{"-unknown", "demo"}, "flag provided but not defined"},
+		{"call", []string{"demo", "[1]"}, "input should be a json object"},
+		{"call", []string{"-ds", "undefined", "-ns", "acme", "-priority", "1", "-idem", "k", "-traceparent", "tp", "demo", `{"Id":1}`}, `data source "undefined" not defined`},
+		{"tail", nil, errUsage},
+		{"tail", []string{"demo", "extra"}, errUsage},
+		{"tail", []string{"-lane", "x", "demo"}, "invalid value"},
+		{"tail", []string{"-ds", "undefined", "-ns", "acme", "-lane", "2", "demo"}, notFound},
+		{"tasks", nil, errUsage},
+		{"tasks", []string{"list", "-unknown"}, "flag provided but not defined"},
+		{"tasks", []string{"list", "-ds", "undefined", "demo"}, notFound},
+		{"tasks", []string{"cancel", "-ds", "undefined", "-ns", "acme", "demo", "1"}, notFound},
+		{"perm", nil, errUsage},
+		{"perm", []string{"list", "extra"}, errUsage},
+		{"perm", []string{"grant", "user"}, errUsage},
+		{"perm", []string{"allow", "user", "hget"}, errUsage},
+		{"dump", nil, errUsage},
+		{"dump", []string{"a", "b"}, errUsage},
+		{"dump", []string{"-ds", "undefined", "user"}, notFound},
+		{"migrate", []string{"user"}, errUsage},
+		{"migrate", []string{"-drop", "Age"}, errUsage},
+		{"migrate", []string{"-rename", "Name", "user"}, `invalid rename "Name"`},
+		{"migrate", []string{"-set", "Age", "user"}, `invalid set "Age"`},
+		{"migrate", []string{"-set", "Age={", "user"}, `invalid json of set "Age={"`},
+		{"migrate", []string{"-ds", "undefined", "-rename", "Name=FullName", "-drop", "Age", "-set", "Tags=[1]", "user"}, notFound},
+		{"conformance", []string{"a", "b"}, errUsage},
+		{"conformance", []string{"-ds", "undefined"}, notFound},
+		{"conformance", []string{"-ds", "undefined", "echo"}, notFound},
+	} {
+		err := commands[c.cmd].run(c.args)
+		if target, ok := c.err.(error); ok && !errors.Is(err, target) {
+			t.Errorf("%s %q should be %v, but %v", c.cmd, c.args, target, err)
+		} else if substr, ok := c.err.(string); ok && (err == nil || !strings.Contains(err.Error(), substr)) {
+			t.Errorf("%s %q should fail with %q, but %v", c.cmd, c.args, substr, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package codegen
+
+// tsRuntime is the request layer of the generated client, with fetch and @msgpack/msgpack.
+// urls follow https.NewHttpContext: {BaseUrl}/{CMD}-!{Key}[-!DS={data source}]?F={field}
+const tsRuntime = `
+import { encode } from "@msgpack/msgpack";
+
+export let BaseUrl = "{{BaseUrl}}";
+export const setBaseUrl = (url: string) => { BaseUrl = url.replace(/\/+$/, ""); };
+
+// the JWT is kept in localStorage, and sent as the Authorization header
+const JwtStorageKey = "Authorization";
+export const setJwt = (jwt: string) => localStorage.setItem(JwtStorageKey, jwt);
+export const clearJwt = () => localStorage.removeItem(JwtStorageKey);
+let unauthorizedHandler: (() => void) | undefined;
+// onUnauthorized is called when a request is responded with 401, i.g. to sign in again
+export const onUnauthorized = (handler?: () => void) => { unauthorizedHandler = handler; };
+
+export class HttpError extends Error {
+    constructor(public status: number, message: string) { super(message); }
+}
+
+export interface CallOption {
+    // dataSource is the redis data source of the key, the default one if empty
+    dataSource?: string
+    // idempotencyKey makes retried api calls run once
+    idempotencyKey?: string
+    // priority is the lane of the api call, 0 is the most urgent one
+    priority?: number
+    signal?: AbortSignal
+}
+
+type Query = { [key: string]: string | number | boolean | undefined };
+
+export const urlOf = (cmd: string, key: string, query: Query = {}, dataSource?: string) => {
+    let url = ` + "`${BaseUrl}/${cmd}-!${encodeURIComponent(key)}`" + `;
+    if (dataSource) url += "-!DS=" + encodeURIComponent(dataSource);
+    const params = Object.entries(query).filter(([, v]) => v !== undefined).map(([k, v]) => k + "=" + encodeURIComponent(String(v)));
+    return params.length > 0 ? url + "?" + params.join("&") : url;
+};
+
+export async function call<T>(method: string, cmd: string, key: string, query: Query = {}, body?: unknown, option?: CallOption, binary = false): Promise<T> {
+    const headers: { [key: string]: string } = {};
+    const jwt = localStorage.getItem(JwtStorageKey);
+    if (jwt) headers["Authorization"] = jwt;
+    if (option?.idempotencyKey) headers["Idempotency-Key"] = option.idempotencyKey;
+    if (option?.priority !== undefined) headers["X-Priority"] = String(option.priority);
+    let payload: Uint8Array | undefined;
+    if (body !== undefined) {
+        payload = encode(body);
+        headers["Content-Type"] = "application/octet-stream";
+    }
+    const rsp = await fetch(urlOf(cmd, key, query, option?.dataSource), { method, headers, body: payload, signal: option?.signal });
+    if (!rsp.ok) {
+        if (rsp.status === 401) unauthorizedHandler?.();
+        throw new HttpError(rsp.status, await rsp.text());
+    }
+    if (binary) return new Uint8Array(await rsp.arrayBuffer()) as unknown as T;
+    // strings are responded as they are, everything else as json
+    const text = await rsp.text();
+    try { return JSON.parse(text) as T; } catch { return text as unknown as T; }
+}
+
+export interface WithScores<V> { members: V[], scores: number[] }
+
+// scanQuery pages a scan from cursor "", until the returned cursor is ""
+const scanQuery = (cursor: string, match?: string, count?: number): Query => {
+    const query: Query = {};
+    if (cursor) query.Cursor = cursor;
+    if (match) query.Match = match;
+    if (count) query.Count = count;
+    return query;
+};
+
+// Key is a data key of type data.Ctx[K, V]
+export class Key<K extends string | number, V> {
+    constructor(public readonly key: string, public readonly dataSource?: string) { }
+    // Concat appends fields to the key, i.g. keyUser.Concat(id) is "user:id"
+    Concat = (...fields: (string | number)[]) => new Key<K, V>([this.key, ...fields].join(":"), this.dataSource);
+    private opt = (option?: CallOption): CallOption => ({ dataSource: this.dataSource, ...option });
+
+    // string, stored at "key:field"
+    GET = (field: K, option?: CallOption) => call<V>("GET", "GET", this.key, { F: field }, undefined, this.opt(option));
+    SET = (field: K, value: V, option?: CallOption) => call<boolean>("PUT", "SET", this.key, { F: field }, value, this.opt(option));
+    // BLOBUrl is the url of a chunked blob, for <video src> or <audio src> that seek by Range. it carries no JWT, so the key should be readable without one
+    BLOBUrl = (field: K) => urlOf("BLOB", this.key, { F: field }, this.dataSource);
+    // DELBLOB removes the blob with its chunks
+    DELBLOB = (field: K, option?: CallOption) => call<boolean>("DELETE", "DELBLOB", this.key, { F: field }, undefined, this.opt(option));
+
+    // hash
+    HGET = (field: K, option?: CallOption) => call<V>("GET", "HGET", this.key, { F: field }, undefined, this.opt(option));
+    HSET = (field: K, value: V, option?: CallOption) => call<boolean>("PUT", "HSET", this.key, { F: field }, value, this.opt(option));
+    HGETALL = (option?: CallOption) => call<{ [field: string]: V }>("GET", "HGETALL", this.key, {}, undefined, this.opt(option));
+    HMGET = (fields: K[], option?: CallOption) => call<V[]>("GET", "HMGET", this.key, { F: fields.join(",") }, undefined, this.opt(option));
+    HKEYS = (option?: CallOption) => call<K[]>("GET", "HKEYS", this.key, {}, undefined, this.opt(option));
+    HVALS = (option?: CallOption) => call<V[]>("GET", "HVALS", this.key, {}, undefined, this.opt(option));
+    HEXISTS = (field: K, option?: CallOption) => call<boolean>("GET", "HEXISTS", this.key, { F: field }, undefined, this.opt(option));
+    HLEN = (option?: CallOption) => call<number>("GET", "HLEN", this.key, {}, undefined, this.opt(option));
+    HRANDFIELD = (count: number, option?: CallOption) => call<K[]>("GET", "HRANDFIELD", this.key, { Count: count }, undefined, this.opt(option));
+    HSCAN = (cursor: string, match?: string, count?: number, option?: CallOption) => call<{ cursor: string, fields: { [field: string]: V } }>("GET", "HSCAN", this.key, scanQuery(cursor, match, count), undefined, this.opt(option));
+    HDEL = (field: K, option?: CallOption) => call<boolean>("DELETE", "HDEL", this.key, { F: field }, undefined, this.opt(option));
+    HSETNX = (field: K, value: V, option?: CallOption) => call<boolean>("PUT", "HSETNX", this.key, { F: field }, value, this.opt(option));
+    HINCRBY = (field: K, increment: number, option?: CallOption) => call<number>("PUT", "HINCRBY", this.key, { F: field, Increment: increment }, undefined, this.opt(option));
+    HINCRBYFLOAT = (field: K, increment: number, option?: CallOption) => call<number>("PUT", "HINCRBYFLOAT", this.key, { F: field, Increment: increment }, undefined, this.opt(option));
+    DEL = (option?: CallOption) => call<boolean>("DELETE", "DEL", this.key, {}, undefined, this.opt(option));
+    EXPIRE = (seconds: number, option?: CallOption) => call<boolean>("PUT", "EXPIRE", this.key, { Seconds: seconds }, undefined, this.opt(option));
+    TTL = (option?: CallOption) => call<number>("GET", "TTL", this.key, {}, undefined, this.opt(option));
+
+    // list and set
+    RPUSH = (value: V, option?: CallOption) => call<boolean>("PUT", "RPUSH", this.key, {}, value, this.opt(option));
+    LRANGE = (start: number, stop: number, option?: CallOption) => call<V[]>("GET", "LRANGE", this.key, { Start: start, Stop: stop }, undefined, this.opt(option));
+    LLEN = (option?: CallOption) => call<number>("GET", "LLEN", this.key, {}, undefined, this.opt(option));
+    LPUSH = (value: V, option?: CallOption) => call<boolean>("PUT", "LPUSH", this.key, {}, value, this.opt(option));
+    LPOP = (option?: CallOption) => call<V>("DELETE", "LPOP", this.key, {}, undefined, this.opt(option));
+    RPOP = (option?: CallOption) => call<V>("DELETE", "RPOP", this.key, {}, undefined, this.opt(option));
+    LINDEX = (index: number, option?: CallOption) => call<V>("GET", "LINDEX", this.key, { Index: index }, undefined, this.opt(option));
+    LSET = (index: number, value: V, option?: CallOption) => call<boolean>("PUT", "LSET", this.key, { Index: index }, value, this.opt(option));
+    LREM = (count: number, member: string, option?: CallOption) => call<number>("DELETE", "LREM", this.key, { Count: count, Member: member }, undefined, this.opt(option));
+    LTRIM = (start: number, stop: number, option?: CallOption) => call<boolean>("DELETE", "LTRIM", this.key, { Start: start, Stop: stop }, undefined, this.opt(option));
+    SISMEMBER = (member: string, option?: CallOption) => call<boolean>("GET", "SISMEMBER", this.key, { Member: member }, undefined, this.opt(option));
+    SMEMBERS = (option?: CallOption) => call<V[]>("GET", "SMEMBERS", this.key, {}, undefined, this.opt(option));
+    SSCAN = (cursor: string, match?: string, count?: number, option?: CallOption) => call<{ cursor: string, members: V[] }>("GET", "SSCAN", this.key, scanQuery(cursor, match, count), undefined, this.opt(option));
+    SADD = (member: V, option?: CallOption) => call<boolean>("PUT", "SADD", this.key, {}, member, this.opt(option));
+    SREM = (member: string, option?: CallOption) => call<boolean>("DELETE", "SREM", this.key, { Member: member }, undefined, this.opt(option));
+
+    // sorted set
+    ZADD = (score: number, member: V, option?: CallOption) => call<boolean>("POST", "ZADD", this.key, { Score: score }, member, this.opt(option));
+    ZRANGE = (start: number, stop: number, option?: CallOption) => call<V[]>("GET", "ZRANGE", this.key, { Start: start, Stop: stop }, undefined, this.opt(option));
+    ZRANGEWithScores = (start: number, stop: number, option?: CallOption) => call<WithScores<V>>("GET", "ZRANGE", this.key, { Start: start, Stop: stop, WITHSCORES: true }, undefined, this.opt(option));
+    ZREVRANGE = (start: number, stop: number, option?: CallOption) => call<V[]>("GET", "ZREVRANGE", this.key, { Start: start, Stop: stop }, undefined, this.opt(option));
+    ZREVRANGEWithScores = (start: number, stop: number, option?: CallOption) => call<WithScores<V>>("GET", "ZREVRANGE", this.key, { Start: start, Stop: stop, WITHSCORES: true }, undefined, this.opt(option));
+    ZRANGEBYSCORE = (min: number | string, max: number | string, option?: CallOption) => call<V[]>("GET", "ZRANGEBYSCORE", this.key, { Min: min, Max: max }, undefined, this.opt(option));
+    ZRANGEBYSCOREWithScores = (min: number | string, max: number | string, option?: CallOption) => call<WithScores<V>>("GET", "ZRANGEBYSCORE", this.key, { Min: min, Max: max, WITHSCORES: true }, undefined, this.opt(option));
+    ZREVRANGEBYSCORE = (min: number | string, max: number | string, option?: CallOption) => call<V[]>("GET", "ZREVRANGEBYSCORE", this.key, { Min: min, Max: max }, undefined, this.opt(option));
+    ZRANGEBYSCOREPage = (min: number | string, max: number | string, offset: number, count: number, option?: CallOption) => call<V[]>("GET", "ZRANGEBYSCORE", this.key, { Min: min, Max: max, Offset: offset, Count: count }, undefined, this.opt(option));
+    ZSCAN = (cursor: string, match?: string, count?: number, option?: CallOption) => call<{ cursor: string } & WithScores<V>>("GET", "ZSCAN", this.key, scanQuery(cursor, match, count), undefined, this.opt(option));
+    ZCARD = (option?: CallOption) => call<number>("GET", "ZCARD", this.key, {}, undefined, this.opt(option));
+    ZCOUNT = (min: number | string, max: number | string, option?: CallOption) => call<number>("GET", "ZCOUNT", this.key, { Min: min, Max: max }, undefined, this.opt(option));
+    ZRANK = (member: string, option?: CallOption) => call<number>("GET", "ZRANK", this.key, { Member: member }, undefined, this.opt(option));
+    ZREVRANK = (member: string, option?: CallOption) => call<number>("GET", "ZREVRANK", this.key, { Member: member }, undefined, this.opt(option));
+    ZINCRBY = (increment: number, member: string, option?: CallOption) => call<number>("PUT", "ZINCRBY", this.key, { Increment: increment, Member: member }, undefined, this.opt(option));
+    ZSCORE = (member: string, option?: CallOption) => call<number>("GET", "ZSCORE", this.key, { Member: member }, undefined, this.opt(option));
+    ZREM = (members: string[], option?: CallOption) => call<boolean>("DELETE", "ZREM", this.key, { Member: members.join(",") }, undefined, this.opt(option));
+    ZREMRANGEBYSCORE = (min: number | string, max: number | string, option?: CallOption) => call<boolean>("DELETE", "ZREMRANGEBYSCORE", this.key, { Min: min, Max: max }, undefined, this.opt(option));
+    ZREMRANGEBYRANK = (start: number, stop: number, option?: CallOption) => call<boolean>("DELETE", "ZREMRANGEBYRANK", this.key, { Start: start, Stop: stop }, undefined, this.opt(option));
+    ZPOPMAX = (count = 1, option?: CallOption) => call<WithScores<V>>("DELETE", "ZPOPMAX", this.key, { Count: count }, undefined, this.opt(option));
+    ZPOPMIN = (count = 1, option?: CallOption) => call<WithScores<V>>("DELETE", "ZPOPMIN", this.key, { Count: count }, undefined, this.opt(option));
+    ZLEXCOUNT = (min: string, max: string, option?: CallOption) => call<number>("GET", "ZLEXCOUNT", this.key, { Min: min, Max: max }, undefined, this.opt(option));
+}
+`
@@ -0,0 +1,304 @@
+// package codegen generates clients of the http interface, from the apis and data keys registered in the running program
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/data"
+)
+
+// naming is how a struct field is named on the wire
+type naming int
+
+const (
+	// inputNaming is the api input. it is msgpack encoded by the client, and decoded by mapstructure
+	inputNaming naming = iota
+	// msgpackNaming is the value of data keys
+	msgpackNaming
+	// jsonNaming is the api output, marshaled by encoding/json
+	jsonNaming
+)
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// tsGenerator collects the interfaces of the go struct types in use
+type tsGenerator struct {
+	// names of the generated interface, by type and naming
+	names map[reflect.Type]map[naming]string
+	// bodies of the generated interfaces, by interface name
+	bodies map[string]string
+	// goTypes of the generated interfaces, to tell apart go types of the same name
+	goTypes map[string]reflect.Type
+}
+
+// TypeScript writes a typed TypeScript client of the registered apis and data keys.
+// baseUrl is the url of Http.Path, i.g. https://api.example.com/rSvc
+//
+// apis and data keys are registered when the program initializes, so call it from the program itself, i.g. behind a flag:
+//
+//	if *genTs != "" { codegen.WriteTypeScriptFile(*genTs, "https://api.example.com/rSvc") }
+func TypeScript(w io.Writer, baseUrl string) (err error) {
+	var (
+		g    = &tsGenerator{names: map[reflect.Type]map[naming]string{}, bodies: map[string]string{}, goTypes: map[string]reflect.Type{}}
+		apis []*api.ApiInfo
+		code bytes.Buffer
+	)
+	for _, apiInfo := range api.ApiServices.Items() {
+		if apiInfo.InType != nil {
+			apis = append(apis, apiInfo)
+		}
+	}
+	sort.Slice(apis, func(i, j int) bool { return apis[i].Name < apis[j].Name })
+
+	code.WriteString("\n// apis\n")
+	for _, apiInfo := range apis {
+		var (
+			//the namespace is the one of the request, not part of the url, i.g. "acme:api:demo" is "demo"
+			name       = apiInfo.Name[strings.Index(apiInfo.Name, "api:")+len("api:"):]
+			in         = g.tsType(apiInfo.InType, inputNaming)
+			callOption = "option?: CallOption"
+		)
+		fmt.Fprintf(&code, "\n// %s calls api %s\n", identifier("api", name), name)
+		if apiInfo.OutType == bytesType {
+			fmt.Fprintf(&code, "export const %s = (input: %s, %s) => call<Uint8Array>(\"POST\", \"API\", %q, {}, input, option, true)\n", identifier("api", name), in, callOption, name)
+			continue
+		}
+		fmt.Fprintf(&code, "export const %s = (input: %s, %s) => call<%s>(\"POST\", \"API\", %q, {}, input, option)\n", identifier("api", name), in, callOption, g.tsType(apiInfo.OutType, jsonNaming), name)
+	}
+
+	code.WriteString("\n// data keys\n")
+	for _, keyInfo := range data.RegisteredKeys() {
+		var dataSource string
+		if len(keyInfo.DataSource) > 0 {
+			dataSource = fmt.Sprintf(", %q", keyInfo.DataSource)
+		}
+		fmt.Fprintf(&code, "export const %s = new Key<%s, %s>(%q%s)\n", identifier("key", keyInfo.Key), fieldType(keyInfo.KeyType), g.tsType(keyInfo.ValueType, msgpackNaming), keyInfo.Key, dataSource)
+	}
+
+	if _, err = fmt.Fprintf(w, "// Code generated by saavuu codegen. DO NOT EDIT.\n%s", strings.Replace(tsRuntime, "{{BaseUrl}}", strings.TrimRight(baseUrl, "/"), 1)); err != nil {
+		return err
+	}
+	if len(g.bodies) > 0 {
+		var interfaceNames []string
+		for name := range g.bodies {
+			interfaceNames = append(interfaceNames, name)
+		}
+		sort.Strings(interfaceNames)
+		io.WriteString(w, "\n// types\n")
+		for _, name := range interfaceNames {
+			if _, err = fmt.Fprintf(w, "\nexport interface %s %s\n", name, g.bodies[name]); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = w.Write(code.Bytes())
+	return err
+}
+
+// WriteTypeScriptFile writes the TypeScript client to the file at path, see TypeScript
+func WriteTypeScriptFile(path string, baseUrl string) (err error) {
+	var code bytes.Buffer
+	if err = TypeScript(&code, baseUrl); err != nil {
+		return err
+	}
+	return os.WriteFile(path, code.Bytes(), 0644)
+}
+
+// tsType returns the TypeScript type of t. structs become interfaces, named after the go type
+func (g *tsGenerator) tsType(t reflect.Type, n naming) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		//time.Time is a msgpack timestamp, or a RFC 3339 string in json
+		if n == jsonNaming {
+			return "string"
+		}
+		return "Date | string"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		//[]byte is msgpack bin, or a base64 string in json
+		if n == jsonNaming {
+			return "string"
+		}
+		return "Uint8Array | string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		elem := g.tsType(t.Elem(), n)
+		if strings.ContainsAny(elem, " |{") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return fmt.Sprintf("{ [key: %s]: %s }", fieldType(t.Key()), g.tsType(t.Elem(), n))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structBody(t, n)
+		}
+		return g.interfaceName(t, n)
+	default:
+		//interface, func, chan
+		return "any"
+	}
+}
+
+// interfaceName generates the interface of the named struct type once, and returns its name.
+// a struct named differently in inputs, outputs or data gets one interface per naming
+func (g *tsGenerator) interfaceName(t reflect.Type, n naming) (name string) {
+	if name, ok := g.names[t][n]; ok {
+		return name
+	}
+	if g.names[t] == nil {
+		g.names[t] = map[naming]string{}
+	}
+	name = t.Name()
+	for i := 2; g.goTypes[name] != nil && g.goTypes[name] != t; i++ {
+		name = fmt.Sprintf("%s%d", t.Name(), i)
+	}
+	if len(g.names[t]) > 0 {
+		name += [...]string{"In", "Data", "Out"}[n]
+	}
+	//reserved before the body is generated, for recursive types
+	g.names[t][n], g.goTypes[name] = name, t
+	body := g.structBody(t, n)
+	for other, otherName := range g.names[t] {
+		if other != n && g.bodies[otherName] == body {
+			g.names[t][n] = otherName
+			delete(g.goTypes, name)
+			return otherName
+		}
+	}
+	g.bodies[name] = body
+	return name
+}
+
+// structBody returns the fields of the struct, embedded structs flattened
+func (g *tsGenerator) structBody(t reflect.Type, n naming) string {
+	var fields strings.Builder
+	fields.WriteString("{\n")
+	g.writeFields(&fields, t, n)
+	fields.WriteString("}")
+	return fields.String()
+}
+
+func (g *tsGenerator) writeFields(fields *strings.Builder, t reflect.Type, n naming) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+			g.writeFields(fields, indirect(field.Type), n)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, optional, skip := fieldName(field, n)
+		if skip {
+			continue
+		}
+		if field.Type.Kind() == reflect.Ptr {
+			optional = true
+		}
+		if !isIdentifier(name) {
+			name = fmt.Sprintf("%q", name)
+		}
+		if optional {
+			name += "?"
+		}
+		fmt.Fprintf(fields, "    %s: %s\n", name, g.tsType(field.Type, n))
+	}
+}
+
+// fieldName returns the wire name of the struct field.
+// api inputs filled by the server, JWT_ and Header fields, are skipped
+func fieldName(field reflect.StructField, n naming) (name string, optional bool, skip bool) {
+	var tagKey = [...]string{"mapstructure", "msgpack", "json"}[n]
+	tag := strings.Split(field.Tag.Get(tagKey), ",")
+	if tag[0] == "-" {
+		return "", false, true
+	}
+	//msgpack options such as alias:JWT_id are not names
+	if name = tag[0]; strings.Contains(name, ":") {
+		name = ""
+	}
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range tag[1:] {
+		optional = optional || option == "omitempty"
+	}
+	if n == inputNaming {
+		jwtAlias := strings.Contains(field.Tag.Get("msgpack"), "JWT_")
+		if jwtAlias || strings.HasPrefix(name, "JWT_") || strings.HasPrefix(field.Name, "Header") {
+			return name, optional, true
+		}
+		//mapstructure matches field names case insensitively, every field may be left out
+		optional = optional || !strings.Contains(field.Tag.Get("mapstructure"), "nonempty") && !strings.Contains(field.Tag.Get("mapstructure"), "nonzero")
+	}
+	return name, optional, false
+}
+
+// fieldType is the TypeScript type of hash fields and map keys
+func fieldType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "string"
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// identifier makes a TypeScript identifier of prefix and name, i.g. "key", "user:info" => keyUserInfo
+func identifier(prefix, name string) string {
+	var id strings.Builder
+	id.WriteString(prefix)
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r, upper = unicode.ToUpper(r), false
+		}
+		id.WriteRune(r)
+	}
+	return id.String()
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || r == '$' || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return len(name) > 0
+}
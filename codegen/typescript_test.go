@@ -0,0 +1,75 @@
+package codegen_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/codegen"
+	"github.com/yangkequn/saavuu/data"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+type Address struct {
+	City string `mapstructure:"city,nonempty" msgpack:"city" json:"city"`
+	Zip  string `msgpack:",omitempty" json:"zip,omitempty"`
+}
+
+type Profile struct {
+	Name     string            `mapstructure:",nonempty"`
+	Birthday time.Time         `msgpack:"birthday"`
+	Avatar   []byte            `json:"avatar"`
+	Tags     map[string]int64  `json:"tags"`
+	Address  *Address          `json:"address"`
+	Friends  []*Profile        `json:"friends"`
+	Extra    interface{}       `msgpack:"-"`
+	Labels   map[int64]string  `json:"-"`
+	Scores   []map[string]bool `json:"scores"`
+}
+
+type TsDemoIn struct {
+	JWT_id      string
+	UserId      string `msgpack:"alias:JWT_id"`
+	HeaderTrace string
+	Profile     Profile
+	Text        string `mapstructure:"text,nonempty"`
+	Items       []Address
+	unexported  string
+	WithSpace   string `mapstructure:"with space,nonempty"`
+	*Embedded
+}
+
+type Embedded struct {
+	Page int
+}
+
+var (
+	_ = api.Api(func(in *TsDemoIn) (out *Profile, err error) { return nil, nil }, api.ApiOption{Name: "tsDemo"})
+	_ = api.Api(func(in *Address) (out []byte, err error) { return nil, nil }, api.ApiOption{Name: "ts:avatar"})
+	_ = data.New[string, *Profile](&data.DataOption{Key: "tsProfile"})
+	_ = data.New[int64, Address](&data.DataOption{Key: "ts:address", DataSource: "geo"})
+)
+
+// TestTypeScriptGolden fails when the generated client changes.
+// if the change is intended, run go test ./codegen -update, and review the diff of the golden file
+func TestTypeScriptGolden(t *testing.T) {
+	const golden = "testdata/client.ts.golden"
+	var buf bytes.Buffer
+	if err := codegen.TypeScript(&buf, "https://api.example.com/rSvc/"); err != nil {
+		t.Fatal(err)
+	}
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected, err := os.ReadFile(golden); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected, buf.Bytes()) {
+		t.Errorf("generated TypeScript differs from %s:\n%s", golden, buf.Bytes())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"slices"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/specification"
 )
 
 type ConfigHttp struct {
@@ -23,6 +25,29 @@ type ConfigHttp struct {
 	Enable bool   `env:"Enable,default=false"`
 	//MaxBufferSize is the max size of a task in bytes, default 10M
 	MaxBufferSize int64 `env:"MaxBufferSize,default=10485760"`
+	//MetricsPath is where metrics are served in prometheus text format. empty to disable
+	MetricsPath string `env:"MetricsPath,default=/metrics"`
+	//AdminPath is where admin endpoints are served, for JWT with the admin claim, i.g. "/admin/". empty to disable.
+	//they are never served without Jwt.Secret, as anyone could sign the admin claim with the empty key
+	AdminPath string `env:"AdminPath"`
+	//HealthPath is where probes are served: HealthPath+"live" and HealthPath+"ready". empty to disable
+	HealthPath string `env:"HealthPath,default=/health/"`
+	//NamespaceClaim is the JWT claim holding the namespace of the request. empty to disable
+	NamespaceClaim string `env:"NamespaceClaim"`
+	//NamespaceByHost takes the first label of the host as the namespace, i.g. acme of acme.api.example.com. only behind a proxy that sets the host
+	NamespaceByHost bool `env:"NamespaceByHost,default=false"`
+	//AccessLog logs every request of the gateway at info level: method, cmd, key, status, latency, JWT subject and request ID
+	AccessLog bool `env:"AccessLog,default=true"`
+	//MaxBatchOps is the max number of operations in a BATCH request
+	MaxBatchOps int64 `env:"MaxBatchOps,default=256"`
+	//MaxPageSize limits the Count of HSCAN, SSCAN, ZSCAN and ZRANGEBYSCORE. 0 means no limit
+	MaxPageSize int64 `env:"MaxPageSize,default=1000"`
+	//MaxBlobSize is the max size of a blob uploaded by SETBLOB, default 1G. 0 means no limit
+	MaxBlobSize int64 `env:"MaxBlobSize,default=1073741824"`
+	//BlobChunkSize is the chunk size of blobs uploaded by SETBLOB, default 256K
+	BlobChunkSize int64 `env:"BlobChunkSize,default=262144"`
+	//CacheControl is the Cache-Control of GET data commands, by key or key pattern, i.g. {"UserAvatar":"public, max-age=86400","*":"no-cache"}
+	CacheControl map[string]string `env:"CacheControl"`
 }
 type ConfigRedis struct {
 	Name     string
@@ -35,10 +60,35 @@ type ConfigRedis struct {
 type ConfigJWT struct {
 	Secret string `env:"Secret"`
 	Fields string `env:"Fields"`
+	//AdminClaim is the claim that should be true in JWT to use admin endpoints
+	AdminClaim string `env:"AdminClaim,default=admin"`
 }
 type ConfigAPI struct {
 	//ServiceBatchSize is the number of tasks that a service can read from redis at the same time
 	ServiceBatchSize int64 `env:"ServiceBatchSize,default=64"`
+	//IdempotencyRetention is the seconds that the result of a call with idempotency key is kept, default 1 day
+	IdempotencyRetention int64 `env:"IdempotencyRetention,default=86400"`
+	//PriorityLanes is the number of streams per api, one per priority. lane 0 is the most urgent one
+	PriorityLanes int64 `env:"PriorityLanes,default=1"`
+	//PriorityPolicy is how the lanes are read: "strict" always drains urgent lanes first, "weighted" reads every lane in proportion to PriorityWeights
+	PriorityPolicy string `env:"PriorityPolicy,default=strict"`
+	//PriorityWeights is the weight of each lane with "weighted" policy, default is 2^(lanes-1-lane)
+	PriorityWeights []int64 `env:"PriorityWeights"`
+	//StreamMaxLen is the capacity of an api stream. entries beyond it are trimmed, processed or not, see HighWaterMark
+	StreamMaxLen int64 `env:"StreamMaxLen,default=4096"`
+	//StreamApproxTrim trims the stream with "~", which is faster but keeps a little more than StreamMaxLen
+	StreamApproxTrim bool `env:"StreamApproxTrim,default=false"`
+	//HighWaterMark is the backlog of an api stream, beyond which calls are rejected as overloaded, rather than trimmed unprocessed.
+	//it is kept below StreamMaxLen, 3/4 of it if not. 0 means no limit
+	HighWaterMark int64 `env:"HighWaterMark,default=3072"`
+}
+type ConfigTracing struct {
+	//Exporter is where spans go: "stdout", "file", or empty to only propagate traceparent
+	Exporter string `env:"Exporter"`
+	//File is the file spans are appended to as OTLP/JSON lines, with "file" exporter
+	File string `env:"File,default=traces.jsonl"`
+	//ServiceName is the service.name of exported spans
+	ServiceName string `env:"ServiceName,default=saavuu"`
 }
 type ConfigData struct {
 	//AutoAuth should never be true in production
@@ -47,23 +97,30 @@ type ConfigData struct {
 
 type Configuration struct {
 	//redis server, format: username:password@address:port/db
-	Redis []*ConfigRedis
-	Jwt   ConfigJWT
-	Http  ConfigHttp
-	Api   ConfigAPI
-	Data  ConfigData
+	Redis   []*ConfigRedis
+	Jwt     ConfigJWT
+	Http    ConfigHttp
+	Api     ConfigAPI
+	Data    ConfigData
+	Tracing ConfigTracing
 	//{"DebugLevel": 0,"InfoLevel": 1,"WarnLevel": 2,"ErrorLevel": 3,"FatalLevel": 4,"PanicLevel": 5,"NoLevel": 6,"Disabled": 7	  }
 	LogLevel int8 `env:"LogLevel,default=1"`
+	//ShutdownTimeout is the seconds to wait for in-flight requests and api calls on shutdown
+	ShutdownTimeout int64 `env:"ShutdownTimeout,default=30"`
+	//Namespace prefixes api streams, delayed tasks and data keys, i.g. "acme:api:demo". empty means no prefix
+	Namespace string `env:"Namespace"`
 }
 
 // set default values
 var Cfg Configuration = Configuration{
-	Redis:    []*ConfigRedis{},
-	Jwt:      ConfigJWT{Secret: "", Fields: "*"},
-	Http:     ConfigHttp{CORES: "*", Port: 80, Path: "/", Enable: false, MaxBufferSize: 10485760},
-	Api:      ConfigAPI{ServiceBatchSize: 64},
-	Data:     ConfigData{AutoAuth: false},
-	LogLevel: 1,
+	Redis:           []*ConfigRedis{},
+	Jwt:             ConfigJWT{Secret: "", Fields: "*", AdminClaim: "admin"},
+	Http:            ConfigHttp{CORES: "*", Port: 80, Path: "/", Enable: false, MaxBufferSize: 10485760, MetricsPath: "/metrics", HealthPath: "/health/", AccessLog: true, MaxBatchOps: 256, MaxPageSize: 1000, MaxBlobSize: 1073741824, BlobChunkSize: 262144},
+	Api:             ConfigAPI{ServiceBatchSize: 64, IdempotencyRetention: 86400, PriorityLanes: 1, PriorityPolicy: "strict", StreamMaxLen: 4096, HighWaterMark: 3072},
+	Data:            ConfigData{AutoAuth: false},
+	Tracing:         ConfigTracing{File: "traces.jsonl", ServiceName: "saavuu"},
+	LogLevel:        1,
+	ShutdownTimeout: 30,
 }
 
 var Rds map[string]*redis.Client = map[string]*redis.Client{}
@@ -145,14 +202,31 @@ func LoadConfig() (err error) {
 		}
 	}
 
+	if tracingEnv, ok := envMap["Tracing"]; ok && tracingEnv != "" {
+		if err := json.Unmarshal([]byte(tracingEnv), &Cfg.Tracing); err != nil {
+			log.Fatal().Err(err).Str("tracingEnv", tracingEnv).Msg("Step1.0 Load Env/Tracing failed")
+		}
+	}
+
 	// Load LogLevel
 	if logLevelEnv, ok := envMap["LogLevel"]; ok && len(logLevelEnv) > 0 {
 		if logLevel, err := strconv.ParseInt(logLevelEnv, 10, 8); err == nil {
 			Cfg.LogLevel = int8(logLevel)
 		}
 	}
+	if shutdownTimeoutEnv, ok := envMap["ShutdownTimeout"]; ok && len(shutdownTimeoutEnv) > 0 {
+		if shutdownTimeout, err := strconv.ParseInt(shutdownTimeoutEnv, 10, 64); err == nil {
+			Cfg.ShutdownTimeout = shutdownTimeout
+		}
+	}
+	if namespaceEnv, ok := envMap["Namespace"]; ok && len(namespaceEnv) > 0 {
+		if Cfg.Namespace = namespaceEnv; !specification.ValidNamespace(namespaceEnv) {
+			log.Fatal().Str("namespaceEnv", namespaceEnv).Msg("Step1.0 Load Env/Namespace failed, letters, digits, '_' or '-' only")
+		}
+	}
 	return nil
 }
+
 func init() {
 	log.Info().Msg("Step1.0: App Start! load config from OS env")
 	if err := LoadConfig(); err != nil {
@@ -170,37 +244,74 @@ func init() {
 	log.Info().Str("Step1.2 Checking Redis", "Start").Send()
 
 	for _, rdsCfg := range Cfg.Redis {
-		//apply configuration
-		redisOption := &redis.Options{
-			Addr:         rdsCfg.Host + ":" + rdsCfg.Port,
-			Username:     rdsCfg.Username,
-			Password:     rdsCfg.Password, // no password set
-			DB:           int(rdsCfg.DB),  // use default DB
-			PoolSize:     200,
-			DialTimeout:  time.Second * 10,
-			ReadTimeout:  -1,
-			WriteTimeout: time.Second * 300,
-		}
-		rdsClient := redis.NewClient(redisOption)
-		//test connection
-		if _, err := rdsClient.Ping(context.Background()).Result(); err != nil {
+		if err := ConnectRedis(rdsCfg); err != nil {
 			log.Fatal().Err(err).Any("Step1.3 Redis server not rechable", rdsCfg.Host).Send()
 			return //if redis server is not valid, exit
 		}
-		//save to the list
-		log.Info().Str("Step1.3 Redis Load ", "Success").Any("RedisUsername", rdsCfg.Username).Any("RedisPassword", rdsCfg.Password).Any("RedisHost", rdsCfg.Host).Any("RedisPort", rdsCfg.Port).Send()
-		Rds[rdsCfg.Name] = rdsClient
-		timeCmd := rdsClient.Time(context.Background())
-		log.Info().Any("Step1.4 Redis server time: ", timeCmd.Val().String()).Send()
-		//ping the address of redisAddress, if failed, print to log
-		pingServer(rdsCfg.Host)
-
 	}
 
 	log.Info().Msg("Step1.E: App loaded done")
 
 }
 
+// ParseRedisURL parses [name=]redis://[user:password@]host:port[/db]. empty name is the default data source
+func ParseRedisURL(s string) (rdsCfg *ConfigRedis, err error) {
+	var (
+		u    *url.URL
+		name string
+	)
+	if ind := strings.Index(s, "="); ind > 0 && !strings.Contains(s[:ind], "://") {
+		name, s = s[:ind], s[ind+1:]
+	}
+	if u, err = url.Parse(s); err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" || len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("invalid redis url %q, format: [name=]redis://[user:password@]host:port[/db]", s)
+	}
+	rdsCfg = &ConfigRedis{Name: name, Host: u.Hostname(), Port: u.Port(), Username: u.User.Username()}
+	if len(rdsCfg.Port) == 0 {
+		rdsCfg.Port = "6379"
+	}
+	rdsCfg.Password, _ = u.User.Password()
+	if db := strings.Trim(u.Path, "/"); len(db) > 0 {
+		if rdsCfg.DB, err = strconv.ParseInt(db, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid redis db %q: %w", db, err)
+		}
+	}
+	return rdsCfg, nil
+}
+
+// ConnectRedis connects to the redis server, and saves the client to Rds with the name of rdsCfg.
+// used by init for the env, and by programs which take redis servers from elsewhere, such as command line flags
+func ConnectRedis(rdsCfg *ConfigRedis) (err error) {
+	//apply configuration
+	redisOption := &redis.Options{
+		Addr:         rdsCfg.Host + ":" + rdsCfg.Port,
+		Username:     rdsCfg.Username,
+		Password:     rdsCfg.Password, // no password set
+		DB:           int(rdsCfg.DB),  // use default DB
+		PoolSize:     200,
+		DialTimeout:  time.Second * 10,
+		ReadTimeout:  -1,
+		WriteTimeout: time.Second * 300,
+	}
+	rdsClient := redis.NewClient(redisOption)
+	//test connection
+	if _, err = rdsClient.Ping(context.Background()).Result(); err != nil {
+		rdsClient.Close()
+		return err
+	}
+	//save to the list
+	log.Info().Str("Step1.3 Redis Load ", "Success").Any("RedisUsername", rdsCfg.Username).Any("RedisHost", rdsCfg.Host).Any("RedisPort", rdsCfg.Port).Send()
+	Rds[rdsCfg.Name] = rdsClient
+	timeCmd := rdsClient.Time(context.Background())
+	log.Info().Any("Step1.4 Redis server time: ", timeCmd.Val().String()).Send()
+	//ping the address of redisAddress, if failed, print to log
+	pingServer(rdsCfg.Host)
+	return nil
+}
+
 var pingTaskServers = []string{}
 
 func pingServer(domain string) {
@@ -0,0 +1,283 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+// EchoApiName is the api the worker under test serves. it replies its input, HeaderTraceparent included, i.g. in python:
+//
+//	def api_conformanceEcho(id, param, send_back):
+//	    send_back(id, param)
+const EchoApiName = "conformanceEcho"
+
+// Check is the result of one behavior of the worker. Err is nil if it conforms
+type Check struct {
+	Name    string
+	Err     error
+	Elapsed time.Duration
+}
+
+type echoInput struct {
+	Text              string
+	Count             int64
+	HeaderTraceparent string `msgpack:",omitempty"`
+}
+
+// Run drives the worker serving apiName, EchoApiName if empty, through rds, and returns the checks in order.
+// the worker should be running, and be the only one serving apiName
+func Run(ctx context.Context, rds *redis.Client, apiName string) (checks []*Check) {
+	if len(apiName) == 0 {
+		apiName = EchoApiName
+	}
+	h := &harness{ctx: ctx, rds: rds, api: specification.ApiName(apiName)}
+	for _, check := range []struct {
+		name string
+		run  func() error
+	}{
+		{"call is replied, with reply expiring in ReplyExpire", h.call},
+		{"traceparent is passed to the api as HeaderTraceparent", h.traceParent},
+		{"call with idempotency key runs once, keeping the result at ApiIdempotencyKeyName", h.idempotency},
+		{"placeholder message is skipped", h.placeholder},
+		{"delayed task is kept in the delay hash, and run at timeAt in unix nanoseconds", h.delayedTask},
+		{"delayed task is canceled", h.cancel},
+	} {
+		start := time.Now()
+		err := check.run()
+		checks = append(checks, &Check{Name: check.name, Err: err, Elapsed: time.Since(start)})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return checks
+}
+
+type harness struct {
+	ctx context.Context
+	rds *redis.Client
+	api string
+}
+
+var errTimeout = errors.New("timeout")
+
+// until polls cond every 50ms, until it is true or timeout
+func (h *harness) until(timeout time.Duration, cond func() (bool, error)) error {
+	for deadline := time.Now().Add(timeout); ; {
+		if ok, err := cond(); err != nil || ok {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errTimeout
+		}
+		select {
+		case <-h.ctx.Done():
+			return h.ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (h *harness) xadd(values []string) (id string, err error) {
+	return h.rds.XAdd(h.ctx, &redis.XAddArgs{Stream: h.api, Values: values}).Result()
+}
+
+// echo calls the api, and returns the decoded reply
+func (h *harness) echo(in *echoInput, idemKey, traceParent string) (out *echoInput, err error) {
+	var (
+		data, reply []byte
+		id          string
+		ttl         time.Duration
+	)
+	if data, err = specification.MarshalApiInput(in); err != nil {
+		return nil, err
+	}
+	if id, err = h.xadd(specification.CallFields(data, idemKey, traceParent)); err != nil {
+		return nil, err
+	}
+	//not BLPOP, the expiry is checked before the list is popped
+	err = h.until(specification.CallTimeout, func() (bool, error) {
+		n, err := h.rds.Exists(h.ctx, id).Result()
+		return n > 0, err
+	})
+	if err == errTimeout {
+		return nil, fmt.Errorf("no reply in list %s in %v", id, specification.CallTimeout)
+	} else if err != nil {
+		return nil, err
+	}
+	//allow the worker to set the expiry after RPUSH, if not in one transaction
+	time.Sleep(50 * time.Millisecond)
+	if ttl, err = h.rds.TTL(h.ctx, id).Result(); err != nil {
+		return nil, err
+	}
+	if reply, err = h.rds.LPop(h.ctx, id).Bytes(); err != nil {
+		return nil, err
+	}
+	h.rds.Del(h.ctx, id)
+	if ttl <= specification.ReplyExpire-2*time.Second || ttl > specification.ReplyExpire {
+		return nil, fmt.Errorf("reply list expires in %v, should be %v", ttl, specification.ReplyExpire)
+	}
+	out = &echoInput{}
+	if err = msgpack.Unmarshal(reply, out); err != nil {
+		return nil, fmt.Errorf("reply is not the msgpack encoded input: %w", err)
+	}
+	return out, nil
+}
+
+func (h *harness) call() error {
+	in := &echoInput{Text: "conformance", Count: 7}
+	out, err := h.echo(in, "", "")
+	if err != nil {
+		return err
+	}
+	if out.Text != in.Text || out.Count != in.Count {
+		return fmt.Errorf("reply %+v differs from input %+v", out, in)
+	}
+	return nil
+}
+
+func (h *harness) traceParent() error {
+	out, err := h.echo(&echoInput{Text: "traceparent", Count: 1}, "", vectorTraceParent)
+	if err != nil {
+		return err
+	}
+	//the worker may start a child span, with the trace ID of the caller
+	if len(out.HeaderTraceparent) != len(vectorTraceParent) || out.HeaderTraceparent[:35] != vectorTraceParent[:35] {
+		return fmt.Errorf("HeaderTraceparent is %q, should be in trace of %q", out.HeaderTraceparent, vectorTraceParent)
+	}
+	return nil
+}
+
+// idempotency replaces the result kept by the first call, so that the second call with the same key is replied the replaced one, if not run again
+func (h *harness) idempotency() (err error) {
+	var (
+		in      = &echoInput{Text: "idempotency", Count: 1}
+		idemKey = "conformance-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		key     = specification.ApiIdempotencyKeyName(h.api, idemKey)
+		stored  []byte
+		call    map[string]interface{}
+		out     *echoInput
+	)
+	defer h.rds.Del(h.ctx, key)
+	if out, err = h.echo(in, idemKey, ""); err != nil {
+		return err
+	} else if out.Text != in.Text {
+		return fmt.Errorf("reply %+v differs from input %+v", out, in)
+	}
+	if stored, err = h.rds.Get(h.ctx, key).Bytes(); err == redis.Nil {
+		return fmt.Errorf("result not kept at %s", key)
+	} else if err != nil {
+		return err
+	}
+	if err = msgpack.Unmarshal(stored, &call); err != nil {
+		return fmt.Errorf("%s is not a msgpack encoded map: %w", key, err)
+	}
+	if done, _ := call["done"].(bool); !done || call["input"] == nil {
+		return fmt.Errorf("%s should have input, and done true once replied, but is %v", key, call)
+	}
+	if ttl, err := h.rds.TTL(h.ctx, key).Result(); err != nil {
+		return err
+	} else if ttl <= 0 {
+		return fmt.Errorf("%s should expire in Api.IdempotencyRetention, but ttl is %v", key, ttl)
+	}
+	call["result"] = &echoInput{Text: "kept result", Count: in.Count}
+	if stored, err = msgpack.Marshal(call); err != nil {
+		return err
+	}
+	if err = h.rds.Set(h.ctx, key, stored, redis.KeepTTL).Err(); err != nil {
+		return err
+	}
+	if out, err = h.echo(in, idemKey, ""); err != nil {
+		return err
+	} else if out.Text != "kept result" {
+		return fmt.Errorf("second call with idempotency key %s is run again, rather than replied the kept result", idemKey)
+	}
+	return nil
+}
+
+func (h *harness) placeholder() error {
+	id, err := h.xadd(specification.CallFields(nil, "", ""))
+	if err != nil {
+		return err
+	}
+	if err = h.until(time.Second, func() (bool, error) {
+		n, err := h.rds.Exists(h.ctx, id).Result()
+		return n > 0, err
+	}); err == nil {
+		h.rds.Del(h.ctx, id)
+		return fmt.Errorf("placeholder message %s is replied", id)
+	} else if err != errTimeout {
+		return err
+	}
+	//the worker should still serve calls
+	return h.call()
+}
+
+// delayed adds a delayed task, and waits until the worker keeps it in the delay hash
+func (h *harness) delayed(timeAt time.Time) (delayHash, delayField string, err error) {
+	var data []byte
+	if data, err = specification.MarshalApiInput(&echoInput{Text: "delayed", Count: 1}); err != nil {
+		return "", "", err
+	}
+	delayHash, delayField = specification.ApiDelayHashName(h.api), specification.TimeAtString(timeAt)
+	if _, err = h.xadd(specification.DelayedTaskFields(timeAt, data)); err != nil {
+		return "", "", err
+	}
+	err = h.until(time.Until(timeAt)-100*time.Millisecond, func() (bool, error) {
+		return h.rds.HExists(h.ctx, delayHash, delayField).Result()
+	})
+	if err == errTimeout {
+		return "", "", fmt.Errorf("delayed task not kept in hash %s field %s", delayHash, delayField)
+	}
+	return delayHash, delayField, err
+}
+
+func (h *harness) delayedTask() error {
+	timeAt := time.Now().Add(1500 * time.Millisecond)
+	delayHash, delayField, err := h.delayed(timeAt)
+	if err != nil {
+		return err
+	}
+	if time.Until(timeAt) > 100*time.Millisecond {
+		if ok, err := h.rds.HExists(h.ctx, delayHash, delayField).Result(); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("delayed task removed before timeAt")
+		}
+	}
+	err = h.until(time.Until(timeAt)+2*time.Second, func() (bool, error) {
+		ok, err := h.rds.HExists(h.ctx, delayHash, delayField).Result()
+		return !ok, err
+	})
+	if err == errTimeout {
+		h.rds.HDel(h.ctx, delayHash, delayField)
+		return fmt.Errorf("delayed task not run in 2s after timeAt. is timeAt read as unix nanoseconds?")
+	}
+	return err
+}
+
+func (h *harness) cancel() error {
+	timeAt := time.Now().Add(3 * time.Second)
+	delayHash, delayField, err := h.delayed(timeAt)
+	if err != nil {
+		return err
+	}
+	if _, err = h.xadd(specification.CancelFields(timeAt)); err != nil {
+		return err
+	}
+	err = h.until(time.Until(timeAt)-100*time.Millisecond, func() (bool, error) {
+		ok, err := h.rds.HExists(h.ctx, delayHash, delayField).Result()
+		return !ok, err
+	})
+	if err == errTimeout {
+		h.rds.HDel(h.ctx, delayHash, delayField)
+		return fmt.Errorf("delayed task not removed before timeAt")
+	}
+	return err
+}
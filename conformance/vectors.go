@@ -0,0 +1,113 @@
+// package conformance holds the golden vectors of the stream protocol, see specification.ProtocolVersion,
+// and a harness verifying a running worker of any language through redis
+package conformance
+
+import (
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+// Field is a field of a stream message. Bytes holds binary values, Text the others
+type Field struct {
+	Name  string `json:"name"`
+	Text  string `json:"text,omitempty"`
+	Bytes []byte `json:"bytes,omitempty"`
+}
+
+// Vector is one message of the protocol, and what a worker does with it
+type Vector struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Stream      string  `json:"stream"`
+	Fields      []Field `json:"fields"`
+	// Input is the data field decoded, for readers of the vectors
+	Input interface{} `json:"input,omitempty"`
+	// DelayHash and DelayField is where the worker keeps the delayed task, until it runs or is canceled
+	DelayHash  string `json:"delayHash,omitempty"`
+	DelayField string `json:"delayField,omitempty"`
+	// ReplyList is the list the worker pushes Reply to, expiring in ReplyExpireSeconds. "<message id>" is the ID returned by XADD
+	ReplyList          string      `json:"replyList,omitempty"`
+	Reply              []byte      `json:"reply,omitempty"`
+	Output             interface{} `json:"output,omitempty"`
+	ReplyExpireSeconds int         `json:"replyExpireSeconds,omitempty"`
+}
+
+type Vectors struct {
+	ProtocolVersion int `json:"protocolVersion"`
+	// ConsumerGroup and Consumer are what workers read the streams as
+	ConsumerGroup string   `json:"consumerGroup"`
+	Consumer      string   `json:"consumer"`
+	Vectors       []Vector `json:"vectors"`
+}
+
+// vectorInput is the input of the vectors. a struct, so that its encoding is stable
+type vectorInput struct {
+	Text  string
+	Count int64
+	Tags  []string
+}
+
+// vectorTimeAt is the time of delayed tasks of the vectors
+var vectorTimeAt = time.Unix(1700000000, 123456789)
+
+const vectorTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// GoldenVectors returns the vectors, encoded by the same functions callers and the go worker use
+func GoldenVectors() (vectors *Vectors, err error) {
+	var (
+		apiName = specification.ApiName("demo")
+		input   []byte
+		reply   []byte
+	)
+	if input, err = specification.MarshalApiInput(&vectorInput{Text: "hello", Count: 3, Tags: []string{"a", "b"}}); err != nil {
+		return nil, err
+	}
+	//the go worker replies msgpack.Marshal of the output
+	if reply, err = msgpack.Marshal("hello world"); err != nil {
+		return nil, err
+	}
+	vectors = &Vectors{ProtocolVersion: specification.ProtocolVersion, ConsumerGroup: specification.ConsumerGroup, Consumer: specification.Consumer}
+	add := func(v Vector, values []string) {
+		for i := 0; i+1 < len(values); i += 2 {
+			field := Field{Name: values[i]}
+			if values[i] == specification.FieldData {
+				field.Bytes = []byte(values[i+1])
+			} else {
+				field.Text = values[i+1]
+			}
+			v.Fields = append(v.Fields, field)
+		}
+		if len(v.ReplyList) > 0 {
+			v.Output, v.ReplyExpireSeconds = decode(v.Reply), int(specification.ReplyExpire/time.Second)
+		}
+		vectors.Vectors = append(vectors.Vectors, v)
+	}
+	add(Vector{Name: "call", Description: "call of the api, replied to the list named by the message ID",
+		Stream: apiName, Input: decode(input), ReplyList: "<message id>", Reply: reply},
+		specification.CallFields(input, "", ""))
+	add(Vector{Name: "call with idempotency key and traceparent", Description: "runs once per idempotency key. the traceparent is passed to apis using header fields as HeaderTraceparent",
+		Stream: apiName, Input: decode(input), ReplyList: "<message id>", Reply: reply},
+		specification.CallFields(input, "8e03978e-40d5-43e8-bc93-6894a57f9324", vectorTraceParent))
+	add(Vector{Name: "call in priority lane", Description: "lane 2 of the api. workers read lower lanes first",
+		Stream: specification.ApiStreamName(apiName, 2), Input: decode(input), ReplyList: "<message id>", Reply: reply},
+		specification.CallFields(input, "", ""))
+	add(Vector{Name: "placeholder", Description: "creates the stream before the consumer group. empty data without timeAt is skipped",
+		Stream: apiName},
+		specification.CallFields(nil, "", ""))
+	add(Vector{Name: "delayed task", Description: "timeAt is unix nanoseconds. kept in the delay hash, run and removed at timeAt, without reply",
+		Stream: apiName, Input: decode(input), DelayHash: specification.ApiDelayHashName(apiName), DelayField: specification.TimeAtString(vectorTimeAt)},
+		specification.DelayedTaskFields(vectorTimeAt, input))
+	add(Vector{Name: "cancel delayed task", Description: "empty data with timeAt removes the delayed task at timeAt",
+		Stream: apiName, DelayHash: specification.ApiDelayHashName(apiName), DelayField: specification.TimeAtString(vectorTimeAt)},
+		specification.CancelFields(vectorTimeAt))
+	return vectors, nil
+}
+
+func decode(b []byte) (v interface{}) {
+	if len(b) == 0 || msgpack.Unmarshal(b, &v) != nil {
+		return nil
+	}
+	return v
+}
@@ -12,10 +12,14 @@ import (
 )
 
 type Ctx[k comparable, v any] struct {
-	Ctx             context.Context
-	Rds             *redis.Client
-	Key             string
+	Ctx context.Context
+	Rds *redis.Client
+	Key string
+	// Namespace prefixing Key, and ModifiedKey of the tracked keys
+	Namespace       string
 	BloomFilterKeys *bloom.BloomFilter
+	// TrackModified records the modification time of writes in ModifiedKey
+	TrackModified bool
 }
 
 func New[k comparable, v any](ops ...*DataOption) *Ctx[k, v] {
@@ -31,11 +35,17 @@ func New[k comparable, v any](ops ...*DataOption) *Ctx[k, v] {
 	if !specification.GetValidDataKeyName((*v)(nil), &option.Key) {
 		log.Panic().Str("Key is empty in Data.New", option.Key).Send()
 	}
+	//registered even without redis, so that code generators can run without it
+	registerKey[k, v](option.Key, option.DataSource, option.TrackModified)
 	if rds, ok = config.Rds[option.DataSource]; !ok {
 		log.Info().Str("DataSource not defined in enviroment", option.DataSource).Send()
 		return nil
 	}
-	ctx := &Ctx[k, v]{Ctx: context.Background(), Rds: rds, Key: option.Key}
+	namespace := option.Namespace
+	if len(namespace) == 0 {
+		namespace = config.Cfg.Namespace
+	}
+	ctx := &Ctx[k, v]{Ctx: context.Background(), Rds: rds, Key: specification.Namespaced(namespace, option.Key), Namespace: namespace, TrackModified: option.TrackModified}
 	log.Debug().Str("data New create end!", option.Key).Send()
 	return ctx
 }
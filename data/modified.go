@@ -0,0 +1,64 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+// ModifiedKey is the hash of the last modification time of tracked keys, in unix milliseconds by redis key, one hash per namespace, i.g. "acme:_modified".
+// keys are tracked if they are created with DataOption.WithTrackModified, and written by this package or the http gateway
+const ModifiedKey = "_modified"
+
+// ModifiedHash is the ModifiedKey of the namespace
+func ModifiedHash(namespace string) string {
+	return specification.Namespaced(namespace, ModifiedKey)
+}
+
+// TouchModified records now as the modification time of the redis keys of the namespace
+func TouchModified(ctx context.Context, rds redis.Cmdable, namespace string, keys ...string) *redis.IntCmd {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	values := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		values = append(values, key, now)
+	}
+	return rds.HSet(ctx, ModifiedHash(namespace), values)
+}
+
+// ForgetModified removes the modification time of the deleted redis keys of the namespace
+func ForgetModified(ctx context.Context, rds redis.Cmdable, namespace string, keys ...string) *redis.IntCmd {
+	return rds.HDel(ctx, ModifiedHash(namespace), keys...)
+}
+
+// ModifiedAt is the last modification time of the redis key of the namespace, zero if it is not tracked
+func ModifiedAt(ctx context.Context, rds redis.Cmdable, namespace, key string) (modified time.Time, err error) {
+	milli, err := rds.HGet(ctx, ModifiedHash(namespace), key).Int64()
+	if err == redis.Nil {
+		return modified, nil
+	} else if err != nil {
+		return modified, err
+	}
+	return time.UnixMilli(milli), nil
+}
+
+// modified touches the redis keys of a successful write, db.Key if none is given, if the key is tracked
+func (db *Ctx[k, v]) modified(err error, keys ...string) error {
+	if err != nil || !db.TrackModified {
+		return err
+	}
+	if len(keys) == 0 {
+		keys = []string{db.Key}
+	}
+	return TouchModified(db.Ctx, db.Rds, db.Namespace, keys...).Err()
+}
+
+// deleted forgets the modification time of the redis keys of a successful delete, if the key is tracked
+func (db *Ctx[k, v]) deleted(err error, keys ...string) error {
+	if err != nil || !db.TrackModified {
+		return err
+	}
+	return ForgetModified(db.Ctx, db.Rds, db.Namespace, keys...).Err()
+}
@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// DefaultChunkSize is the chunk size of blobs, if none is given
+const DefaultChunkSize int64 = 256 << 10
+
+var ErrNotBlob = errors.New("value is not a blob")
+
+// BlobManifest is stored msgpack encoded at the key of a blob, and the raw bytes of chunk N at key:chunk:Generation:N,
+// so that a range of the blob is read by GETRANGE of the chunks it spans, without loading the whole blob
+type BlobManifest struct {
+	Size        int64  `msgpack:"size"`
+	ChunkSize   int64  `msgpack:"chunkSize"`
+	Chunks      int64  `msgpack:"chunks"`
+	ContentType string `msgpack:"contentType,omitempty"`
+	// Generation is new for every write, so that the chunks of a new blob never overwrite the chunks of the one being read
+	Generation string `msgpack:"generation,omitempty"`
+}
+
+// ChunkKey is the key of the chunk of the blob at key, i.g. "user:avatar:chunk:1a2b3c4d5e6f7a8b:0"
+func (manifest *BlobManifest) ChunkKey(key string, chunk int64) string {
+	if len(manifest.Generation) == 0 {
+		//blobs written before generations
+		return key + ":chunk:" + strconv.FormatInt(chunk, 10)
+	}
+	return key + ":chunk:" + manifest.Generation + ":" + strconv.FormatInt(chunk, 10)
+}
+
+// chunkKeys are the keys of all chunks of the blob at key
+func (manifest *BlobManifest) chunkKeys(key string) (keys []string) {
+	for chunk := int64(0); chunk < manifest.Chunks; chunk++ {
+		keys = append(keys, manifest.ChunkKey(key, chunk))
+	}
+	return keys
+}
+
+func newGeneration() (generation string, err error) {
+	b := make([]byte, 8)
+	if _, err = rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WriteBlob stores the reader in chunks of a new generation, then swaps the manifest at the redis key to it, and removes the previous generation.
+// the blob being read is never changed by the write. chunks written are removed if the reader fails
+func WriteBlob(ctx context.Context, rds redis.Cmdable, key string, r io.Reader, chunkSize int64, contentType string) (manifest *BlobManifest, err error) {
+	var (
+		buf      []byte
+		n        int
+		readErr  error
+		previous []byte
+	)
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	manifest, buf = &BlobManifest{ChunkSize: chunkSize, ContentType: contentType}, make([]byte, chunkSize)
+	if manifest.Generation, err = newGeneration(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			if keys := manifest.chunkKeys(key); len(keys) > 0 {
+				rds.Del(context.Background(), keys...)
+			}
+		}
+	}()
+	for {
+		if n, readErr = io.ReadFull(r, buf); n > 0 {
+			//counted before it is written, so that it is removed even if the write fails halfway
+			manifest.Chunks++
+			if err = rds.Set(ctx, manifest.ChunkKey(key, manifest.Chunks-1), buf[:n], 0).Err(); err != nil {
+				return manifest, err
+			}
+			manifest.Size += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			return manifest, readErr
+		}
+	}
+	bytes, err := msgpack.Marshal(manifest)
+	if err != nil {
+		return manifest, err
+	}
+	//GETSET swaps the manifest atomically, and gets the previous one, whose chunks are no longer reachable
+	if previous, err = rds.GetSet(ctx, key, bytes).Bytes(); err == redis.Nil {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, err
+	}
+	var old *BlobManifest
+	if msgpack.Unmarshal(previous, &old) == nil && old != nil && old.ChunkSize > 0 {
+		if keys := old.chunkKeys(key); len(keys) > 0 {
+			rds.Del(ctx, keys...)
+		}
+	}
+	return manifest, nil
+}
+
+// DeleteBlob removes the blob at the redis key with its chunks
+func DeleteBlob(ctx context.Context, rds redis.Cmdable, key string) (err error) {
+	manifest, err := ReadBlobManifest(ctx, rds, key)
+	if err != nil {
+		return err
+	}
+	return rds.Del(ctx, append(manifest.chunkKeys(key), key)...).Err()
+}
+
+// ReadBlobManifest reads the manifest of the blob at the redis key
+func ReadBlobManifest(ctx context.Context, rds redis.Cmdable, key string) (manifest *BlobManifest, err error) {
+	bytes, err := rds.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err = msgpack.Unmarshal(bytes, &manifest); err != nil || manifest == nil || manifest.ChunkSize <= 0 {
+		return nil, ErrNotBlob
+	}
+	return manifest, nil
+}
+
+// CopyBlobRange writes the bytes start to end, inclusive, of the blob to w, a chunk at a time.
+// w is flushed after every chunk, if it is a http.Flusher, so that clients receive the blob as it is read
+func CopyBlobRange(ctx context.Context, rds redis.Cmdable, key string, manifest *BlobManifest, start, end int64, w io.Writer) (err error) {
+	var bytes []byte
+	for chunk := start / manifest.ChunkSize; chunk <= end/manifest.ChunkSize && chunk < manifest.Chunks; chunk++ {
+		//the range within the chunk
+		offset, from, to := chunk*manifest.ChunkSize, int64(0), manifest.ChunkSize-1
+		if start > offset {
+			from = start - offset
+		}
+		if end < offset+to {
+			to = end - offset
+		}
+		if bytes, err = rds.GetRange(ctx, manifest.ChunkKey(key, chunk), from, to).Bytes(); err != nil {
+			return err
+		}
+		if _, err = w.Write(bytes); err != nil {
+			return err
+		}
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// SetBlob stores the blob at key:field in chunks of chunkSize, DefaultChunkSize if 0
+func (db *Ctx[k, v]) SetBlob(key k, r io.Reader, chunkSize int64, contentType string) (manifest *BlobManifest, err error) {
+	keyStr, err := db.toKeyStr(key)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err = WriteBlob(db.Ctx, db.Rds, db.Key+":"+keyStr, r, chunkSize, contentType)
+	return manifest, db.modified(err, db.Key+":"+keyStr)
+}
+
+// GetBlob writes the whole blob at key:field to w, and returns its manifest
+func (db *Ctx[k, v]) GetBlob(key k, w io.Writer) (manifest *BlobManifest, err error) {
+	keyStr, err := db.toKeyStr(key)
+	if err != nil {
+		return nil, err
+	}
+	if manifest, err = ReadBlobManifest(db.Ctx, db.Rds, db.Key+":"+keyStr); err != nil {
+		return nil, err
+	}
+	return manifest, CopyBlobRange(db.Ctx, db.Rds, db.Key+":"+keyStr, manifest, 0, manifest.Size-1, w)
+}
+
+// DelBlob removes the blob at key:field with its chunks
+func (db *Ctx[k, v]) DelBlob(key k) (err error) {
+	keyStr, err := db.toKeyStr(key)
+	if err != nil {
+		return err
+	}
+	return db.deleted(DeleteBlob(db.Ctx, db.Rds, db.Key+":"+keyStr), db.Key+":"+keyStr)
+}
@@ -41,7 +41,7 @@ func (db *Ctx[k, v]) HSet(values ...interface{}) (err error) {
 		return err
 	}
 	status := db.Rds.HSet(db.Ctx, db.Key, KeyValuesStrs)
-	return status.Err()
+	return db.modified(status.Err())
 }
 
 func (db *Ctx[k, v]) HExists(field k) (ok bool, err error) {
@@ -140,7 +140,7 @@ func (db *Ctx[k, v]) HDel(fields ...k) (err error) {
 		}
 	}
 	cmd = db.Rds.HDel(db.Ctx, db.Key, fieldStrs...)
-	return cmd.Err()
+	return db.modified(cmd.Err())
 }
 func (db *Ctx[k, v]) HKeys() (fields []k, err error) {
 	var (
@@ -167,7 +167,7 @@ func (db *Ctx[k, v]) HIncrBy(field k, increment int64) (err error) {
 		return err
 	}
 	cmd = db.Rds.HIncrBy(db.Ctx, db.Key, fieldStr, increment)
-	return cmd.Err()
+	return db.modified(cmd.Err())
 }
 
 func (db *Ctx[k, v]) HIncrByFloat(field k, increment float64) (err error) {
@@ -179,7 +179,7 @@ func (db *Ctx[k, v]) HIncrByFloat(field k, increment float64) (err error) {
 		return err
 	}
 	cmd = db.Rds.HIncrByFloat(db.Ctx, db.Key, fieldStr, increment)
-	return cmd.Err()
+	return db.modified(cmd.Err())
 
 }
 func (db *Ctx[k, v]) HSetNX(field k, value v) (err error) {
@@ -195,5 +195,5 @@ func (db *Ctx[k, v]) HSetNX(field k, value v) (err error) {
 		return err
 	}
 	cmd = db.Rds.HSetNX(db.Ctx, db.Key, fieldStr, valStr)
-	return cmd.Err()
+	return db.modified(cmd.Err())
 }
@@ -11,14 +11,14 @@ func (db *Ctx[k, v]) RPush(param ...v) (err error) {
 		return err
 	} else {
 
-		return db.Rds.RPush(db.Ctx, db.Key, val).Err()
+		return db.modified(db.Rds.RPush(db.Ctx, db.Key, val).Err())
 	}
 }
 func (db *Ctx[k, v]) LPush(param ...v) (err error) {
 	if val, err := db.toValueStrs(param); err != nil {
 		return err
 	} else {
-		return db.Rds.LPush(db.Ctx, db.Key, val).Err()
+		return db.modified(db.Rds.LPush(db.Ctx, db.Key, val).Err())
 	}
 }
 func (db *Ctx[k, v]) RPop() (ret v, err error) {
@@ -56,14 +56,14 @@ func (db *Ctx[k, v]) LRem(count int64, param v) (err error) {
 	if val, err := db.toValueStr(param); err != nil {
 		return err
 	} else {
-		return db.Rds.LRem(db.Ctx, db.Key, count, val).Err()
+		return db.modified(db.Rds.LRem(db.Ctx, db.Key, count, val).Err())
 	}
 }
 func (db *Ctx[k, v]) LSet(index int64, param v) (err error) {
 	if val, err := db.toValueStr(param); err != nil {
 		return err
 	} else {
-		return db.Rds.LSet(db.Ctx, db.Key, index, val).Err()
+		return db.modified(db.Rds.LSet(db.Ctx, db.Key, index, val).Err())
 	}
 }
 func (db *Ctx[k, v]) BLPop(timeout time.Duration) (ret v, err error) {
@@ -100,7 +100,7 @@ func (db *Ctx[k, v]) LInsertBefore(pivot, param v) (err error) {
 		if pivotStr, err = db.toValueStr(pivot); err != nil {
 			return err
 		} else {
-			return db.Rds.LInsertBefore(db.Ctx, db.Key, pivotStr, val).Err()
+			return db.modified(db.Rds.LInsertBefore(db.Ctx, db.Key, pivotStr, val).Err())
 		}
 	}
 }
@@ -114,7 +114,7 @@ func (db *Ctx[k, v]) LInsertAfter(pivot, param v) (err error) {
 		if pivotStr, err = db.toValueStr(pivot); err != nil {
 			return err
 		} else {
-			return db.Rds.LInsertAfter(db.Ctx, db.Key, pivotStr, val).Err()
+			return db.modified(db.Rds.LInsertAfter(db.Ctx, db.Key, pivotStr, val).Err())
 		}
 	}
 }
@@ -134,7 +134,7 @@ func (db *Ctx[k, v]) Sort(sort *redis.Sort) (ret []v, err error) {
 	}
 }
 func (db *Ctx[k, v]) LTrim(start, stop int64) (err error) {
-	return db.Rds.LTrim(db.Ctx, db.Key, start, stop).Err()
+	return db.modified(db.Rds.LTrim(db.Ctx, db.Key, start, stop).Err())
 }
 func (db *Ctx[k, v]) LIndex(index int64) (ret v, err error) {
 	cmd := db.Rds.LIndex(db.Ctx, db.Key, index)
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// HScanIter iterates the fields of the hash by HSCAN, a page at a time, without loading the whole hash:
+//
+//	for it := db.HScan("", 100); it.Next(); {
+//		fmt.Println(it.Key(), it.Value())
+//	}
+//	err := it.Err()
+//
+// fields may be visited more than once if the hash is modified during the iteration, as redis guarantees
+type HScanIter[k comparable, v any] struct {
+	db     *Ctx[k, v]
+	match  string
+	count  int64
+	cursor uint64
+	// page holds field, value pairs of the page, i is the next pair in it
+	page    []string
+	i       int
+	started bool
+	key     k
+	value   v
+	err     error
+}
+
+// HScan returns the iterator of the fields matching the pattern, all if empty. count is the page size hint of HSCAN
+func (db *Ctx[k, v]) HScan(match string, count int64) *HScanIter[k, v] {
+	return &HScanIter[k, v]{db: db, match: match, count: count}
+}
+
+// Next moves to the next field, and is false when all are visited, or on error
+func (it *HScanIter[k, v]) Next() bool {
+	for it.err == nil {
+		for ; it.i+1 < len(it.page); it.i += 2 {
+			if it.key, it.err = it.db.toKey([]byte(it.page[it.i])); it.err != nil {
+				log.Info().AnErr("HScan: key unmarshal error:", it.err).Msgf("Key: %s", it.db.Key)
+				it.err = nil
+				continue
+			}
+			if it.value, it.err = it.db.toValue([]byte(it.page[it.i+1])); it.err != nil {
+				log.Info().AnErr("HScan: value unmarshal error:", it.err).Msgf("Key: %s", it.db.Key)
+				it.err = nil
+				continue
+			}
+			it.i += 2
+			return true
+		}
+		if it.started && it.cursor == 0 {
+			return false
+		}
+		it.started, it.i = true, 0
+		it.page, it.cursor, it.err = it.db.Rds.HScan(it.db.Ctx, it.db.Key, it.cursor, it.match, it.count).Result()
+	}
+	return false
+}
+func (it *HScanIter[k, v]) Key() k     { return it.key }
+func (it *HScanIter[k, v]) Value() v   { return it.value }
+func (it *HScanIter[k, v]) Err() error { return it.err }
+
+// SScanIter iterates the members of the set by SSCAN, a page at a time, as HScanIter does
+type SScanIter[k comparable, v any] struct {
+	db      *Ctx[k, v]
+	match   string
+	count   int64
+	cursor  uint64
+	page    []string
+	i       int
+	started bool
+	value   v
+	err     error
+}
+
+// SScan returns the iterator of the members matching the pattern, all if empty. count is the page size hint of SSCAN.
+// members are msgpack encoded, so patterns other than "" and "*" rarely match
+func (db *Ctx[k, v]) SScan(match string, count int64) *SScanIter[k, v] {
+	return &SScanIter[k, v]{db: db, match: match, count: count}
+}
+
+// Next moves to the next member, and is false when all are visited, or on error
+func (it *SScanIter[k, v]) Next() bool {
+	for it.err == nil {
+		for ; it.i < len(it.page); it.i++ {
+			if it.value, it.err = it.db.toValue([]byte(it.page[it.i])); it.err != nil {
+				log.Info().AnErr("SScan: value unmarshal error:", it.err).Msgf("Key: %s", it.db.Key)
+				it.err = nil
+				continue
+			}
+			it.i++
+			return true
+		}
+		if it.started && it.cursor == 0 {
+			return false
+		}
+		it.started, it.i = true, 0
+		it.page, it.cursor, it.err = it.db.Rds.SScan(it.db.Ctx, it.db.Key, it.cursor, it.match, it.count).Result()
+	}
+	return false
+}
+func (it *SScanIter[k, v]) Value() v   { return it.value }
+func (it *SScanIter[k, v]) Err() error { return it.err }
+
+// HScanPage returns a page of the hash and the cursor of the next page, 0 if it is the last one.
+// start with cursor 0
+func (db *Ctx[k, v]) HScanPage(cursor uint64, match string, count int64) (mapOut map[k]v, next uint64, err error) {
+	var (
+		page  []string
+		key   k
+		value v
+	)
+	if page, next, err = db.Rds.HScan(db.Ctx, db.Key, cursor, match, count).Result(); err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+	mapOut = make(map[k]v, len(page)/2)
+	for i := 0; i+1 < len(page); i += 2 {
+		if key, err = db.toKey([]byte(page[i])); err != nil {
+			continue
+		}
+		if value, err = db.toValue([]byte(page[i+1])); err != nil {
+			continue
+		}
+		mapOut[key] = value
+	}
+	return mapOut, next, nil
+}
@@ -9,7 +9,7 @@ func (db *Ctx[k, v]) SAdd(param v) (err error) {
 		return err
 	}
 	status := db.Rds.SAdd(db.Ctx, db.Key, valStr)
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) SRem(param v) (err error) {
 	valStr, err := db.toValueStr(param)
@@ -17,7 +17,7 @@ func (db *Ctx[k, v]) SRem(param v) (err error) {
 		return err
 	}
 	status := db.Rds.SRem(db.Ctx, db.Key, valStr)
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) SIsMember(param v) (isMember bool, err error) {
 	valStr, err := db.toValueStr(param)
@@ -61,6 +61,9 @@ func (db *Ctx[k, v]) SetAll(_map map[k]v) (err error) {
 		}
 
 		pipe.Set(db.Ctx, db.Key+":"+keyStr, bytes, -1)
+		if db.TrackModified {
+			TouchModified(db.Ctx, pipe, db.Namespace, db.Key+":"+keyStr)
+		}
 	}
 	pipe.Exec(db.Ctx)
 	return result
@@ -49,7 +49,7 @@ func (db *Ctx[k, v]) Set(key k, param v, expiration time.Duration) (err error) {
 		return err
 	} else {
 		status := db.Rds.Set(db.Ctx, db.Key+":"+keyStr, valStr, expiration)
-		return status.Err()
+		return db.modified(status.Err(), db.Key+":"+keyStr)
 	}
 }
 
@@ -62,5 +62,5 @@ func (db *Ctx[k, v]) Del(key k) (err error) {
 		return err
 	}
 	status := db.Rds.Del(db.Ctx, db.Key+":"+keyStr)
-	return status.Err()
+	return db.deleted(status.Err(), db.Key+":"+keyStr)
 }
@@ -13,7 +13,7 @@ func (db *Ctx[k, v]) ZAdd(members ...redis.Z) (err error) {
 		}
 	}
 	status := db.Rds.ZAdd(db.Ctx, db.Key, members...)
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) ZRem(members ...interface{}) (err error) {
 	//msgpack marshal members to slice of bytes
@@ -29,7 +29,7 @@ func (db *Ctx[k, v]) ZRem(members ...interface{}) (err error) {
 	}
 	_, err = redisPipe.Exec(db.Ctx)
 
-	return err
+	return db.modified(err)
 }
 func (db *Ctx[k, v]) ZRange(start, stop int64) (members []v, err error) {
 	var cmd *redis.StringSliceCmd
@@ -120,11 +120,11 @@ func (db *Ctx[k, v]) ZRevRangeByScoreWithScores(opt *redis.ZRangeBy) (out []v, s
 }
 func (db *Ctx[k, v]) ZRemRangeByRank(start, stop int64) (err error) {
 	status := db.Rds.ZRemRangeByRank(db.Ctx, db.Key, start, stop)
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) ZRemRangeByScore(min, max string) (err error) {
 	status := db.Rds.ZRemRangeByScore(db.Ctx, db.Key, min, max)
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) ZIncrBy(increment float64, member interface{}) (err error) {
 	var (
@@ -135,7 +135,7 @@ func (db *Ctx[k, v]) ZIncrBy(increment float64, member interface{}) (err error)
 		return err
 	}
 	status := db.Rds.ZIncrBy(db.Ctx, db.Key, increment, string(memberBytes))
-	return status.Err()
+	return db.modified(status.Err())
 }
 func (db *Ctx[k, v]) ZPopMax(count int64) (out []v, scores []float64, err error) {
 	cmd := db.Rds.ZPopMax(db.Ctx, db.Key, count)
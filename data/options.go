@@ -5,6 +5,10 @@ package data
 type DataOption struct {
 	Key        string
 	DataSource string
+	// Namespace prefixes the key, i.g. "acme:user". empty means config.Cfg.Namespace
+	Namespace string
+	// TrackModified records the modification time of the key on writes, for Last-Modified of http responses
+	TrackModified bool
 }
 
 var Option *DataOption
@@ -24,3 +28,21 @@ func (o *DataOption) WithDataSource(dataSource string) (out *DataOption) {
 	out.DataSource = dataSource
 	return out
 }
+
+// WithNamespace puts the key in the namespace, apart from the same key of other namespaces
+func (o *DataOption) WithNamespace(namespace string) (out *DataOption) {
+	if out = o; o == Option {
+		out = &DataOption{}
+	}
+	out.Namespace = namespace
+	return out
+}
+
+// WithTrackModified records the modification time of the key on every write, in the hash ModifiedKey
+func (o *DataOption) WithTrackModified() (out *DataOption) {
+	if out = o; o == Option {
+		out = &DataOption{}
+	}
+	out.TrackModified = true
+	return out
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+// KeyInfo describes a data key created by New, with the types of its fields and values
+type KeyInfo struct {
+	Key        string
+	DataSource string
+	KeyType    reflect.Type
+	ValueType  reflect.Type
+	// TrackModified keys have their modification time in ModifiedKey
+	TrackModified bool
+}
+
+var registeredKeys cmap.ConcurrentMap[string, *KeyInfo] = cmap.New[*KeyInfo]()
+
+// registerKey records the key, unless the value type is an interface, as in the https handlers,
+// where key names come from requests
+func registerKey[k comparable, v any](key, dataSource string, trackModified bool) {
+	valueType := reflect.TypeOf((*v)(nil)).Elem()
+	if valueType.Kind() == reflect.Interface {
+		return
+	}
+	registeredKeys.Set(key, &KeyInfo{Key: key, DataSource: dataSource, KeyType: reflect.TypeOf((*k)(nil)).Elem(), ValueType: valueType, TrackModified: trackModified})
+}
+
+// RegisteredKeys returns the data keys created by New with concrete value types, sorted by key
+func RegisteredKeys() (keys []*KeyInfo) {
+	for _, keyInfo := range registeredKeys.Items() {
+		keys = append(keys, keyInfo)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+	return keys
+}
+
+// KeyInfoOf returns the registered key, or the registered key that key is concatenated from, i.g. "user" of "user:1"
+func KeyInfoOf(key string) (keyInfo *KeyInfo, ok bool) {
+	for {
+		if keyInfo, ok = registeredKeys.Get(key); ok {
+			return keyInfo, true
+		}
+		i := strings.LastIndex(key, ":")
+		if i < 0 {
+			return nil, false
+		}
+		key = key[:i]
+	}
+}
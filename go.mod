@@ -4,6 +4,7 @@ go 1.20
 
 require (
 	github.com/bits-and-blooms/bloom/v3 v3.6.0
+	github.com/fxamacker/cbor/v2 v2.9.4
 	github.com/go-ping/ping v1.1.0
 	github.com/golang-jwt/jwt/v5 v5.0.0-rc.1
 	github.com/jinzhu/copier v0.3.5
@@ -25,6 +26,7 @@ require (
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/net v0.15.0 // indirect
 	golang.org/x/sync v0.3.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
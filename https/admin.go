@@ -0,0 +1,185 @@
+package https
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/permission"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+var (
+	ErrNotAdmin      = errors.New("admin claim required in JWT")
+	ErrAdminDisabled = errors.New("admin endpoints are disabled without Jwt.Secret")
+	// ErrRuleIncomplete is responded as 400, rather than writing a rule of empty key or operation to the permission table
+	ErrRuleIncomplete = errors.New("key and operation of the permission are required")
+)
+
+// AdminHandler serves operational introspection under prefix, i.g. /admin/apis.
+// every request needs a JWT (header Authorization) with the claim config.Cfg.Jwt.AdminClaim set to true,
+// signed with config.Cfg.Jwt.Secret. every request is rejected if the secret is empty
+//
+//	GET    apis                              registered apis and their data sources
+//	GET    streams?api=                      stream length, pending and lag per consumer group. all apis if api is empty
+//	GET    tasks                             delayed tasks waiting to be dispatched by this instance
+//	DELETE tasks?api=&timeAt=                cancel the delayed task. timeAt is unix ns
+//	GET    deadletters?api=&count=           failed calls, newest first
+//	POST   deadletters/replay?api=&id=       put failed calls back to the api stream. id can be comma separated
+//	GET    permissions                       the permission table
+//	PUT    permissions?key=&operation=&allow= allow (default) or deny the operation on the data key
+//	DELETE permissions?key=&operation=       remove the rule
+//	GET    instances?ds=                     live instances reporting to the data source
+func AdminHandler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			result interface{}
+			err    error
+			status int = http.StatusOK
+		)
+		if CorsChecked(r, w) {
+			return
+		}
+		if err = adminAuthorized(r); err != nil {
+			if status = http.StatusUnauthorized; errors.Is(err, ErrNotAdmin) || errors.Is(err, ErrAdminDisabled) {
+				status = http.StatusForbidden
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		route := r.Method + " " + strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		switch route {
+		case "GET apis":
+			result = adminApis()
+		case "GET streams":
+			result, err = adminStreams(r.FormValue("api"))
+		case "GET tasks":
+			result = api.ScheduledTasks()
+		case "DELETE tasks":
+			var timeAt int64
+			if timeAt, err = strconv.ParseInt(r.FormValue("timeAt"), 10, 64); err == nil {
+				err = api.CancelScheduledTask(adminApiName(r.FormValue("api")), time.Unix(0, timeAt))
+			}
+			result = "canceled"
+		case "GET deadletters":
+			count, _ := strconv.ParseInt(r.FormValue("count"), 10, 64)
+			result, err = api.DeadLetters(adminApiName(r.FormValue("api")), count)
+		case "POST deadletters/replay":
+			result, err = api.ReplayDeadLetters(adminApiName(r.FormValue("api")), strings.Split(r.FormValue("id"), ",")...)
+		case "GET permissions":
+			result, err = permission.Rules()
+		case "PUT permissions":
+			if key, operation := r.FormValue("key"), r.FormValue("operation"); len(key) == 0 || len(operation) == 0 {
+				err = ErrRuleIncomplete
+			} else if r.FormValue("allow") == "false" {
+				err = permission.Deny(key, operation)
+			} else {
+				err = permission.Grant(key, operation)
+			}
+			result = "updated"
+		case "DELETE permissions":
+			if key, operation := r.FormValue("key"), r.FormValue("operation"); len(key) == 0 || len(operation) == 0 {
+				err = ErrRuleIncomplete
+			} else {
+				err = permission.Revoke(key, operation)
+			}
+			result = "revoked"
+		case "GET instances":
+			result, err = api.LiveInstances(r.FormValue("ds"))
+		default:
+			http.Error(w, "admin route not found: "+route, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, api.ErrApiNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, ErrRuleIncomplete) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(config.Cfg.Http.CORES) > 0 {
+			w.Header().Set("Access-Control-Allow-Origin", config.Cfg.Http.CORES)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+}
+
+// adminAuthorized reuses the JWT parsing of api requests, and checks the admin claim
+func adminAuthorized(r *http.Request) (err error) {
+	//an empty HMAC key is accepted by jwt, so tokens would be forged by anyone
+	if len(config.Cfg.Jwt.Secret) == 0 {
+		return ErrAdminDisabled
+	}
+	svc := &HttpContext{Req: r}
+	if err = svc.ParseJwtToken(); err != nil {
+		return err
+	}
+	mpclaims, ok := svc.jwtToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrNotAdmin
+	}
+	switch claim := mpclaims[config.Cfg.Jwt.AdminClaim].(type) {
+	case bool:
+		ok = claim
+	case string:
+		ok = claim == "true"
+	case int64:
+		ok = claim != 0
+	default:
+		ok = false
+	}
+	if !ok {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// adminApiName accepts api name with or without "api:" prefix
+func adminApiName(name string) string {
+	if len(strings.TrimPrefix(name, "api:")) == 0 {
+		return ""
+	}
+	return specification.ApiName(name)
+}
+
+type adminApi struct {
+	Name       string
+	DataSource string
+	WithHeader bool
+	Coalesce   bool
+}
+
+func adminApis() (apis []*adminApi) {
+	for _, apiInfo := range api.ApiServices.Items() {
+		apis = append(apis, &adminApi{Name: apiInfo.Name, DataSource: apiInfo.DataSource, WithHeader: apiInfo.WithHeader, Coalesce: apiInfo.Coalesce})
+	}
+	sort.Slice(apis, func(i, j int) bool { return apis[i].Name < apis[j].Name })
+	return apis
+}
+
+func adminStreams(apiName string) (states []*api.StreamState, err error) {
+	var apiNames []string = []string{adminApiName(apiName)}
+	if len(apiName) == 0 {
+		apiNames = api.ApiServices.Keys()
+		sort.Strings(apiNames)
+	}
+	for _, name := range apiNames {
+		apiStates, err := api.StreamStates(name)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, apiStates...)
+	}
+	return states, nil
+}
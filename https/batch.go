@@ -0,0 +1,197 @@
+package https
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+)
+
+var ErrBatchTooLarge = errors.New("too many operations in batch")
+
+// BatchOp is one command of a BATCH request. Params are the url query parameters of the command,
+// and Value the value to write, i.g. {"cmd":"ZRANGE","key":"rank","params":{"Start":0,"Stop":9}}
+type BatchOp struct {
+	Cmd    string                 `json:"cmd" msgpack:"cmd"`
+	Key    string                 `json:"key" msgpack:"key"`
+	Field  string                 `json:"field,omitempty" msgpack:"field,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty" msgpack:"params,omitempty"`
+	Value  interface{}            `json:"value,omitempty" msgpack:"value,omitempty"`
+}
+
+// BatchResult is the result of a BatchOp, or its error
+type BatchResult struct {
+	Result interface{} `json:"result,omitempty" msgpack:"result,omitempty"`
+	Error  string      `json:"error,omitempty" msgpack:"error,omitempty"`
+}
+
+// Param is the parameter of the command: of the BatchOp, or of the url query
+func (svc *HttpContext) Param(name string) string {
+	if svc.params == nil {
+		return svc.Req.FormValue(name)
+	}
+	if value, ok := svc.params[name]; ok && value != nil {
+		return paramString(value)
+	}
+	return ""
+}
+
+// paramString formats the parameter as the url query would carry it. json numbers are float64,
+// and fmt.Sprint formats 1000000 as "1e+06", which no integer parameter parses
+func paramString(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return fmt.Sprint(value)
+}
+
+// batch runs the operations of the body in one pipeline, each permitted and responded as if it were requested alone.
+// i.g. POST /BATCH-! with body [{"cmd":"HGET","key":"user","field":"@id","params":{"Queries":"name"}},{"cmd":"ZCARD","key":"rank"}]
+func (svcCtx *HttpContext) batch() (results []*BatchResult, err error) {
+	var (
+		ops      []*BatchOp
+		cmds     []redis.Cmder
+		converts []func(value interface{}) interface{}
+		rds      *redis.Client
+	)
+	if body := svcCtx.MsgpackBodyBytes(); len(body) > 0 {
+		err = msgpack.Unmarshal(body, &ops)
+	} else if body = svcCtx.JsonBodyBytes(); len(body) > 0 {
+		err = json.Unmarshal(body, &ops)
+	} else {
+		return nil, errors.New("missing batch operations")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) > int(config.Cfg.Http.MaxBatchOps) {
+		return nil, ErrBatchTooLarge
+	}
+	if rds, err = svcCtx.rds(); err != nil {
+		return nil, err
+	}
+	results, cmds, converts = make([]*BatchResult, len(ops)), make([]redis.Cmder, len(ops)), make([]func(value interface{}) interface{}, len(ops))
+	pipe := rds.Pipeline()
+	for i, op := range ops {
+		results[i] = &BatchResult{}
+		if cmds[i], converts[i], err = svcCtx.queueOp(pipe, op); err != nil {
+			cmds[i], results[i].Error = nil, err.Error()
+		}
+	}
+	//errors of commands are kept in cmds
+	if pipe.Len() > 0 {
+		pipe.Exec(svcCtx.Ctx)
+	}
+	for i, op := range ops {
+		if cmds[i] == nil {
+			continue
+		}
+		if results[i].Result, err = commands[op.cmd()].result(cmds[i], converts[i]); err != nil {
+			results[i].Result, results[i].Error = nil, err.Error()
+		}
+	}
+	return results, nil
+}
+
+func (op *BatchOp) cmd() string {
+	return strings.ToUpper(op.Cmd)
+}
+
+// child is the context of an operation of the request, sharing its jwt, data source and namespace
+func (svcCtx *HttpContext) child(cmd, key, field string, params map[string]interface{}) *HttpContext {
+	//parse the jwt once for all operations
+	if svcCtx.jwtToken == nil && len(svcCtx.Req.Header.Get("Authorization")) > 0 {
+		svcCtx.ParseJwtToken()
+	}
+	return &HttpContext{Req: svcCtx.Req, Rsb: svcCtx.Rsb, jwtToken: svcCtx.jwtToken, Ctx: svcCtx.Ctx, RedisDataSource: svcCtx.RedisDataSource,
+		Cmd: cmd, Key: key, Field: field, Namespace: svcCtx.Namespace, gateway: svcCtx.gateway, params: params}
+}
+
+// queueOp checks the permission of the operation, and adds it to the pipeline.
+// convert is the converter of its result, by the Queries param of the operation or the registered type of the key, as of single commands
+func (svcCtx *HttpContext) queueOp(pipe redis.Pipeliner, op *BatchOp) (cmd redis.Cmder, convert func(value interface{}) interface{}, err error) {
+	var (
+		operation string
+		command   *Command
+		ok        bool
+		params    = op.Params
+	)
+	if command, ok = commands[op.cmd()]; !ok {
+		return nil, nil, ErrBadCommand
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	params["Value"] = op.Value
+	//the operation is checked as a request of its own, sharing the jwt of the batch
+	svc := svcCtx.child(op.cmd(), op.Key, op.Field, params)
+	if operation, err = svc.KeyFieldAtJwt(); err != nil {
+		return nil, nil, err
+	}
+	if !svc.permitted(operation) {
+		return nil, nil, ErrOperationNotPermited
+	}
+	if command.Values != nil {
+		if convert, err = svc.converter(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cmd, err = command.Queue(svc, pipe); err == nil && command.Method != http.MethodGet {
+		svc.touchModified(pipe)
+	}
+	return cmd, convert, err
+}
+
+// value is the msgpack encoded value to write: Value of the BatchOp, or the msgpack body
+func (svc *HttpContext) value() (bytes []byte, err error) {
+	if svc.params == nil {
+		return svc.MsgpackBody()
+	}
+	if value := svc.params["Value"]; value != nil {
+		return msgpack.Marshal(value)
+	}
+	return nil, errors.New("missing value")
+}
+
+// member is the msgpack encoded member parameter, as members are stored by data.Ctx
+func (svc *HttpContext) member() (string, error) {
+	bytes, err := msgpack.Marshal(svc.Param("Member"))
+	return string(bytes), err
+}
+
+func (svc *HttpContext) int64Param(name string) (int64, error) {
+	i, err := strconv.ParseInt(svc.Param(name), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s error: %v", name, err)
+	}
+	return i, nil
+}
+
+// rangeBy is the Min and Max parameters, and the page of Offset and Count if Count is set
+func (svc *HttpContext) rangeBy() (rangeBy *redis.ZRangeBy, err error) {
+	rangeBy = &redis.ZRangeBy{Min: svc.Param("Min"), Max: svc.Param("Max")}
+	if rangeBy.Min == "" || rangeBy.Max == "" {
+		return nil, errors.New("no Min or Max")
+	}
+	if len(svc.Param("Count")) == 0 {
+		return rangeBy, nil
+	}
+	if rangeBy.Count, err = svc.pageSize(); err != nil {
+		return nil, err
+	}
+	if len(svc.Param("Offset")) > 0 {
+		if rangeBy.Offset, err = svc.int64Param("Offset"); err != nil || rangeBy.Offset < 0 {
+			return nil, errors.New("parameter Offset should be non-negative integer")
+		}
+	}
+	return rangeBy, nil
+}
@@ -0,0 +1,103 @@
+package https
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/data"
+)
+
+// cacheable responses are of data commands read by GET. api results are never cached
+func (svc *HttpContext) cacheable() bool {
+	command, ok := commands[svc.Cmd]
+	return ok && command.Method == http.MethodGet && svc.Req.Method == http.MethodGet
+}
+
+// redisKey is the redis key the command reads or writes. strings and blobs are stored at key:field, as data.Ctx stores them
+func (svc *HttpContext) redisKey() string {
+	if svc.Cmd == "GET" || svc.Cmd == "SET" || svc.Cmd == "BLOB" || svc.Cmd == "SETBLOB" || svc.Cmd == "DELBLOB" {
+		return svc.DataKey() + ":" + svc.Field
+	}
+	return svc.DataKey()
+}
+
+// etag is strong, as it is the sha256 of the encoded response. the content type is hashed too,
+// because json and msgpack of the same value are different representations
+func etag(contentType string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(contentType))
+	h.Write([]byte{0})
+	h.Write(body)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// cacheControl is the policy of the key, by Http.CacheControl. an exact key wins over patterns, and longer patterns win over shorter ones
+func cacheControl(key string) (policy string) {
+	var matched string
+	for pattern, value := range config.Cfg.Http.CacheControl {
+		if pattern == key {
+			return value
+		}
+		if ok, _ := path.Match(pattern, key); ok && len(pattern) > len(matched) {
+			matched, policy = pattern, value
+		}
+	}
+	return policy
+}
+
+// etagMatch is the weak comparison of If-None-Match, as GET requires
+func etagMatch(ifNoneMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/"); candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheHeaders sets ETag, Cache-Control and Last-Modified of the response,
+// and is true if the client has the response already, by If-None-Match, or by If-Modified-Since without If-None-Match
+func (svc *HttpContext) cacheHeaders(w http.ResponseWriter, body []byte) (notModified bool) {
+	var (
+		tag      = etag(svc.ResponseContentType, body)
+		modified time.Time
+	)
+	w.Header().Set("ETag", tag)
+	if policy := cacheControl(svc.Key); len(policy) > 0 {
+		w.Header().Set("Cache-Control", policy)
+	}
+	if keyInfo, ok := data.KeyInfoOf(svc.Key); ok && keyInfo.TrackModified {
+		if rds, err := svc.rds(); err == nil {
+			modified, _ = data.ModifiedAt(svc.Ctx, rds, svc.Namespace, svc.redisKey())
+		}
+	}
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if ifNoneMatch := svc.Req.Header.Get("If-None-Match"); len(ifNoneMatch) > 0 {
+		return etagMatch(ifNoneMatch, tag)
+	}
+	if since, err := http.ParseTime(svc.Req.Header.Get("If-Modified-Since")); err == nil && !modified.IsZero() {
+		return !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// touchModified queues the modification time of the key written by the command, if the key is tracked.
+// DEL forgets the modification time instead, so that the hash does not keep deleted keys
+func (svc *HttpContext) touchModified(pipe redis.Pipeliner) {
+	if keyInfo, ok := data.KeyInfoOf(svc.Key); !ok || !keyInfo.TrackModified {
+		return
+	}
+	if svc.Cmd == "DEL" {
+		data.ForgetModified(svc.Ctx, pipe, svc.Namespace, svc.redisKey())
+	} else {
+		data.TouchModified(svc.Ctx, pipe, svc.Namespace, svc.redisKey())
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// BatchOp is one command of Client.Batch. Params are the url query parameters of the command, Value the value to write
+type BatchOp struct {
+	Cmd    string                 `json:"cmd" msgpack:"cmd"`
+	Key    string                 `json:"key" msgpack:"key"`
+	Field  string                 `json:"field,omitempty" msgpack:"field,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty" msgpack:"params,omitempty"`
+	Value  interface{}            `json:"value,omitempty" msgpack:"value,omitempty"`
+}
+
+// BatchResult is the result of a BatchOp. Err is not nil if the operation failed
+type BatchResult struct {
+	Raw         []byte
+	contentType string
+	Err         error
+}
+
+// Decode decodes the result to out, as Key does
+func (r *BatchResult) Decode(out interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return decodeValue(r.Raw, r.contentType, out)
+}
+
+// Batch runs the operations in one request and one redis pipeline, and returns their results in order
+func (c *Client) Batch(ctx context.Context, ops []*BatchOp, options ...*CallOption) (results []*BatchResult, err error) {
+	var (
+		body        []byte
+		contentType string
+		r           = &request{method: http.MethodPost, cmd: "BATCH", body: ops}
+	)
+	if len(options) > 0 {
+		r.option = options[0]
+	}
+	if body, contentType, err = c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return batchResults(body, contentType)
+}
+
+// Precondition of Client.Transaction, see https.Precondition
+type Precondition struct {
+	Type  string      `json:"type" msgpack:"type"`
+	Key   string      `json:"key" msgpack:"key"`
+	Field string      `json:"field,omitempty" msgpack:"field,omitempty"`
+	Value interface{} `json:"value,omitempty" msgpack:"value,omitempty"`
+}
+
+// Transaction runs the operations atomically, if all preconditions hold. otherwise it fails with ErrConflict
+func (c *Client) Transaction(ctx context.Context, preconditions []*Precondition, ops []*BatchOp, options ...*CallOption) (results []*BatchResult, err error) {
+	var (
+		body        []byte
+		contentType string
+		r           = &request{method: http.MethodPost, cmd: "TXN", body: map[string]interface{}{"preconditions": preconditions, "ops": ops}}
+	)
+	if len(options) > 0 {
+		r.option = options[0]
+	}
+	if body, contentType, err = c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return batchResults(body, contentType)
+}
+
+func batchResults(body []byte, contentType string) (results []*BatchResult, err error) {
+	var replies []struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if isMsgpack(contentType) {
+		return msgpackBatchResults(body, contentType)
+	}
+	if err = json.Unmarshal(body, &replies); err != nil {
+		return nil, err
+	}
+	results = make([]*BatchResult, len(replies))
+	for i, reply := range replies {
+		results[i] = &BatchResult{Raw: reply.Result, contentType: contentType}
+		if len(reply.Error) > 0 {
+			results[i].Err = newStatusError(http.StatusInternalServerError, []byte(reply.Error))
+		}
+	}
+	return results, nil
+}
+
+func msgpackBatchResults(body []byte, contentType string) (results []*BatchResult, err error) {
+	var replies []struct {
+		Result msgpack.RawMessage `msgpack:"result"`
+		Error  string             `msgpack:"error"`
+	}
+	if err = msgpack.Unmarshal(body, &replies); err != nil {
+		return nil, err
+	}
+	results = make([]*BatchResult, len(replies))
+	for i, reply := range replies {
+		results[i] = &BatchResult{Raw: reply.Result, contentType: contentType}
+		if len(reply.Error) > 0 {
+			results[i].Err = newStatusError(http.StatusInternalServerError, []byte(reply.Error))
+		}
+	}
+	return results, nil
+}
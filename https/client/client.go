@@ -0,0 +1,271 @@
+// package client calls a remote saavuu http server: apis, and redis commands on data keys, i.g.
+//
+//	c := client.New("https://api.example.com/rSvc", client.Option.WithToken(client.StaticToken(jwt)))
+//	user, err := client.NewKey[string, *User](c, "user").HGet(id)
+//	ret, err := client.CallAPI[*InDemo, string](ctx, c, "demo", &InDemo{Text: "hello"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ClientOption is parameter to create a Client
+type ClientOption struct {
+	HttpClient *http.Client
+	Token      TokenSource
+	// Retries is the number of retries of a failed request, that can be retried safely
+	Retries int
+	// RetryWait is the wait before the first retry, doubled every retry
+	RetryWait time.Duration
+}
+
+var Option *ClientOption
+
+func (o *ClientOption) WithHttpClient(httpClient *http.Client) (out *ClientOption) {
+	if out = o; o == Option {
+		out = &ClientOption{}
+	}
+	out.HttpClient = httpClient
+	return out
+}
+
+// WithToken sets the source of the JWT, sent as the Authorization header
+func (o *ClientOption) WithToken(token TokenSource) (out *ClientOption) {
+	if out = o; o == Option {
+		out = &ClientOption{}
+	}
+	out.Token = token
+	return out
+}
+func (o *ClientOption) WithRetries(retries int, retryWait time.Duration) (out *ClientOption) {
+	if out = o; o == Option {
+		out = &ClientOption{}
+	}
+	out.Retries, out.RetryWait = retries, retryWait
+	return out
+}
+
+// Client is a remote saavuu http server. it is safe for concurrent use
+type Client struct {
+	// BaseURL is the url of Http.Path of the server, i.g. https://api.example.com/rSvc
+	BaseURL    string
+	HttpClient *http.Client
+	Token      TokenSource
+	Retries    int
+	RetryWait  time.Duration
+}
+
+func New(baseURL string, options ...*ClientOption) *Client {
+	var option *ClientOption = &ClientOption{}
+	if len(options) > 0 && options[0] != nil {
+		option = options[0]
+	}
+	c := &Client{BaseURL: strings.TrimRight(baseURL, "/"), HttpClient: option.HttpClient, Token: option.Token, Retries: 2, RetryWait: 200 * time.Millisecond}
+	if c.HttpClient == nil {
+		c.HttpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if option.Retries > 0 || option.RetryWait > 0 {
+		c.Retries, c.RetryWait = option.Retries, option.RetryWait
+	}
+	return c
+}
+
+// request is one http call of a command on a key
+type request struct {
+	method, cmd, key string
+	query            url.Values
+	// body is msgpack encoded, if not nil
+	body   interface{}
+	option *CallOption
+}
+
+// url follows https.NewHttpContext: {BaseURL}/{CMD}-!{Key}[-!DS={data source}]?F={field}
+func (r *request) url(baseURL string) string {
+	var u strings.Builder
+	u.WriteString(baseURL + "/" + r.cmd + "-!" + url.PathEscape(r.key))
+	if r.option != nil && len(r.option.DataSource) > 0 {
+		u.WriteString("-!DS=" + url.QueryEscape(r.option.DataSource))
+	}
+	if len(r.query) > 0 {
+		u.WriteString("?" + r.query.Encode())
+	}
+	return u.String()
+}
+
+// retryable is true if the request can be sent again without side effects.
+// posts are apis or ZADD, retried only with an idempotency key
+func (r *request) retryable() bool {
+	return r.method != http.MethodPost || r.option != nil && len(r.option.IdempotencyKey) > 0
+}
+
+// do sends the request, and returns the response body and content type of a 2xx response
+func (c *Client) do(ctx context.Context, r *request) (body []byte, contentType string, err error) {
+	var (
+		payload   []byte
+		refreshed bool
+		wait      time.Duration = c.RetryWait
+	)
+	if r.body != nil {
+		if payload, err = msgpack.Marshal(r.body); err != nil {
+			return nil, "", err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		var (
+			statusCode int
+			retryAfter time.Duration
+		)
+		body, contentType, statusCode, retryAfter, err = c.send(ctx, r, payload, refreshed)
+		if err == nil && statusCode >= 200 && statusCode < 300 {
+			return body, contentType, nil
+		}
+		if err == nil {
+			statusErr := newStatusError(statusCode, body)
+			//the token may be expired, refresh it once
+			if err = statusErr; statusCode == http.StatusUnauthorized && c.Token != nil && !refreshed {
+				refreshed = true
+				continue
+			}
+			//503 means the call is rejected before being queued, so even posts are safe to retry
+			if statusErr.Err != ErrOverloaded && (statusErr.Err != ErrServer || !r.retryable()) {
+				return nil, "", err
+			}
+		} else if ctx.Err() != nil || !r.retryable() {
+			return nil, "", err
+		}
+		if attempt >= c.Retries {
+			return nil, "", err
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, r *request, payload []byte, refresh bool) (body []byte, contentType string, statusCode int, retryAfter time.Duration, err error) {
+	var (
+		req *http.Request
+		rsp *http.Response
+		jwt string
+	)
+	if req, err = http.NewRequestWithContext(ctx, r.method, r.url(c.BaseURL), bytes.NewReader(payload)); err != nil {
+		return nil, "", 0, 0, err
+	}
+	if len(payload) > 0 {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set("Accept", "application/msgpack, application/json;q=0.9, */*;q=0.8")
+	if c.Token != nil {
+		if jwt, err = c.Token.Token(ctx, refresh); err != nil {
+			return nil, "", 0, 0, err
+		}
+		req.Header.Set("Authorization", jwt)
+	}
+	if o := r.option; o != nil {
+		if len(o.IdempotencyKey) > 0 {
+			req.Header.Set("Idempotency-Key", o.IdempotencyKey)
+		}
+		if o.Priority != nil {
+			req.Header.Set("X-Priority", strconv.Itoa(*o.Priority))
+		}
+		if len(o.TraceParent) > 0 {
+			req.Header.Set("traceparent", o.TraceParent)
+		}
+	}
+	if rsp, err = c.HttpClient.Do(req); err != nil {
+		return nil, "", 0, 0, err
+	}
+	defer rsp.Body.Close()
+	if body, err = io.ReadAll(rsp.Body); err != nil {
+		return nil, "", 0, 0, err
+	}
+	if seconds, e := strconv.Atoi(rsp.Header.Get("Retry-After")); e == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return body, rsp.Header.Get("Content-Type"), rsp.StatusCode, retryAfter, nil
+}
+
+// CallAPI calls the api of the remote server, with the input msgpack encoded.
+// the output is decoded as the server marshals it: msgpack as the client accepts it, or []byte and string as they are,
+// and other types as json
+func CallAPI[i any, o any](ctx context.Context, c *Client, apiName string, in i, options ...*CallOption) (out o, err error) {
+	var (
+		body        []byte
+		contentType string
+		r           = &request{method: http.MethodPost, cmd: "API", key: strings.TrimPrefix(apiName, "api:"), body: in}
+	)
+	if len(options) > 0 {
+		r.option = options[0]
+	}
+	if body, contentType, err = c.do(ctx, r); err != nil {
+		return out, err
+	}
+	if isMsgpack(contentType) {
+		return out, msgpack.Unmarshal(body, &out)
+	}
+	switch p := interface{}(&out).(type) {
+	case *[]byte:
+		*p = body
+	case *string:
+		*p = string(body)
+	default:
+		err = json.Unmarshal(body, &out)
+	}
+	return out, err
+}
+
+func isMsgpack(contentType string) bool {
+	return strings.Contains(contentType, "msgpack")
+}
+
+// decodeValue decodes data values. the server decodes them from msgpack without their types, then encodes them as json,
+// so keys are the msgpack names. it is converted back to msgpack to decode with the msgpack tags of out
+func decodeValue(body []byte, contentType string, out interface{}) (err error) {
+	var value interface{}
+	if isMsgpack(contentType) {
+		return msgpack.Unmarshal(body, out)
+	}
+	//string values are responded as they are
+	if json.Unmarshal(body, &value) != nil {
+		value = string(body)
+	}
+	if body, err = msgpack.Marshal(integral(value)); err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(body, out)
+}
+
+// integral converts json numbers that are integers to int64, so that they decode to integer fields
+func integral(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = integral(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = integral(v[key])
+		}
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is the key or field not exists. the server responds it as redis: nil
+	ErrNotFound     = errors.New("not found")
+	ErrNotPermitted = errors.New("operation not permitted")
+	// ErrOverloaded is the api stream too long to accept calls, retry later
+	ErrOverloaded = errors.New("server overloaded")
+	ErrServer     = errors.New("server error")
+	// ErrConflict is a precondition of a transaction failed, read and retry
+	ErrConflict = errors.New("conflict")
+)
+
+// StatusError is a non 2xx response. errors.Is matches it with the Err of the status code and message
+type StatusError struct {
+	StatusCode int
+	// Message is the response body, the error of the server
+	Message string
+	Err     error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("saavuu: %d %s", e.StatusCode, e.Message)
+}
+func (e *StatusError) Unwrap() error { return e.Err }
+
+func newStatusError(statusCode int, body []byte) *StatusError {
+	e := &StatusError{StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	switch {
+	case statusCode == http.StatusBadRequest:
+		e.Err = ErrBadRequest
+	case statusCode == http.StatusUnauthorized:
+		e.Err = ErrUnauthorized
+	case statusCode == http.StatusForbidden, strings.Contains(e.Message, "not permitted"), strings.Contains(e.Message, "permission denied"):
+		e.Err = ErrNotPermitted
+	case statusCode == http.StatusNotFound, e.Message == "redis: nil":
+		e.Err = ErrNotFound
+	case statusCode == http.StatusConflict:
+		e.Err = ErrConflict
+	case statusCode == http.StatusServiceUnavailable:
+		e.Err = ErrOverloaded
+	default:
+		e.Err = ErrServer
+	}
+	return e
+}
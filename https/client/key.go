@@ -0,0 +1,382 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is a data key of the remote server, with the methods of data.Ctx over http.
+// values are msgpack encoded, as data.Ctx does, so both share the same data
+type Key[k comparable, v any] struct {
+	Ctx    context.Context
+	Client *Client
+	Key    string
+	Option *CallOption
+}
+
+func NewKey[k comparable, v any](c *Client, key string, options ...*CallOption) *Key[k, v] {
+	var option *CallOption
+	if len(options) > 0 {
+		option = options[0]
+	}
+	return &Key[k, v]{Ctx: context.Background(), Client: c, Key: key, Option: option}
+}
+
+// WithContext returns a copy of the key, with requests bound to ctx
+func (key *Key[k, v]) WithContext(ctx context.Context) *Key[k, v] {
+	return &Key[k, v]{ctx, key.Client, key.Key, key.Option}
+}
+
+// Concat appends fields to the key, as data.Ctx.Concat
+func (key *Key[k, v]) Concat(fields ...interface{}) *Key[k, v] {
+	results := make([]string, 0, len(fields)+1)
+	results = append(results, key.Key)
+	for _, field := range fields {
+		results = append(results, fmt.Sprintf("%v", field))
+	}
+	return &Key[k, v]{key.Ctx, key.Client, strings.Join(results, ":"), key.Option}
+}
+
+func (key *Key[k, v]) do(method, cmd string, query url.Values, body interface{}) (rsp []byte, contentType string, err error) {
+	return key.Client.do(key.Ctx, &request{method: method, cmd: cmd, key: key.Key, query: query, body: body, option: key.Option})
+}
+
+// get runs the command, and decodes the result to out
+func (key *Key[k, v]) get(cmd string, query url.Values, out interface{}) (err error) {
+	return key.send(http.MethodGet, cmd, query, nil, out)
+}
+
+// send runs the command, and decodes the result to out
+func (key *Key[k, v]) send(method, cmd string, query url.Values, body interface{}, out interface{}) (err error) {
+	var (
+		rsp         []byte
+		contentType string
+	)
+	if rsp, contentType, err = key.do(method, cmd, query, body); err != nil {
+		return err
+	}
+	return decodeValue(rsp, contentType, out)
+}
+
+// exec runs the command, that responds "true" on success
+func (key *Key[k, v]) exec(method, cmd string, query url.Values, body interface{}) (err error) {
+	_, _, err = key.do(method, cmd, query, body)
+	return err
+}
+
+func field[k comparable](f k) url.Values {
+	return url.Values{"F": {fmt.Sprint(f)}}
+}
+
+// parseField converts a hash field, or map key, from string to k
+func parseField[k comparable](s string) (f k, err error) {
+	if p, ok := interface{}(&f).(*string); ok {
+		*p = s
+		return f, nil
+	}
+	return f, json.Unmarshal([]byte(s), &f)
+}
+
+func parseFields[k comparable](strs []string) (fields []k, err error) {
+	fields = make([]k, len(strs))
+	for i, s := range strs {
+		if fields[i], err = parseField[k](s); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// string, stored at key:field
+
+func (key *Key[k, v]) Get(f k) (value v, err error) {
+	return value, key.get("GET", field(f), &value)
+}
+func (key *Key[k, v]) Set(f k, value v) (err error) {
+	return key.exec(http.MethodPut, "SET", field(f), value)
+}
+
+// hash
+
+func (key *Key[k, v]) HGet(f k) (value v, err error) {
+	return value, key.get("HGET", field(f), &value)
+}
+func (key *Key[k, v]) HSet(f k, value v) (err error) {
+	return key.exec(http.MethodPut, "HSET", field(f), value)
+}
+func (key *Key[k, v]) HGetAll() (mapOut map[k]v, err error) {
+	var values map[string]v
+	if err = key.get("HGETALL", nil, &values); err != nil {
+		return nil, err
+	}
+	mapOut = make(map[k]v, len(values))
+	for s, value := range values {
+		f, err := parseField[k](s)
+		if err != nil {
+			return nil, err
+		}
+		mapOut[f] = value
+	}
+	return mapOut, nil
+}
+func (key *Key[k, v]) HMGET(fields ...k) (values []v, err error) {
+	strs := make([]string, len(fields))
+	for i, f := range fields {
+		strs[i] = fmt.Sprint(f)
+	}
+	return values, key.get("HMGET", url.Values{"F": {strings.Join(strs, ",")}}, &values)
+}
+func (key *Key[k, v]) HKeys() (fields []k, err error) {
+	var strs []string
+	if err = key.get("HKEYS", nil, &strs); err != nil {
+		return nil, err
+	}
+	return parseFields[k](strs)
+}
+func (key *Key[k, v]) HVals() (values []v, err error) {
+	return values, key.get("HVALS", nil, &values)
+}
+func (key *Key[k, v]) HExists(f k) (ok bool, err error) {
+	return ok, key.get("HEXISTS", field(f), &ok)
+}
+func (key *Key[k, v]) HLen() (length int64, err error) {
+	return length, key.get("HLEN", nil, &length)
+}
+func (key *Key[k, v]) HRandField(count int) (fields []k, err error) {
+	var strs []string
+	if err = key.get("HRANDFIELD", url.Values{"Count": {strconv.Itoa(count)}}, &strs); err != nil {
+		return nil, err
+	}
+	return parseFields[k](strs)
+}
+func (key *Key[k, v]) HDel(f k) (err error) {
+	return key.exec(http.MethodDelete, "HDEL", field(f), nil)
+}
+func (key *Key[k, v]) HSetNX(f k, value v) (ok bool, err error) {
+	return ok, key.send(http.MethodPut, "HSETNX", field(f), value, &ok)
+}
+func (key *Key[k, v]) HIncrBy(f k, increment int64) (value int64, err error) {
+	query := field(f)
+	query.Set("Increment", strconv.FormatInt(increment, 10))
+	return value, key.send(http.MethodPut, "HINCRBY", query, nil, &value)
+}
+func (key *Key[k, v]) HIncrByFloat(f k, increment float64) (value float64, err error) {
+	query := field(f)
+	query.Set("Increment", strconv.FormatFloat(increment, 'f', -1, 64))
+	return value, key.send(http.MethodPut, "HINCRBYFLOAT", query, nil, &value)
+}
+func (key *Key[k, v]) Del() (err error) {
+	return key.exec(http.MethodDelete, "DEL", nil, nil)
+}
+
+// Expire responds false if the key does not exist
+func (key *Key[k, v]) Expire(expiration time.Duration) (ok bool, err error) {
+	return ok, key.send(http.MethodPut, "EXPIRE", url.Values{"Seconds": {strconv.FormatInt(int64(expiration/time.Second), 10)}}, nil, &ok)
+}
+
+// TTL is -1 if the key has no expiry, -2 if it does not exist, as redis responds
+func (key *Key[k, v]) TTL() (ttl time.Duration, err error) {
+	var seconds int64
+	if err = key.get("TTL", nil, &seconds); err != nil || seconds < 0 {
+		return time.Duration(seconds), err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// list and set
+
+func (key *Key[k, v]) RPush(value v) (err error) {
+	return key.exec(http.MethodPut, "RPUSH", nil, value)
+}
+func (key *Key[k, v]) LRange(start, stop int64) (values []v, err error) {
+	return values, key.get("LRANGE", url.Values{"Start": {strconv.FormatInt(start, 10)}, "Stop": {strconv.FormatInt(stop, 10)}}, &values)
+}
+func (key *Key[k, v]) LLen() (length int64, err error) {
+	return length, key.get("LLEN", nil, &length)
+}
+func (key *Key[k, v]) LPush(value v) (err error) {
+	return key.exec(http.MethodPut, "LPUSH", nil, value)
+}
+func (key *Key[k, v]) LPop() (value v, err error) {
+	return value, key.send(http.MethodDelete, "LPOP", nil, nil, &value)
+}
+func (key *Key[k, v]) RPop() (value v, err error) {
+	return value, key.send(http.MethodDelete, "RPOP", nil, nil, &value)
+}
+func (key *Key[k, v]) LIndex(index int64) (value v, err error) {
+	return value, key.get("LINDEX", url.Values{"Index": {strconv.FormatInt(index, 10)}}, &value)
+}
+func (key *Key[k, v]) LSet(index int64, value v) (err error) {
+	return key.exec(http.MethodPut, "LSET", url.Values{"Index": {strconv.FormatInt(index, 10)}}, value)
+}
+
+// LRem removes count occurrences of member, and responds the number removed
+func (key *Key[k, v]) LRem(count int64, member string) (removed int64, err error) {
+	return removed, key.send(http.MethodDelete, "LREM", url.Values{"Count": {strconv.FormatInt(count, 10)}, "Member": {member}}, nil, &removed)
+}
+func (key *Key[k, v]) LTrim(start, stop int64) (err error) {
+	return key.exec(http.MethodDelete, "LTRIM", url.Values{"Start": {strconv.FormatInt(start, 10)}, "Stop": {strconv.FormatInt(stop, 10)}}, nil)
+}
+func (key *Key[k, v]) SIsMember(member string) (isMember bool, err error) {
+	return isMember, key.get("SISMEMBER", url.Values{"Member": {member}}, &isMember)
+}
+func (key *Key[k, v]) SMembers() (members []v, err error) {
+	return members, key.get("SMEMBERS", nil, &members)
+}
+func (key *Key[k, v]) SAdd(member v) (err error) {
+	return key.exec(http.MethodPut, "SADD", nil, member)
+}
+func (key *Key[k, v]) SRem(member string) (err error) {
+	return key.exec(http.MethodDelete, "SREM", url.Values{"Member": {member}}, nil)
+}
+
+// sorted set
+
+func (key *Key[k, v]) ZAdd(score float64, member v) (err error) {
+	return key.exec(http.MethodPost, "ZADD", url.Values{"Score": {strconv.FormatFloat(score, 'f', -1, 64)}}, member)
+}
+func (key *Key[k, v]) ZRange(start, stop int64) (members []v, err error) {
+	return members, key.get("ZRANGE", rangeQuery(start, stop, false), &members)
+}
+func (key *Key[k, v]) ZRangeWithScores(start, stop int64) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodGet, "ZRANGE", rangeQuery(start, stop, true))
+}
+func (key *Key[k, v]) ZRevRange(start, stop int64) (members []v, err error) {
+	return members, key.get("ZREVRANGE", rangeQuery(start, stop, false), &members)
+}
+func (key *Key[k, v]) ZRevRangeWithScores(start, stop int64) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodGet, "ZREVRANGE", rangeQuery(start, stop, true))
+}
+
+// ZRangeByScore min and max are scores, or redis score ranges such as "(1" "-inf" "+inf"
+func (key *Key[k, v]) ZRangeByScore(min, max string) (members []v, err error) {
+	return members, key.get("ZRANGEBYSCORE", scoreQuery(min, max, false), &members)
+}
+func (key *Key[k, v]) ZRangeByScoreWithScores(min, max string) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodGet, "ZRANGEBYSCORE", scoreQuery(min, max, true))
+}
+func (key *Key[k, v]) ZRevRangeByScore(min, max string) (members []v, err error) {
+	return members, key.get("ZREVRANGEBYSCORE", scoreQuery(min, max, false), &members)
+}
+func (key *Key[k, v]) ZRevRangeByScoreWithScores(min, max string) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodGet, "ZREVRANGEBYSCORE", scoreQuery(min, max, true))
+}
+func (key *Key[k, v]) ZCard() (length int64, err error) {
+	return length, key.get("ZCARD", nil, &length)
+}
+func (key *Key[k, v]) ZCount(min, max string) (count int64, err error) {
+	return count, key.get("ZCOUNT", scoreQuery(min, max, false), &count)
+}
+func (key *Key[k, v]) ZRank(member string) (rank int64, err error) {
+	return rank, key.get("ZRANK", url.Values{"Member": {member}}, &rank)
+}
+func (key *Key[k, v]) ZRevRank(member string) (rank int64, err error) {
+	return rank, key.get("ZREVRANK", url.Values{"Member": {member}}, &rank)
+}
+func (key *Key[k, v]) ZIncrBy(increment float64, member string) (score float64, err error) {
+	query := url.Values{"Increment": {strconv.FormatFloat(increment, 'f', -1, 64)}, "Member": {member}}
+	return score, key.send(http.MethodPut, "ZINCRBY", query, nil, &score)
+}
+func (key *Key[k, v]) ZScore(member string) (score float64, err error) {
+	return score, key.get("ZSCORE", url.Values{"Member": {member}}, &score)
+}
+func (key *Key[k, v]) ZRem(members ...string) (err error) {
+	return key.exec(http.MethodDelete, "ZREM", url.Values{"Member": {strings.Join(members, ",")}}, nil)
+}
+func (key *Key[k, v]) ZRemRangeByScore(min, max string) (err error) {
+	return key.exec(http.MethodDelete, "ZREMRANGEBYSCORE", scoreQuery(min, max, false), nil)
+}
+func (key *Key[k, v]) ZRemRangeByRank(start, stop int64) (err error) {
+	return key.exec(http.MethodDelete, "ZREMRANGEBYRANK", url.Values{"Start": {strconv.FormatInt(start, 10)}, "Stop": {strconv.FormatInt(stop, 10)}}, nil)
+}
+func (key *Key[k, v]) ZPopMax(count int64) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodDelete, "ZPOPMAX", url.Values{"Count": {strconv.FormatInt(count, 10)}})
+}
+func (key *Key[k, v]) ZPopMin(count int64) (members []v, scores []float64, err error) {
+	return key.withScores(http.MethodDelete, "ZPOPMIN", url.Values{"Count": {strconv.FormatInt(count, 10)}})
+}
+
+// ZLexCount min and max are redis lex ranges such as "[a" "-" "+", of members as they are stored, msgpack encoded
+func (key *Key[k, v]) ZLexCount(min, max string) (count int64, err error) {
+	return count, key.get("ZLEXCOUNT", url.Values{"Min": {min}, "Max": {max}}, &count)
+}
+
+// ZRangeByScorePage is a page of ZRangeByScore, count members from offset
+func (key *Key[k, v]) ZRangeByScorePage(min, max string, offset, count int64) (members []v, err error) {
+	query := scoreQuery(min, max, false)
+	query.Set("Offset", strconv.FormatInt(offset, 10))
+	query.Set("Count", strconv.FormatInt(count, 10))
+	return members, key.get("ZRANGEBYSCORE", query, &members)
+}
+
+// paginated scans. start with cursor "", and request the next page with the returned cursor, until it is ""
+
+func (key *Key[k, v]) HScan(cursor, match string, count int) (fields map[k]v, next string, err error) {
+	var page struct {
+		Cursor string       `msgpack:"cursor"`
+		Fields map[string]v `msgpack:"fields"`
+	}
+	if err = key.get("HSCAN", scanQuery(cursor, match, count), &page); err != nil {
+		return nil, "", err
+	}
+	fields = make(map[k]v, len(page.Fields))
+	for s, value := range page.Fields {
+		f, err := parseField[k](s)
+		if err != nil {
+			return nil, "", err
+		}
+		fields[f] = value
+	}
+	return fields, page.Cursor, nil
+}
+func (key *Key[k, v]) SScan(cursor, match string, count int) (members []v, next string, err error) {
+	var page struct {
+		Cursor  string `msgpack:"cursor"`
+		Members []v    `msgpack:"members"`
+	}
+	err = key.get("SSCAN", scanQuery(cursor, match, count), &page)
+	return page.Members, page.Cursor, err
+}
+func (key *Key[k, v]) ZScan(cursor, match string, count int) (members []v, scores []float64, next string, err error) {
+	var page struct {
+		Cursor  string    `msgpack:"cursor"`
+		Members []v       `msgpack:"members"`
+		Scores  []float64 `msgpack:"scores"`
+	}
+	err = key.get("ZSCAN", scanQuery(cursor, match, count), &page)
+	return page.Members, page.Scores, page.Cursor, err
+}
+
+func (key *Key[k, v]) withScores(method, cmd string, query url.Values) (members []v, scores []float64, err error) {
+	var result struct {
+		Members []v       `msgpack:"members"`
+		Scores  []float64 `msgpack:"scores"`
+	}
+	if err = key.send(method, cmd, query, nil, &result); err != nil {
+		return nil, nil, err
+	}
+	return result.Members, result.Scores, nil
+}
+
+func rangeQuery(start, stop int64, withScores bool) url.Values {
+	return url.Values{"Start": {strconv.FormatInt(start, 10)}, "Stop": {strconv.FormatInt(stop, 10)}, "WITHSCORES": {strconv.FormatBool(withScores)}}
+}
+func scanQuery(cursor, match string, count int) url.Values {
+	query := url.Values{"Count": {strconv.Itoa(count)}}
+	if len(cursor) > 0 {
+		query.Set("Cursor", cursor)
+	}
+	if len(match) > 0 {
+		query.Set("Match", match)
+	}
+	return query
+}
+func scoreQuery(min, max string, withScores bool) url.Values {
+	return url.Values{"Min": {min}, "Max": {max}, "WITHSCORES": {strconv.FormatBool(withScores)}}
+}
@@ -0,0 +1,44 @@
+package client
+
+// CallOption is the call level setting of a request
+type CallOption struct {
+	// DataSource is the redis data source of the key or api, the default one if empty
+	DataSource string
+	// IdempotencyKey makes retried api calls run once. posts are retried only with it
+	IdempotencyKey string
+	// Priority is the lane of the api call, 0 is the most urgent one
+	Priority *int
+	// TraceParent is the W3C traceparent of the call
+	TraceParent string
+}
+
+var Call *CallOption
+
+func (o *CallOption) WithDataSource(dataSource string) (out *CallOption) {
+	if out = o; o == Call {
+		out = &CallOption{}
+	}
+	out.DataSource = dataSource
+	return out
+}
+func (o *CallOption) WithIdempotencyKey(key string) (out *CallOption) {
+	if out = o; o == Call {
+		out = &CallOption{}
+	}
+	out.IdempotencyKey = key
+	return out
+}
+func (o *CallOption) WithPriority(priority int) (out *CallOption) {
+	if out = o; o == Call {
+		out = &CallOption{}
+	}
+	out.Priority = &priority
+	return out
+}
+func (o *CallOption) WithTraceParent(traceParent string) (out *CallOption) {
+	if out = o; o == Call {
+		out = &CallOption{}
+	}
+	out.TraceParent = traceParent
+	return out
+}
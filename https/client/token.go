@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenSource returns the JWT of requests. refresh is true when the server responded 401 with the last one
+type TokenSource interface {
+	Token(ctx context.Context, refresh bool) (jwt string, err error)
+}
+
+// TokenFunc adapts a function to TokenSource, i.g. one fetching JWT from a login api
+type TokenFunc func(ctx context.Context, refresh bool) (jwt string, err error)
+
+func (f TokenFunc) Token(ctx context.Context, refresh bool) (string, error) { return f(ctx, refresh) }
+
+// StaticToken is a JWT that never refreshes
+func StaticToken(jwt string) TokenSource {
+	return TokenFunc(func(ctx context.Context, refresh bool) (string, error) { return jwt, nil })
+}
+
+// hs256Token signs JWT with the secret shared with the server, see config Jwt.Secret
+type hs256Token struct {
+	secret []byte
+	claims map[string]interface{}
+	ttl    time.Duration
+
+	mut       sync.Mutex
+	jwt       string
+	expiresAt time.Time
+}
+
+// HS256Token signs JWT of the claims, valid for ttl, with the secret of the server. for services trusted with the secret.
+// it is signed again before it expires
+func HS256Token(secret string, claims map[string]interface{}, ttl time.Duration) TokenSource {
+	return &hs256Token{secret: []byte(secret), claims: claims, ttl: ttl}
+}
+
+func (t *hs256Token) Token(ctx context.Context, refresh bool) (jwtString string, err error) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	//renew when 90% of ttl passed
+	if !refresh && len(t.jwt) > 0 && time.Until(t.expiresAt) > t.ttl/10 {
+		return t.jwt, nil
+	}
+	var (
+		now    = time.Now()
+		claims = jwt.MapClaims{}
+	)
+	for k, v := range t.claims {
+		claims[k] = v
+	}
+	claims["iat"], claims["exp"] = now.Unix(), now.Add(t.ttl).Unix()
+	if jwtString, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret); err != nil {
+		return "", err
+	}
+	t.jwt, t.expiresAt = jwtString, now.Add(t.ttl)
+	return jwtString, nil
+}
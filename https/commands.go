@@ -0,0 +1,632 @@
+package https
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Command is a data command of the gateway, requested alone as /{Name}-!{Key}, or in BATCH and TXN
+type Command struct {
+	Name string
+	// Method is the http method the command is requested with
+	Method string
+	// Operation is checked by the permission of the key, strings.ToLower(Name) if empty
+	Operation string
+	// Queue parses the parameters of the request, and adds the command to the pipeline
+	Queue func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error)
+	// Result decodes the reply of the command
+	Result func(cmd redis.Cmder) (interface{}, error)
+	// Values converts the stored values the result holds, to the Queries projection or to the registered type of the key.
+	// nil if the result holds no stored values
+	Values func(result interface{}, convert func(value interface{}) interface{}) interface{}
+}
+
+// commands are the registered data commands, by name
+var commands = map[string]*Command{}
+
+// RegisterCommand adds the command to the gateway, replacing the one of the same name
+func RegisterCommand(command *Command) {
+	if command.Name = strings.ToUpper(command.Name); len(command.Operation) == 0 {
+		command.Operation = strings.ToLower(command.Name)
+	}
+	commands[command.Name] = command
+}
+
+// Commands returns the registered data commands
+func Commands() (cmds []*Command) {
+	for _, command := range commands {
+		cmds = append(cmds, command)
+	}
+	return cmds
+}
+
+// knownCommand is true for the registered commands, and API, BATCH and TXN.
+// metrics count other commands as "UNKNOWN", so that bad requests can not blow up the number of series
+func knownCommand(cmd string) bool {
+	_, ok := commands[cmd]
+	return ok || cmd == "API" || cmd == "BATCH" || cmd == "TXN" || cmd == "BLOB" || cmd == "SETBLOB" || cmd == "DELBLOB"
+}
+
+// runCommand runs the data command of the request, if it is requested with its method
+func (svcCtx *HttpContext) runCommand(method string) (ret interface{}, err error) {
+	var (
+		command *Command
+		ok      bool
+		rds     *redis.Client
+		cmd     redis.Cmder
+		convert func(value interface{}) interface{}
+	)
+	if command, ok = commands[svcCtx.Cmd]; !ok || command.Method != method {
+		return nil, ErrBadCommand
+	}
+	if rds, err = svcCtx.permittedRds(); err != nil {
+		return nil, err
+	}
+	if command.Values != nil {
+		if convert, err = svcCtx.converter(); err != nil {
+			return nil, err
+		}
+	}
+	pipe := rds.Pipeline()
+	if cmd, err = command.Queue(svcCtx, pipe); err != nil {
+		return nil, err
+	}
+	if method != http.MethodGet {
+		svcCtx.touchModified(pipe)
+	}
+	//the error is kept in cmd
+	pipe.Exec(svcCtx.Ctx)
+	return command.result(cmd, convert)
+}
+
+// result decodes the reply of the command, and converts the stored values it holds with convert, if not nil
+func (command *Command) result(cmd redis.Cmder, convert func(value interface{}) interface{}) (ret interface{}, err error) {
+	if ret, err = command.Result(cmd); err != nil || convert == nil {
+		return ret, err
+	}
+	return command.Values(ret, convert), nil
+}
+
+// permittedRds resolves the key and field of the request, checks the permission of the command, and returns the redis client
+func (svcCtx *HttpContext) permittedRds() (rds *redis.Client, err error) {
+	var operation string
+	if rds, err = svcCtx.rds(); err != nil {
+		return nil, err
+	}
+	if operation, err = svcCtx.KeyFieldAtJwt(); err != nil {
+		return nil, err
+	}
+	if !svcCtx.permitted(operation) {
+		return nil, ErrOperationNotPermited
+	}
+	return rds, nil
+}
+
+// converter of the stored values of the response: projected if Queries is set, else typed if the key is registered
+func (svcCtx *HttpContext) converter() (convert func(value interface{}) interface{}, err error) {
+	var projection Projection
+	if projection, err = svcCtx.projection(); err != nil {
+		return nil, err
+	}
+	if projection != nil {
+		return projection.Apply, nil
+	}
+	if valueType := svcCtx.valueType(); valueType != nil {
+		return func(value interface{}) interface{} { return typedValue(valueType, value) }, nil
+	}
+	return nil, nil
+}
+
+// decoders of replies. values are msgpack encoded, as data.Ctx stores them
+
+func decodeValue(b []byte) (value interface{}, err error) {
+	return value, msgpack.Unmarshal(b, &value)
+}
+func decodeValues(strs []string) (values []interface{}) {
+	values = make([]interface{}, 0, len(strs))
+	for _, s := range strs {
+		//values that fail to decode are skipped, as data.Ctx does
+		if value, err := decodeValue([]byte(s)); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Command.Values of results: a value, a slice of values, or a map of hash fields to values
+func oneValue(result interface{}, convert func(value interface{}) interface{}) interface{} {
+	return convert(result)
+}
+func eachValue(result interface{}, convert func(value interface{}) interface{}) interface{} {
+	values, ok := result.([]interface{})
+	if !ok {
+		return result
+	}
+	out := make([]interface{}, len(values))
+	for i, value := range values {
+		out[i] = convert(value)
+	}
+	return out
+}
+func eachField(result interface{}, convert func(value interface{}) interface{}) interface{} {
+	values, ok := result.(map[string]interface{})
+	if !ok {
+		return result
+	}
+	out := make(map[string]interface{}, len(values))
+	for field, value := range values {
+		out[field] = convert(value)
+	}
+	return out
+}
+
+func valueResult(cmd redis.Cmder) (interface{}, error) {
+	b, err := cmd.(*redis.StringCmd).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(b)
+}
+func valuesResult(cmd redis.Cmder) (interface{}, error) {
+	strs, err := cmd.(*redis.StringSliceCmd).Result()
+	return decodeValues(strs), err
+}
+func stringsResult(cmd redis.Cmder) (interface{}, error) {
+	return cmd.(*redis.StringSliceCmd).Result()
+}
+func intResult(cmd redis.Cmder) (interface{}, error) {
+	return cmd.(*redis.IntCmd).Result()
+}
+func boolResult(cmd redis.Cmder) (interface{}, error) {
+	return cmd.(*redis.BoolCmd).Result()
+}
+func floatResult(cmd redis.Cmder) (interface{}, error) {
+	return cmd.(*redis.FloatCmd).Result()
+}
+func okResult(cmd redis.Cmder) (interface{}, error) {
+	if err := cmd.Err(); err != nil {
+		return "false", err
+	}
+	return "true", nil
+}
+
+// withScoresResult is {"members":[...],"scores":[...]}, as ZRANGE with WITHSCORES=true responds
+func withScoresResult(cmd redis.Cmder) (interface{}, error) {
+	var (
+		zs, err = cmd.(*redis.ZSliceCmd).Result()
+		members = make([]interface{}, 0, len(zs))
+		scores  = make([]float64, 0, len(zs))
+	)
+	for _, z := range zs {
+		if s, ok := z.Member.(string); ok {
+			if value, e := decodeValue([]byte(s)); e == nil {
+				members, scores = append(members, value), append(scores, z.Score)
+			}
+		}
+	}
+	return map[string]interface{}{"members": members, "scores": scores}, err
+}
+
+// zrange queues ZRANGE or ZREVRANGE, with scores if WITHSCORES is true
+func zrange(rev bool) func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+	return func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+		start, err := svc.int64Param("Start")
+		if err != nil {
+			return nil, err
+		}
+		stop, err := svc.int64Param("Stop")
+		if err != nil {
+			return nil, err
+		}
+		switch withScores := svc.Param("WITHSCORES") == "true"; {
+		case rev && withScores:
+			return pipe.ZRevRangeWithScores(svc.Ctx, svc.DataKey(), start, stop), nil
+		case rev:
+			return pipe.ZRevRange(svc.Ctx, svc.DataKey(), start, stop), nil
+		case withScores:
+			return pipe.ZRangeWithScores(svc.Ctx, svc.DataKey(), start, stop), nil
+		default:
+			return pipe.ZRange(svc.Ctx, svc.DataKey(), start, stop), nil
+		}
+	}
+}
+
+// zrangeByScore queues ZRANGEBYSCORE or ZREVRANGEBYSCORE, with scores if WITHSCORES is true
+func zrangeByScore(rev bool) func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+	return func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+		rangeBy, err := svc.rangeBy()
+		if err != nil {
+			return nil, err
+		}
+		switch withScores := svc.Param("WITHSCORES") == "true"; {
+		case rev && withScores:
+			return pipe.ZRevRangeByScoreWithScores(svc.Ctx, svc.DataKey(), rangeBy), nil
+		case rev:
+			return pipe.ZRevRangeByScore(svc.Ctx, svc.DataKey(), rangeBy), nil
+		case withScores:
+			return pipe.ZRangeByScoreWithScores(svc.Ctx, svc.DataKey(), rangeBy), nil
+		default:
+			return pipe.ZRangeByScore(svc.Ctx, svc.DataKey(), rangeBy), nil
+		}
+	}
+}
+
+// zpop queues ZPOPMAX or ZPOPMIN of Count members
+func zpop(max bool) func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+	return func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+		count := int64(1)
+		if len(svc.Param("Count")) > 0 {
+			var err error
+			if count, err = svc.int64Param("Count"); err != nil {
+				return nil, err
+			}
+		}
+		if max {
+			return pipe.ZPopMax(svc.Ctx, svc.DataKey(), count), nil
+		}
+		return pipe.ZPopMin(svc.Ctx, svc.DataKey(), count), nil
+	}
+}
+
+// rangeResult decodes the reply of zrange and zrangeByScore
+func rangeResult(cmd redis.Cmder) (interface{}, error) {
+	if _, ok := cmd.(*redis.ZSliceCmd); ok {
+		return withScoresResult(cmd)
+	}
+	return valuesResult(cmd)
+}
+
+func withValue(svc *HttpContext) ([]byte, error) {
+	if svc.Key == "" {
+		return nil, ErrEmptyKeyOrField
+	}
+	return svc.value()
+}
+func withField(svc *HttpContext) error {
+	if svc.Key == "" || svc.Field == "" {
+		return ErrEmptyKeyOrField
+	}
+	return nil
+}
+
+func init() {
+	for _, command := range []*Command{
+		// strings, stored at key:field
+		{Name: "GET", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.Get(svc.Ctx, svc.DataKey()+":"+svc.Field), nil
+		}, Result: valueResult, Values: oneValue},
+		{Name: "SET", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if err := withField(svc); err != nil {
+				return nil, err
+			}
+			bytes, err := svc.value()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.Set(svc.Ctx, svc.DataKey()+":"+svc.Field, bytes, 0), nil
+		}, Result: okResult},
+
+		// hashes
+		{Name: "HGET", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HGet(svc.Ctx, svc.DataKey(), svc.Field), nil
+		}, Result: valueResult, Values: oneValue},
+		{Name: "HGETALL", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HGetAll(svc.Ctx, svc.DataKey()), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			strs, err := cmd.(*redis.MapStringStringCmd).Result()
+			values := make(map[string]interface{}, len(strs))
+			for field, s := range strs {
+				if value, e := decodeValue([]byte(s)); e == nil {
+					values[field] = value
+				}
+			}
+			return values, err
+		}, Values: eachField},
+		{Name: "HMGET", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HMGet(svc.Ctx, svc.DataKey(), strings.Split(svc.Field, ",")...), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			replies, err := cmd.(*redis.SliceCmd).Result()
+			values := make([]interface{}, len(replies))
+			for i, reply := range replies {
+				if s, ok := reply.(string); ok {
+					values[i], _ = decodeValue([]byte(s))
+				}
+			}
+			return values, err
+		}, Values: eachValue},
+		{Name: "HKEYS", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HKeys(svc.Ctx, svc.DataKey()), nil
+		}, Result: stringsResult},
+		{Name: "HEXISTS", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HExists(svc.Ctx, svc.DataKey(), svc.Field), nil
+		}, Result: boolResult},
+		{Name: "HRANDFIELD", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			count, err := strconv.Atoi(svc.Param("Count"))
+			if err != nil {
+				return nil, errors.New("parse count error:" + err.Error())
+			}
+			return pipe.HRandField(svc.Ctx, svc.DataKey(), count), nil
+		}, Result: stringsResult},
+		{Name: "HLEN", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HLen(svc.Ctx, svc.DataKey()), nil
+		}, Result: intResult},
+		{Name: "HVALS", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.HVals(svc.Ctx, svc.DataKey()), nil
+		}, Result: valuesResult, Values: eachValue},
+		{Name: "HSET", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if err := withField(svc); err != nil {
+				return nil, err
+			}
+			bytes, err := svc.value()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.HSet(svc.Ctx, svc.DataKey(), svc.Field, bytes), nil
+		}, Result: okResult},
+		// HSETNX responds true if the field is set, false if it exists already
+		{Name: "HSETNX", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if err := withField(svc); err != nil {
+				return nil, err
+			}
+			bytes, err := svc.value()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.HSetNX(svc.Ctx, svc.DataKey(), svc.Field, bytes), nil
+		}, Result: boolResult},
+		// HINCRBY responds the value after the increment. the field is a redis integer, as data.Ctx.HIncrBy keeps it
+		{Name: "HINCRBY", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if err := withField(svc); err != nil {
+				return nil, err
+			}
+			increment, err := svc.int64Param("Increment")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.HIncrBy(svc.Ctx, svc.DataKey(), svc.Field, increment), nil
+		}, Result: intResult},
+		// HINCRBYFLOAT responds the value after the increment. the field is a redis float, as data.Ctx.HIncrByFloat keeps it
+		{Name: "HINCRBYFLOAT", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if err := withField(svc); err != nil {
+				return nil, err
+			}
+			increment, err := strconv.ParseFloat(svc.Param("Increment"), 64)
+			if err != nil {
+				return nil, errors.New("parameter Increment shoule be float")
+			}
+			return pipe.HIncrByFloat(svc.Ctx, svc.DataKey(), svc.Field, increment), nil
+		}, Result: floatResult},
+		{Name: "HDEL", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			if svc.Field == "" {
+				return nil, ErrEmptyKeyOrField
+			}
+			return pipe.HDel(svc.Ctx, svc.DataKey(), svc.Field), nil
+		}, Result: okResult},
+
+		// lists
+		{Name: "RPUSH", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			bytes, err := withValue(svc)
+			if err != nil {
+				return nil, err
+			}
+			return pipe.RPush(svc.Ctx, svc.DataKey(), bytes), nil
+		}, Result: okResult},
+		{Name: "LPUSH", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			bytes, err := withValue(svc)
+			if err != nil {
+				return nil, err
+			}
+			return pipe.LPush(svc.Ctx, svc.DataKey(), bytes), nil
+		}, Result: okResult},
+		// LPOP and RPOP remove the element, and respond it
+		{Name: "LPOP", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.LPop(svc.Ctx, svc.DataKey()), nil
+		}, Result: valueResult, Values: oneValue},
+		{Name: "RPOP", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.RPop(svc.Ctx, svc.DataKey()), nil
+		}, Result: valueResult, Values: oneValue},
+		{Name: "LINDEX", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			index, err := svc.int64Param("Index")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.LIndex(svc.Ctx, svc.DataKey(), index), nil
+		}, Result: valueResult, Values: oneValue},
+		{Name: "LSET", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			index, err := svc.int64Param("Index")
+			if err != nil {
+				return nil, err
+			}
+			bytes, err := withValue(svc)
+			if err != nil {
+				return nil, err
+			}
+			return pipe.LSet(svc.Ctx, svc.DataKey(), index, bytes), nil
+		}, Result: okResult},
+		// LREM removes Count occurrences of Member: from the head if Count > 0, from the tail if < 0, all if 0
+		{Name: "LREM", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			count, err := svc.int64Param("Count")
+			if err != nil {
+				return nil, err
+			}
+			member, err := svc.member()
+			return pipe.LRem(svc.Ctx, svc.DataKey(), count, member), err
+		}, Result: intResult},
+		// LTRIM removes the elements out of Start to Stop
+		{Name: "LTRIM", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			start, err := svc.int64Param("Start")
+			if err != nil {
+				return nil, err
+			}
+			stop, err := svc.int64Param("Stop")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.LTrim(svc.Ctx, svc.DataKey(), start, stop), nil
+		}, Result: okResult},
+		{Name: "LRANGE", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			start, err := svc.int64Param("Start")
+			if err != nil {
+				return nil, err
+			}
+			stop, err := svc.int64Param("Stop")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.LRange(svc.Ctx, svc.DataKey(), start, stop), nil
+		}, Result: valuesResult, Values: eachValue},
+		{Name: "LLEN", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.LLen(svc.Ctx, svc.DataKey()), nil
+		}, Result: intResult},
+
+		// sets. members are msgpack encoded, as data.Ctx stores them
+		{Name: "SISMEMBER", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			member, err := svc.member()
+			return pipe.SIsMember(svc.Ctx, svc.DataKey(), member), err
+		}, Result: boolResult},
+		{Name: "SMEMBERS", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.SMembers(svc.Ctx, svc.DataKey()), nil
+		}, Result: valuesResult, Values: eachValue},
+		{Name: "SADD", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			bytes, err := withValue(svc)
+			if err != nil {
+				return nil, err
+			}
+			return pipe.SAdd(svc.Ctx, svc.DataKey(), bytes), nil
+		}, Result: okResult},
+		{Name: "SREM", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			member, err := svc.member()
+			return pipe.SRem(svc.Ctx, svc.DataKey(), member), err
+		}, Result: okResult},
+
+		// sorted sets
+		{Name: "ZADD", Method: http.MethodPost, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			score, err := strconv.ParseFloat(svc.Param("Score"), 64)
+			if err != nil {
+				return nil, errors.New("parameter Score shoule be float")
+			}
+			bytes, err := svc.value()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.ZAdd(svc.Ctx, svc.DataKey(), redis.Z{Score: score, Member: bytes}), nil
+		}, Result: okResult},
+		{Name: "ZRANGE", Method: http.MethodGet, Queue: zrange(false), Result: rangeResult},
+		{Name: "ZREVRANGE", Method: http.MethodGet, Queue: zrange(true), Result: rangeResult},
+		{Name: "ZRANGEBYSCORE", Method: http.MethodGet, Queue: zrangeByScore(false), Result: rangeResult},
+		{Name: "ZREVRANGEBYSCORE", Method: http.MethodGet, Queue: zrangeByScore(true), Result: rangeResult},
+		{Name: "ZCARD", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.ZCard(svc.Ctx, svc.DataKey()), nil
+		}, Result: intResult},
+		{Name: "ZRANK", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			member, err := svc.member()
+			return pipe.ZRank(svc.Ctx, svc.DataKey(), member), err
+		}, Result: intResult},
+		{Name: "ZREVRANK", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			member, err := svc.member()
+			return pipe.ZRevRank(svc.Ctx, svc.DataKey(), member), err
+		}, Result: intResult},
+		{Name: "ZCOUNT", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.ZCount(svc.Ctx, svc.DataKey(), svc.Param("Min"), svc.Param("Max")), nil
+		}, Result: intResult},
+		// ZSCORE of a missing member is 0, as data.Ctx.ZScore
+		{Name: "ZSCORE", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			member, err := svc.member()
+			return pipe.ZScore(svc.Ctx, svc.DataKey(), member), err
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			if score, err := cmd.(*redis.FloatCmd).Result(); err != redis.Nil {
+				return score, err
+			}
+			return float64(0), nil
+		}},
+		// ZINCRBY responds the score after the increment
+		{Name: "ZINCRBY", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			increment, err := strconv.ParseFloat(svc.Param("Increment"), 64)
+			if err != nil {
+				return nil, errors.New("parameter Increment shoule be float")
+			}
+			member, err := svc.member()
+			return pipe.ZIncrBy(svc.Ctx, svc.DataKey(), increment, member), err
+		}, Result: floatResult},
+		// ZREM removes the comma separated members
+		{Name: "ZREM", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			members := strings.Split(svc.Param("Member"), ",")
+			for i, member := range members {
+				bytes, err := msgpack.Marshal(member)
+				if err != nil {
+					return nil, err
+				}
+				members[i] = string(bytes)
+			}
+			return pipe.ZRem(svc.Ctx, svc.DataKey(), toInterfaces(members)...), nil
+		}, Result: okResult},
+		{Name: "ZREMRANGEBYSCORE", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.ZRemRangeByScore(svc.Ctx, svc.DataKey(), svc.Param("Min"), svc.Param("Max")), nil
+		}, Result: okResult},
+		{Name: "ZREMRANGEBYRANK", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			start, err := svc.int64Param("Start")
+			if err != nil {
+				return nil, err
+			}
+			stop, err := svc.int64Param("Stop")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.ZRemRangeByRank(svc.Ctx, svc.DataKey(), start, stop), nil
+		}, Result: okResult},
+		// ZPOPMAX and ZPOPMIN remove Count members, 1 if not set, and respond them with their scores
+		{Name: "ZPOPMAX", Method: http.MethodDelete, Queue: zpop(true), Result: withScoresResult},
+		{Name: "ZPOPMIN", Method: http.MethodDelete, Queue: zpop(false), Result: withScoresResult},
+		// ZLEXCOUNT counts members between Min and Max, i.g. "[a" and "+". members are compared as they are stored, msgpack encoded
+		{Name: "ZLEXCOUNT", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.ZLexCount(svc.Ctx, svc.DataKey(), svc.Param("Min"), svc.Param("Max")), nil
+		}, Result: intResult},
+
+		// keys
+		{Name: "DEL", Method: http.MethodDelete, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.Del(svc.Ctx, svc.DataKey()), nil
+		}, Result: okResult},
+		// EXPIRE responds true if the key exists
+		{Name: "EXPIRE", Method: http.MethodPut, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			seconds, err := svc.int64Param("Seconds")
+			if err != nil {
+				return nil, err
+			}
+			return pipe.Expire(svc.Ctx, svc.DataKey(), time.Duration(seconds)*time.Second), nil
+		}, Result: boolResult},
+		// TTL responds the seconds to live, -1 if the key has no expiry, -2 if it does not exist
+		{Name: "TTL", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.TTL(svc.Ctx, svc.DataKey()), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			ttl, err := cmd.(*redis.DurationCmd).Result()
+			if ttl < 0 {
+				//-1 and -2 are kept by go-redis as nanoseconds
+				return int64(ttl), err
+			}
+			return int64(ttl / time.Second), err
+		}},
+		// TIME responds the unix milliseconds of the redis server
+		{Name: "TIME", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			return pipe.Time(svc.Ctx), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			tm, err := cmd.(*redis.TimeCmd).Result()
+			return tm.UnixMilli(), err
+		}},
+	} {
+		RegisterCommand(command)
+	}
+}
+
+func toInterfaces(strs []string) (values []interface{}) {
+	values = make([]interface{}, len(strs))
+	for i, s := range strs {
+		values[i] = s
+	}
+	return values
+}
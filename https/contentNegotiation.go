@@ -0,0 +1,115 @@
+package https
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/data"
+)
+
+const (
+	ContentTypeJson    = "application/json"
+	ContentTypeMsgpack = "application/msgpack"
+	ContentTypeCbor    = "application/cbor"
+)
+
+// encodings the Accept header may select, by media type
+var acceptable = map[string]string{
+	"application/json":        ContentTypeJson,
+	"application/msgpack":     ContentTypeMsgpack,
+	"application/x-msgpack":   ContentTypeMsgpack,
+	"application/vnd.msgpack": ContentTypeMsgpack,
+	"application/cbor":        ContentTypeCbor,
+}
+
+// negotiate selects the response encoding of the Accept header, by quality then by order. json if none is acceptable.
+// i.g. "application/msgpack, application/json;q=0.9" selects msgpack
+func negotiate(accept string) (contentType string) {
+	var best float64
+	contentType = ContentTypeJson
+	for _, mediaRange := range strings.Split(accept, ",") {
+		var (
+			params    = strings.Split(mediaRange, ";")
+			mediaType = strings.ToLower(strings.TrimSpace(params[0]))
+			q         = 1.0
+		)
+		for _, param := range params[1:] {
+			if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if encoding, ok := acceptable[mediaType]; ok && q > best {
+			contentType, best = encoding, q
+		}
+	}
+	return contentType
+}
+
+// maps are encoded in sorted order, as json does, so that the same value is the same response, with the same ETag
+var cborEncMode, _ = cbor.CoreDetEncOptions().EncMode()
+
+// structured content types encode every result, including []byte and string
+func structured(contentType string) bool {
+	return contentType == ContentTypeMsgpack || contentType == ContentTypeCbor
+}
+
+// marshal encodes the result as the response content type. other than msgpack and cbor,
+// []byte and string are responded as they are, and everything else as json
+func (svc *HttpContext) marshal(result interface{}) (b []byte, err error) {
+	switch svc.ResponseContentType {
+	case ContentTypeMsgpack:
+		var buf bytes.Buffer
+		encoder := msgpack.NewEncoder(&buf)
+		encoder.SetSortMapKeys(true)
+		err = encoder.Encode(result)
+		return buf.Bytes(), err
+	case ContentTypeCbor:
+		return cborEncMode.Marshal(result)
+	}
+	if b, ok := result.([]byte); ok {
+		return b, nil
+	} else if s, ok := result.(string); ok {
+		return []byte(s), nil
+	}
+	if b, err = json.Marshal(result); err != nil {
+		return nil, err
+	}
+	//json Compact b
+	var dst *bytes.Buffer = bytes.NewBuffer([]byte{})
+	if err = json.Compact(dst, b); err != nil {
+		return nil, err
+	}
+	return dst.Bytes(), nil
+}
+
+// valueType is the value type of the data key, if it is created by data.New with a concrete type
+func (svc *HttpContext) valueType() reflect.Type {
+	if keyInfo, ok := data.KeyInfoOf(svc.Key); ok {
+		return keyInfo.ValueType
+	}
+	return nil
+}
+
+// typedValue decodes the value again into the type, so that json responses follow the tags of the type.
+// the value is kept as it is if it does not fit the type
+func typedValue(valueType reflect.Type, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return value
+	}
+	typed := reflect.New(valueType)
+	if err = msgpack.Unmarshal(b, typed.Interface()); err != nil {
+		return value
+	}
+	return typed.Elem().Interface()
+}
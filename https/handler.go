@@ -0,0 +1,218 @@
+package https
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/permission"
+	"github.com/yangkequn/saavuu/specification"
+	"github.com/yangkequn/saavuu/tracing"
+)
+
+// HandlerOption is parameter to create the http gateway by NewHandler
+type HandlerOption struct {
+	// Prefix is the path the gateway is mounted at, i.g. "/rSvc/". requests out of it are not found. empty means any path
+	Prefix string
+	// DataSource is the redis data source of requests without -!DS=
+	DataSource string
+	// DataSources are redis clients by name, looked up before config.Rds
+	DataSources map[string]*redis.Client
+	// Permitted checks the operation on the data key, permission.IsPermitted if nil
+	Permitted func(dataKey string, operation string) bool
+	// Middlewares wrap the gateway, the first one is the outermost.
+	// they run inside RequestID, AccessLog if Http.AccessLog, and Recover
+	Middlewares []func(http.Handler) http.Handler
+}
+
+var Option *HandlerOption
+
+// WithPrefix mounts the gateway at prefix, i.g. "/rSvc/"
+func (o *HandlerOption) WithPrefix(prefix string) (out *HandlerOption) {
+	if out = o; o == Option {
+		out = &HandlerOption{}
+	}
+	out.Prefix = prefix
+	return out
+}
+
+// WithDataSource adds the redis client as data source name. empty name makes it the default one of the gateway
+func (o *HandlerOption) WithDataSource(name string, rds *redis.Client) (out *HandlerOption) {
+	if out = o; o == Option {
+		out = &HandlerOption{}
+	}
+	if out.DataSources == nil {
+		out.DataSources = map[string]*redis.Client{}
+	}
+	out.DataSources[name] = rds
+	return out
+}
+
+// WithDefaultDataSource sets the data source of requests without -!DS=
+func (o *HandlerOption) WithDefaultDataSource(name string) (out *HandlerOption) {
+	if out = o; o == Option {
+		out = &HandlerOption{}
+	}
+	out.DataSource = name
+	return out
+}
+func (o *HandlerOption) WithPermission(permitted func(dataKey string, operation string) bool) (out *HandlerOption) {
+	if out = o; o == Option {
+		out = &HandlerOption{}
+	}
+	out.Permitted = permitted
+	return out
+}
+func (o *HandlerOption) WithMiddleware(middlewares ...func(http.Handler) http.Handler) (out *HandlerOption) {
+	if out = o; o == Option {
+		out = &HandlerOption{}
+	}
+	out.Middlewares = append(out.Middlewares, middlewares...)
+	return out
+}
+
+// gateway serves the redis commands and apis of requests
+type gateway struct {
+	option HandlerOption
+}
+
+// NewHandler returns the http gateway, to mount on any router or server, i.g.
+//
+//	mux.Handle("/rSvc/", https.NewHandler(https.Option.WithPrefix("/rSvc/")))
+func NewHandler(options ...*HandlerOption) http.Handler {
+	var g = &gateway{}
+	if len(options) > 0 && options[0] != nil {
+		g.option = *options[0]
+	}
+	if g.option.Permitted == nil {
+		g.option.Permitted = permission.IsPermitted
+	}
+	var (
+		handler     http.Handler = g
+		middlewares              = append(defaultMiddlewares(), g.option.Middlewares...)
+	)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		result     interface{}
+		b          []byte
+		err        error
+		httpStatus int = http.StatusOK
+		svcCtx     *HttpContext
+		start      time.Time = time.Now()
+	)
+	if len(g.option.Prefix) > 0 && !strings.HasPrefix(r.URL.Path, g.option.Prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	if CorsChecked(r, w) {
+		return
+	}
+	if len(config.Cfg.Http.CORES) > 0 {
+		w.Header().Set("Access-Control-Allow-Origin", config.Cfg.Http.CORES)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*12000)
+	defer cancel()
+	span := tracing.Start(r.Header.Get("traceparent"), "HTTP "+r.Method)
+	if svcCtx, err = NewHttpContext(ctx, r, w); err != nil || svcCtx == nil {
+		httpStatus = http.StatusBadRequest
+	} else if svcCtx.gateway, svcCtx.TraceParent = g, span.TraceParent(); r.Method == "GET" {
+		result, err = svcCtx.GetHandler()
+	} else if r.Method == "POST" {
+		result, err = svcCtx.PostHandler()
+	} else if r.Method == "PUT" {
+		result, err = svcCtx.PutHandler()
+	} else if r.Method == "DELETE" {
+		result, err = svcCtx.DelHandler()
+	}
+
+	if status, ok := result.(responded); ok && status > 0 {
+		//the response is written already, streamed or by ranges. an error can only have ended it early
+		httpStatus = int(status)
+	} else {
+		w.Header().Add("Vary", "Accept")
+		if err == nil && svcCtx != nil {
+			b, err = svcCtx.marshal(result)
+		}
+		if err == nil && svcCtx != nil && svcCtx.cacheable() && svcCtx.cacheHeaders(w, b) {
+			httpStatus, b = http.StatusNotModified, nil
+		}
+		//this err may be from json.marshal, so don't move it to the above else if
+		if err != nil {
+			if b = []byte(err.Error()); bytes.Contains(b, []byte("JWT")) {
+				httpStatus = http.StatusUnauthorized
+			} else if errors.Is(err, specification.ErrNamespacedName) {
+				httpStatus = http.StatusBadRequest
+			} else if errors.Is(err, api.ErrIdempotencyKeyReused) {
+				httpStatus = http.StatusUnprocessableEntity
+			} else if errors.Is(err, ErrConflict) {
+				httpStatus = http.StatusConflict
+			} else if errors.Is(err, api.ErrOverloaded) {
+				//let the client back off, rather than time out
+				httpStatus = http.StatusServiceUnavailable
+				w.Header().Set("Retry-After", "1")
+			} else if httpStatus == http.StatusOK {
+				// this if is needed, because  httpStatus may have already setted as StatusBadRequest
+				httpStatus = http.StatusInternalServerError
+			}
+		}
+
+		//errors are text, whatever the encoding of results is
+		if err != nil && svcCtx != nil && structured(svcCtx.ResponseContentType) {
+			svcCtx.ResponseContentType = "text/plain; charset=utf-8"
+		}
+		//set Content-Type
+		if svcCtx != nil && len(svcCtx.ResponseContentType) > 0 {
+			svcCtx.Rsb.Header().Set("Content-Type", svcCtx.ResponseContentType)
+		}
+		w.WriteHeader(httpStatus)
+		w.Write(b)
+	}
+
+	cmd := "UNKNOWN"
+	if svcCtx != nil && knownCommand(svcCtx.Cmd) {
+		cmd = svcCtx.Cmd
+	}
+	httpRequests.Inc(cmd, strconv.Itoa(httpStatus))
+	httpDuration.Observe(time.Since(start).Seconds(), cmd)
+	if span.Name = "HTTP " + r.Method + " " + cmd; svcCtx != nil {
+		span.SetAttr("saavuu.key", svcCtx.Key)
+		svcCtx.logAccess(r)
+	}
+	span.SetAttr("http.method", r.Method).SetAttr("http.status_code", strconv.Itoa(httpStatus)).Finish(err)
+}
+
+// rds is the redis client of the data source of the request
+func (svc *HttpContext) rds() (rds *redis.Client, err error) {
+	var dataSource = svc.RedisDataSource
+	if svc.gateway == nil {
+		return config.GetRdsClientByName(dataSource)
+	}
+	if len(dataSource) == 0 {
+		dataSource = svc.gateway.option.DataSource
+	}
+	if rds, ok := svc.gateway.option.DataSources[dataSource]; ok {
+		return rds, nil
+	}
+	return config.GetRdsClientByName(dataSource)
+}
+
+// permitted checks the operation on the data key of the request
+func (svc *HttpContext) permitted(operation string) bool {
+	if svc.gateway == nil {
+		return permission.IsPermitted(svc.DataKey(), operation)
+	}
+	return svc.gateway.option.Permitted(svc.DataKey(), operation)
+}
@@ -0,0 +1,35 @@
+package https
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yangkequn/saavuu/config"
+)
+
+// healthHandler serves probes under prefix:
+//
+//	live   the process is serving http
+//	ready  every redis data source answers PING
+func healthHandler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/") {
+		case "live":
+			w.Write([]byte("ok"))
+		case "ready":
+			ctx, cancel := context.WithTimeout(r.Context(), time.Second*2)
+			defer cancel()
+			for name, rds := range config.Rds {
+				if err := rds.Ping(ctx).Err(); err != nil {
+					http.Error(w, "redis "+name+" not ready: "+err.Error(), http.StatusServiceUnavailable)
+					return
+				}
+			}
+			w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
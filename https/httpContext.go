@@ -7,10 +7,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
 )
 
 type HttpContext struct {
@@ -25,9 +27,21 @@ type HttpContext struct {
 	Field string
 
 	ResponseContentType string
+	// TraceParent is the W3C traceparent of the request, replaced by the one of the http span once it starts
+	TraceParent string
+	// Namespace of the request, prefixing the api names and data keys it reaches
+	Namespace string
+	// gateway serving the request, nil if the context is created outside of a gateway
+	gateway *gateway
+	// params of an operation of BATCH, instead of the url query
+	params map[string]interface{}
 }
 
-var ErrIncompleteRequest = errors.New("incomplete request")
+var (
+	ErrIncompleteRequest = errors.New("incomplete request")
+	// ErrIdempotencyKeyWithoutSubject is responded as 401, because anonymous callers would share their keys
+	ErrIdempotencyKeyWithoutSubject = errors.New("Idempotency-Key requires a JWT with subject")
+)
 
 func NewHttpContext(ctx context.Context, r *http.Request, w http.ResponseWriter) (httpCtx *HttpContext, err error) {
 	var (
@@ -46,12 +60,17 @@ func NewHttpContext(ctx context.Context, r *http.Request, w http.ResponseWriter)
 	svcContext.Cmd, svcContext.Key = CmdKeyFields[0], CmdKeyFields[1]
 	//url decoded already
 	svcContext.Field = r.FormValue("F")
+	//i.g. traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	svcContext.TraceParent = r.Header.Get("traceparent")
+	if svcContext.Namespace, err = svcContext.namespace(); err != nil {
+		return nil, err
+	}
 
-	//default response content type: application/json
-	svcContext.ResponseContentType = "application/json"
+	//response content type: as the Accept header selects, application/json by default. suffixes below override it
+	svcContext.ResponseContentType = negotiate(r.Header.Get("Accept"))
 	for i, l := 2, len(CmdKeyFields); i < l; i++ {
 		//export enum RspType { json = "&RspType=application/json", jpeg = "&RspType=image/jpeg", ogg = "&RspType=audio/ogg", mpeg = "&RspType=video/mpeg", mp4 = "&RspType=video/mp4", none = "", text = "&RspType=text/plain", stream = "&RspType=application/octet-stream" }
-		//export enum RspType { json = "-!JSON", jpeg = "-!JPG", ogg = "-!OGG", mpeg = "-!MPEG", mp4 = "-!MP4", none = "", text = "-!TEXT", stream = "-!STREAM" }
+		//export enum RspType { json = "-!JSON", msgpack = "-!MSGPACK", cbor = "-!CBOR", jpeg = "-!JPG", ogg = "-!OGG", mpeg = "-!MPEG", mp4 = "-!MP4", none = "", text = "-!TEXT", stream = "-!STREAM" }
 
 		if param = CmdKeyFields[i]; param == "" {
 			continue
@@ -61,6 +80,10 @@ func NewHttpContext(ctx context.Context, r *http.Request, w http.ResponseWriter)
 		switch param {
 		case "JSON":
 			svcContext.ResponseContentType = "application/json"
+		case "MSGPACK":
+			svcContext.ResponseContentType = ContentTypeMsgpack
+		case "CBOR":
+			svcContext.ResponseContentType = ContentTypeCbor
 		case "JPG":
 			svcContext.ResponseContentType = "image/jpeg"
 		case "OGG":
@@ -131,3 +154,36 @@ func (svc *HttpContext) MsgpackBody() (bytes []byte, err error) {
 	//return remarshaled bytes, because golang msgpack is better fullfill than javascript msgpack
 	return bytes, nil
 }
+
+// idempotencyKey is the Idempotency-Key header, scoped to the subject of the JWT, so that callers never get the results of each other by the same key.
+// the header is rejected without a valid JWT with subject, i.g. Idempotency-Key: 8e03978e-40d5-43e8-bc93-6894a57f9324
+func (svc *HttpContext) idempotencyKey() (key string, err error) {
+	var subject string
+	if key = svc.Req.Header.Get("Idempotency-Key"); len(key) == 0 {
+		return "", nil
+	}
+	if svc.ParseJwtToken() == nil {
+		if mpclaims, ok := svc.jwtToken.Claims.(jwt.MapClaims); ok {
+			subject, _ = mpclaims.GetSubject()
+		}
+	}
+	if len(subject) == 0 {
+		return "", ErrIdempotencyKeyWithoutSubject
+	}
+	//the subject is escaped, so that it never contains ':', and no subject and key pair is another one
+	return url.QueryEscape(subject) + ":" + key, nil
+}
+
+// ApiOption carries the call level settings in request headers to the API call
+func (svc *HttpContext) ApiOption() (option *api.ApiOption, err error) {
+	var idemKey string
+	if idemKey, err = svc.idempotencyKey(); err != nil {
+		return nil, err
+	}
+	option = api.Option.WithIdempotencyKey(idemKey)
+	//i.g. X-Priority: 2 , lane 0 is the most urgent one
+	if priority, err := strconv.Atoi(svc.Req.Header.Get("X-Priority")); err == nil {
+		option.WithPriority(priority)
+	}
+	return option.WithTraceParent(svc.TraceParent).WithNamespace(svc.Namespace), nil
+}
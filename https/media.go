@@ -0,0 +1,217 @@
+package https
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/data"
+)
+
+var ErrNotBytes = errors.New("value is not bytes or string")
+
+// responded is the result of a handler that writes the response itself, with the status. 0 if nothing is written
+type responded int
+
+// media responses are raw bytes, as -!MP4 -!OGG -!JPG etc. select. they accept byte ranges
+func (svc *HttpContext) media() bool {
+	return svc.ResponseContentType != ContentTypeJson && !structured(svc.ResponseContentType)
+}
+
+// byteRange parses the single range of the Range header, i.g. "bytes=0-1023", "bytes=1024-" or "bytes=-1024".
+// ok is false if the header is absent, or is not a single byte range, so that the whole value is responded
+func byteRange(header string, size int64) (start, end int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, nil
+	}
+	if first == "" {
+		//suffix range, the last bytes
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end <= 0 {
+			return 0, 0, false, nil
+		}
+		if end > size {
+			end = size
+		}
+		return size - end, size - 1, size > 0, errUnsatisfiable(size == 0)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil || start < 0 {
+		return 0, 0, false, nil
+	}
+	if end = size - 1; last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false, nil
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true, errUnsatisfiable(start >= size)
+}
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+func errUnsatisfiable(unsatisfiable bool) error {
+	if unsatisfiable {
+		return errRangeNotSatisfiable
+	}
+	return nil
+}
+
+// writeRange writes the whole value of size, or its range requested by the Range header, by copy
+func (svc *HttpContext) writeRange(size int64, contentType string, copy func(start, end int64, w io.Writer) error) (status responded, err error) {
+	var (
+		w          = svc.Rsb
+		start, end = int64(0), size - 1
+		partial    bool
+	)
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Type", contentType)
+	if rangeHeader := svc.Req.Header.Get("Range"); len(rangeHeader) > 0 {
+		if start, end, partial, err = byteRange(rangeHeader, size); err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return http.StatusRequestedRangeNotSatisfiable, nil
+		} else if !partial {
+			start, end = 0, size-1
+		}
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	if status = http.StatusOK; partial {
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+	w.WriteHeader(int(status))
+	if size == 0 {
+		return status, nil
+	}
+	//the status is sent already, so an error can only end the response early
+	return status, copy(start, end, w)
+}
+
+// msgpackBytes is the offset and length of the bytes of a msgpack bin or str value, from its header
+func msgpackBytes(head []byte) (offset, length int64, err error) {
+	if len(head) == 0 {
+		return 0, 0, ErrNotBytes
+	}
+	switch code := head[0]; {
+	case code >= 0xa0 && code <= 0xbf:
+		return 1, int64(code & 0x1f), nil
+	case (code == 0xc4 || code == 0xd9) && len(head) >= 2:
+		return 2, int64(head[1]), nil
+	case (code == 0xc5 || code == 0xda) && len(head) >= 3:
+		return 3, int64(binary.BigEndian.Uint16(head[1:3])), nil
+	case (code == 0xc6 || code == 0xdb) && len(head) >= 5:
+		return 5, int64(binary.BigEndian.Uint32(head[1:5])), nil
+	}
+	return 0, 0, ErrNotBytes
+}
+
+// serveRange responds the range of the bytes stored at key:field by GETRANGE, without reading the whole value.
+// the offset of the msgpack header is skipped, as data.Ctx stores []byte and string msgpack encoded
+func (svc *HttpContext) serveRange() (status responded, err error) {
+	var (
+		rds            *redis.Client
+		head           []byte
+		offset, length int64
+	)
+	if rds, err = svc.permittedRds(); err != nil {
+		return 0, err
+	}
+	key := svc.redisKey()
+	if head, err = rds.GetRange(svc.Ctx, key, 0, 4).Bytes(); err == nil && len(head) == 0 {
+		err = redis.Nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if offset, length, err = msgpackBytes(head); err != nil {
+		return 0, err
+	}
+	return svc.writeRange(length, svc.ResponseContentType, func(start, end int64, w io.Writer) error {
+		bytes, err := rds.GetRange(svc.Ctx, key, offset+start, offset+end).Bytes()
+		if err == nil {
+			_, err = w.Write(bytes)
+		}
+		return err
+	})
+}
+
+// serveBlob streams the blob stored in chunks at key:field, or the range of it.
+// the content type is the one of the response suffix, or the one the blob is stored with
+func (svc *HttpContext) serveBlob() (status responded, err error) {
+	var (
+		rds      *redis.Client
+		manifest *data.BlobManifest
+	)
+	if rds, err = svc.permittedRds(); err != nil {
+		return 0, err
+	}
+	key := svc.redisKey()
+	if manifest, err = data.ReadBlobManifest(svc.Ctx, rds, key); err != nil {
+		return 0, err
+	}
+	contentType := svc.ResponseContentType
+	if !svc.media() {
+		if contentType = manifest.ContentType; len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+	}
+	return svc.writeRange(manifest.Size, contentType, func(start, end int64, w io.Writer) error {
+		return data.CopyBlobRange(svc.Ctx, rds, key, manifest, start, end, w)
+	})
+}
+
+// putBlob stores the raw body in chunks at key:field, with the content type of the request
+func (svc *HttpContext) putBlob() (manifest *data.BlobManifest, err error) {
+	var (
+		rds  *redis.Client
+		body io.Reader = svc.Req.Body
+	)
+	if svc.Key == "" || svc.Field == "" {
+		return nil, ErrEmptyKeyOrField
+	}
+	if rds, err = svc.permittedRds(); err != nil {
+		return nil, err
+	}
+	if max := config.Cfg.Http.MaxBlobSize; max > 0 {
+		body = http.MaxBytesReader(svc.Rsb, svc.Req.Body, max)
+	}
+	key := svc.redisKey()
+	if manifest, err = data.WriteBlob(svc.Ctx, rds, key, body, config.Cfg.Http.BlobChunkSize, svc.Req.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+	if keyInfo, ok := data.KeyInfoOf(svc.Key); ok && keyInfo.TrackModified {
+		err = data.TouchModified(svc.Ctx, rds, svc.Namespace, key).Err()
+	}
+	return manifest, err
+}
+
+// delBlob removes the blob stored at key:field with its chunks. DEL of key:field would leave the chunks behind
+func (svc *HttpContext) delBlob() (ok bool, err error) {
+	var rds *redis.Client
+	if svc.Key == "" || svc.Field == "" {
+		return false, ErrEmptyKeyOrField
+	}
+	if rds, err = svc.permittedRds(); err != nil {
+		return false, err
+	}
+	key := svc.redisKey()
+	if err = data.DeleteBlob(svc.Ctx, rds, key); err != nil {
+		return false, err
+	}
+	if keyInfo, ok := data.KeyInfoOf(svc.Key); ok && keyInfo.TrackModified {
+		err = data.ForgetModified(svc.Ctx, rds, svc.Namespace, key).Err()
+	}
+	return err == nil, err
+}
@@ -15,11 +15,15 @@ func (svc *HttpContext) KeyFieldAtJwt() (operation string, err error) {
 		subTag   string
 		f64      float64
 	)
-	operation = strings.ToLower(svc.Cmd)
+	if command, ok := commands[svc.Cmd]; ok {
+		operation = command.Operation
+	} else {
+		operation = strings.ToLower(svc.Cmd)
+	}
 	KeyContainsAt := strings.Contains(svc.Key, "@")
 	FieldContainsAt := strings.Contains(svc.Field, "@")
 	if !KeyContainsAt && !FieldContainsAt {
-		return operation, nil
+		return operation, svc.namespacedName()
 	}
 	operation = "@" + operation
 
@@ -64,5 +68,6 @@ func (svc *HttpContext) KeyFieldAtJwt() (operation string, err error) {
 		KeyParts[len(KeyParts)-1] = fmt.Sprintf("%v", obj)
 		svc.Key = strings.Join(KeyParts, "")
 	}
-	return operation, nil
+	//the key is checked as it is finally, with the jwt values in it
+	return operation, svc.namespacedName()
 }
@@ -0,0 +1,153 @@
+package https
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/config"
+)
+
+type contextKey int
+
+const (
+	requestIdKey contextKey = iota
+	accessLogKey
+)
+
+// RequestID takes the X-Request-ID of the request, or generates one, and sets it to the request and the response.
+// apis using header fields receive it as HeaderRequestId
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestId(id) {
+			var b [16]byte
+			rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+			r.Header.Set("X-Request-ID", id)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIdKey, id)))
+	})
+}
+
+// validRequestId allows up to 128 printable ascii characters, so that ids from clients can not forge log lines
+func validRequestId(id string) bool {
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return len(id) > 0 && len(id) <= 128
+}
+
+// RequestIDFrom returns the request ID set by RequestID, empty if there is none
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+// accessLog is filled by the gateway, once the request is parsed
+type accessLog struct {
+	cmd, key, subject string
+}
+
+// statusRecorder keeps the status and size of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+func (rec *statusRecorder) Write(b []byte) (n int, err error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err = rec.ResponseWriter.Write(b)
+	rec.size += n
+	return n, err
+}
+
+// Flush lets streaming responses pass through the recorder
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// AccessLog logs every request at info level, with method, cmd, key, status, latency, JWT subject and request ID
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start  = time.Now()
+			rec    = &statusRecorder{ResponseWriter: w}
+			access = &accessLog{}
+		)
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogKey, access)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		log.Info().Str("method", r.Method).Str("cmd", access.cmd).Str("key", access.key).Int("status", rec.status).
+			Int("size", rec.size).Dur("latency", time.Since(start)).Str("sub", access.subject).
+			Str("requestId", RequestIDFrom(r.Context())).Msg("http request")
+	})
+}
+
+// Recover responds 500 to a request that panics, instead of dropping the connection, and logs the stack
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := w.(*statusRecorder)
+		if !ok {
+			rec = &statusRecorder{ResponseWriter: w}
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Error().Str("panic", fmt.Sprint(p)).Str("stack", string(debug.Stack())).
+					Str("requestId", RequestIDFrom(r.Context())).Msg("http request panic")
+				if rec.status == 0 {
+					http.Error(rec, "internal server error", http.StatusInternalServerError)
+				}
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// defaultMiddlewares run before the middlewares of the HandlerOption, outermost first
+func defaultMiddlewares() (middlewares []func(http.Handler) http.Handler) {
+	middlewares = append(middlewares, RequestID)
+	if config.Cfg.Http.AccessLog {
+		middlewares = append(middlewares, AccessLog)
+	}
+	return append(middlewares, Recover)
+}
+
+// logAccess fills the access log of the request, if AccessLog is in the chain
+func (svc *HttpContext) logAccess(r *http.Request) {
+	access, ok := r.Context().Value(accessLogKey).(*accessLog)
+	if !ok {
+		return
+	}
+	access.cmd, access.key = svc.Cmd, svc.Key
+	if len(r.Header.Get("Authorization")) == 0 || svc.ParseJwtToken() != nil {
+		return
+	}
+	if subject, err := svc.jwtToken.Claims.(jwt.MapClaims).GetSubject(); err == nil {
+		access.subject = subject
+	}
+}
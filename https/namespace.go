@@ -0,0 +1,70 @@
+package https
+
+import (
+	"net"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+// namespace of the request: the jwt claim Http.NamespaceClaim, the first label of the host if Http.NamespaceByHost, or Cfg.Namespace.
+// a request with an invalid JWT is rejected, rather than falling back to the namespace of the host or the default one
+func (svc *HttpContext) namespace() (namespace string, err error) {
+	if claim := config.Cfg.Http.NamespaceClaim; len(claim) > 0 && len(svc.Req.Header.Get("Authorization")) > 0 {
+		if err = svc.ParseJwtToken(); err != nil {
+			return "", err
+		}
+		if mpclaims, ok := svc.jwtToken.Claims.(jwt.MapClaims); ok {
+			namespace, _ = mpclaims[claim].(string)
+		}
+	}
+	if len(namespace) == 0 && config.Cfg.Http.NamespaceByHost {
+		host := svc.Req.Host
+		if h, _, e := net.SplitHostPort(host); e == nil {
+			host = h
+		}
+		//i.g. acme.api.example.com, but not localhost or an ip
+		if labels := strings.Split(host, "."); len(labels) > 2 && net.ParseIP(host) == nil {
+			namespace = labels[0]
+		}
+	}
+	if len(namespace) == 0 {
+		namespace = config.Cfg.Namespace
+	}
+	if len(namespace) > 0 && !specification.ValidNamespace(namespace) {
+		return "", specification.ErrInvalidNamespace
+	}
+	return namespace, nil
+}
+
+// multiTenant is true if requests are namespaced by JWT or host, so that requests without a namespace are apart from the namespaced ones
+func multiTenant() bool {
+	return len(config.Cfg.Http.NamespaceClaim) > 0 || config.Cfg.Http.NamespaceByHost
+}
+
+// namespacedName rejects api names and keys of the request that carry a namespace, i.g. "acme:api:demo",
+// or "acme:user" requested without a namespace where namespaces come from JWT or host.
+// otherwise they would be kept as they are, and reach the apis and keys of another namespace
+func (svc *HttpContext) namespacedName() error {
+	if svc.Cmd == "API" {
+		if len(specification.ApiNamespace(svc.Key)) > 0 {
+			return specification.ErrNamespacedName
+		}
+		return nil
+	}
+	if len(svc.Namespace) == 0 && multiTenant() && strings.Contains(svc.Key, ":") {
+		return specification.ErrNamespacedName
+	}
+	return nil
+}
+
+// DataKey is the redis key of the request, in the namespace of the request.
+// the key is always prefixed, even if it starts with the namespace, so that "acme:user" of acme is not "acme:user" of no namespace
+func (svc *HttpContext) DataKey() string {
+	if len(svc.Namespace) == 0 {
+		return svc.Key
+	}
+	return svc.Namespace + ":" + svc.Key
+}
@@ -0,0 +1,110 @@
+package https
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var ErrBadQueries = errors.New("bad Queries")
+
+// Projection is the tree of fields to respond, parsed from the Queries parameter. a nil subtree keeps the whole value.
+// i.g. Queries=name,address.city,tags.*.id keeps name, the city of address, and the id of every element of tags.
+// paths may start with "$." as in JSONPath, and "*" matches every field of a map
+type Projection map[string]Projection
+
+// ParseProjection parses the comma separated dotted paths. empty or "*" is no projection, and responds everything
+func ParseProjection(queries string) (projection Projection, err error) {
+	if queries = strings.TrimSpace(queries); queries == "" || queries == "*" || queries == "$" {
+		return nil, nil
+	}
+	projection = Projection{}
+	for _, path := range strings.Split(queries, ",") {
+		if path = strings.TrimPrefix(strings.TrimSpace(path), "$."); path == "" {
+			return nil, fmt.Errorf("%w: empty path", ErrBadQueries)
+		}
+		node := projection
+		for i, names := 0, strings.Split(path, "."); i < len(names); i++ {
+			if names[i] == "" {
+				return nil, fmt.Errorf("%w: %q", ErrBadQueries, path)
+			}
+			sub, exists := node[names[i]]
+			if exists && sub == nil {
+				//the whole field is kept already
+				break
+			}
+			if i == len(names)-1 {
+				node[names[i]] = nil
+				break
+			}
+			if !exists {
+				sub = Projection{}
+				node[names[i]] = sub
+			}
+			node = sub
+		}
+	}
+	return projection, nil
+}
+
+// Apply keeps the projected fields of maps. slices are projected element by element, other values are kept as they are
+func (projection Projection) Apply(value interface{}) interface{} {
+	if projection == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(projection))
+		for name, sub := range projection {
+			if name == "*" {
+				for field, fieldValue := range v {
+					out[field] = sub.Apply(fieldValue)
+				}
+			} else if fieldValue, ok := v[name]; ok {
+				out[name] = sub.Apply(fieldValue)
+			}
+		}
+		return out
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for field, fieldValue := range v {
+			m[fmt.Sprint(field)] = fieldValue
+		}
+		return projection.Apply(m)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, element := range v {
+			out[i] = projection.Apply(element)
+		}
+		return out
+	}
+	return value
+}
+
+// projection is the Queries parameter of the request
+func (svc *HttpContext) projection() (Projection, error) {
+	return ParseProjection(svc.Param("Queries"))
+}
+
+// projectApiResult projects the result of an api. structs are msgpack encoded and decoded to maps first,
+// so fields are named as msgpack names them
+func projectApiResult(projection Projection, result interface{}) (interface{}, error) {
+	if projection == nil || result == nil {
+		return result, nil
+	}
+	switch result.(type) {
+	case string, []byte:
+		return result, nil
+	}
+	b, err := msgpack.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = msgpack.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return projection.Apply(value), nil
+}
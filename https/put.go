@@ -2,74 +2,15 @@ package https
 
 import (
 	"errors"
-
-	"github.com/redis/go-redis/v9"
-	"github.com/yangkequn/saavuu/config"
-	"github.com/yangkequn/saavuu/permission"
+	"net/http"
 )
 
 var ErrEmptyKeyOrField = errors.New("empty key or field")
 var ErrOperationNotPermited = errors.New("operation permission denied")
 
 func (svcCtx *HttpContext) PutHandler() (data interface{}, err error) {
-	//use remote service map to handle request
-	var (
-		bytes     []byte
-		operation string
-		rds       *redis.Client
-	)
-	if rds, err = config.GetRdsClientByName(svcCtx.RedisDataSource); err != nil {
-		return nil, err
-	}
-
-	if operation, err = svcCtx.KeyFieldAtJwt(); err != nil {
-		return "", err
-	}
-	if !permission.IsPermitted(svcCtx.Key, operation) {
-		return "false", ErrOperationNotPermited
-	}
-
-	switch svcCtx.Cmd {
-	case "SET":
-		if svcCtx.Key == "" || svcCtx.Field == "" {
-			return "false", ErrEmptyKeyOrField
-		}
-		if bytes, err = svcCtx.MsgpackBody(); err != nil {
-			return "false", err
-		}
-		cmd := rds.Set(svcCtx.Ctx, svcCtx.Key+":"+svcCtx.Field, bytes, 0)
-		if err = cmd.Err(); err != nil {
-			return "false", err
-		}
-		return "true", nil
-
-	case "HSET":
-		//error if empty Key or Field
-		if svcCtx.Key == "" || svcCtx.Field == "" {
-			return "false", ErrEmptyKeyOrField
-		}
-		if bytes, err = svcCtx.MsgpackBody(); err != nil {
-			return "false", err
-		}
-		cmd := rds.HSet(svcCtx.Ctx, svcCtx.Key, svcCtx.Field, bytes)
-		if err = cmd.Err(); err != nil {
-			return "false", err
-		}
-		return "true", nil
-	case "RPUSH":
-		//error if empty Key or Field
-		if svcCtx.Key == "" {
-			return "false", ErrEmptyKeyOrField
-		}
-		if bytes, err = svcCtx.MsgpackBody(); err != nil {
-			return "false", err
-		}
-		cmd := rds.RPush(svcCtx.Ctx, svcCtx.Key, bytes)
-		if err = cmd.Err(); err != nil {
-			return "false", err
-		}
-		return "true", nil
-	default:
-		return nil, ErrBadCommand
+	if svcCtx.Cmd == "SETBLOB" {
+		return svcCtx.putBlob()
 	}
+	return svcCtx.runCommand(http.MethodPut)
 }
@@ -0,0 +1,115 @@
+package https
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+)
+
+var ErrBadCursor = errors.New("bad cursor")
+
+// encodeCursor makes the redis cursor opaque to clients. the last page has the empty cursor
+func encodeCursor(cursor uint64) string {
+	if cursor == 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(cursor, 10)))
+}
+func decodeCursor(cursor string) (uint64, error) {
+	if len(cursor) == 0 {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrBadCursor
+	}
+	if c, err := strconv.ParseUint(string(b), 10, 64); err == nil {
+		return c, nil
+	}
+	return 0, ErrBadCursor
+}
+
+// pageSize is the Count parameter, limited to Http.MaxPageSize. default is 10, as redis SCAN
+func (svc *HttpContext) pageSize() (count int64, err error) {
+	if count = 10; len(svc.Param("Count")) > 0 {
+		if count, err = svc.int64Param("Count"); err != nil || count <= 0 {
+			return 0, errors.New("parameter Count should be positive integer")
+		}
+	}
+	if max := config.Cfg.Http.MaxPageSize; max > 0 && count > max {
+		count = max
+	}
+	return count, nil
+}
+
+// scanParams are the Cursor, Match and Count parameters of a scan command
+func (svc *HttpContext) scanParams() (cursor uint64, match string, count int64, err error) {
+	if cursor, err = decodeCursor(svc.Param("Cursor")); err != nil {
+		return 0, "", 0, err
+	}
+	if count, err = svc.pageSize(); err != nil {
+		return 0, "", 0, err
+	}
+	return cursor, svc.Param("Match"), count, nil
+}
+
+func init() {
+	for _, command := range []*Command{
+		// HSCAN responds {"cursor":"...","fields":{...}}, a page of the hash. request the next page with Cursor, until it is empty
+		{Name: "HSCAN", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			cursor, match, count, err := svc.scanParams()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.HScan(svc.Ctx, svc.DataKey(), cursor, match, count), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			page, cursor, err := cmd.(*redis.ScanCmd).Result()
+			fields := make(map[string]interface{}, len(page)/2)
+			for i := 0; i+1 < len(page); i += 2 {
+				if value, e := decodeValue([]byte(page[i+1])); e == nil {
+					fields[page[i]] = value
+				}
+			}
+			return map[string]interface{}{"cursor": encodeCursor(cursor), "fields": fields}, err
+		}},
+		// SSCAN responds {"cursor":"...","members":[...]}
+		{Name: "SSCAN", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			cursor, match, count, err := svc.scanParams()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.SScan(svc.Ctx, svc.DataKey(), cursor, match, count), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			page, cursor, err := cmd.(*redis.ScanCmd).Result()
+			return map[string]interface{}{"cursor": encodeCursor(cursor), "members": decodeValues(page)}, err
+		}},
+		// ZSCAN responds {"cursor":"...","members":[...],"scores":[...]}
+		{Name: "ZSCAN", Method: http.MethodGet, Queue: func(svc *HttpContext, pipe redis.Pipeliner) (redis.Cmder, error) {
+			cursor, match, count, err := svc.scanParams()
+			if err != nil {
+				return nil, err
+			}
+			return pipe.ZScan(svc.Ctx, svc.DataKey(), cursor, match, count), nil
+		}, Result: func(cmd redis.Cmder) (interface{}, error) {
+			var (
+				page, cursor, err = cmd.(*redis.ScanCmd).Result()
+				members           = make([]interface{}, 0, len(page)/2)
+				scores            = make([]float64, 0, len(page)/2)
+			)
+			for i := 0; i+1 < len(page); i += 2 {
+				value, e := decodeValue([]byte(page[i]))
+				score, e2 := strconv.ParseFloat(page[i+1], 64)
+				if e == nil && e2 == nil {
+					members, scores = append(members, value), append(scores, score)
+				}
+			}
+			return map[string]interface{}{"cursor": encodeCursor(cursor), "members": members, "scores": scores}, err
+		}},
+	} {
+		RegisterCommand(command)
+	}
+}
@@ -1,86 +1,53 @@
 package https
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/metrics"
 	"github.com/yangkequn/saavuu/permission"
 )
 
-// listten to a port and start http server
-func RedisHttpStart(path string, port int64) {
-	//get item
-	router := http.NewServeMux()
-	router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		var (
-			result     interface{}
-			b          []byte
-			s          string
-			ok         bool
-			err        error
-			httpStatus int = http.StatusOK
-			svcCtx     *HttpContext
-		)
-		if CorsChecked(r, w) {
-			return
-		}
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*12000)
-		defer cancel()
-		if svcCtx, err = NewHttpContext(ctx, r, w); err != nil || svcCtx == nil {
-			httpStatus = http.StatusBadRequest
-		} else if r.Method == "GET" {
-			result, err = svcCtx.GetHandler()
-		} else if r.Method == "POST" {
-			result, err = svcCtx.PostHandler()
-		} else if r.Method == "PUT" {
-			result, err = svcCtx.PutHandler()
-		} else if r.Method == "DELETE" {
-			result, err = svcCtx.DelHandler()
-		}
+var (
+	httpServer *http.Server
 
-		if len(config.Cfg.Http.CORES) > 0 {
-			w.Header().Set("Access-Control-Allow-Origin", config.Cfg.Http.CORES)
-		}
+	httpRequests = metrics.NewCounterVec("saavuu_http_requests_total", "Number of http requests, by command and status code.", "cmd", "code")
+	httpDuration = metrics.NewHistogramVec("saavuu_http_request_duration_seconds", "Time to serve http requests, by command.", nil, "cmd")
+)
 
-		if err == nil {
-			if b, ok = result.([]byte); ok {
-			} else if s, ok = result.(string); ok {
-				b = []byte(s)
-			} else {
-				if b, err = json.Marshal(result); err == nil {
-					//json Compact b
-					var dst *bytes.Buffer = bytes.NewBuffer([]byte{})
-					if err = json.Compact(dst, b); err == nil {
-						b = dst.Bytes()
-					}
-				}
-			}
-		}
-		//this err may be from json.marshal, so don't move it to the above else if
-		if err != nil {
-			if b = []byte(err.Error()); bytes.Contains(b, []byte("JWT")) {
-				httpStatus = http.StatusUnauthorized
-			} else if httpStatus == http.StatusOK {
-				// this if is needed, because  httpStatus may have already setted as StatusBadRequest
-				httpStatus = http.StatusInternalServerError
-			}
-		}
+// RedisHttpStart listens to the port, and serves the gateway at path, with metrics, admin and health endpoints
+func RedisHttpStart(path string, port int64) {
+	server := newRedisHttpServer(path, port)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Error().Err(err).Msg("http server ListenAndServe error")
+	}
+	log.Info().Any("port", port).Any("path", path).Msg("Step3.E: http server stopped")
+}
 
-		//set Content-Type
-		if svcCtx != nil && len(svcCtx.ResponseContentType) > 0 {
-			svcCtx.Rsb.Header().Set("Content-Type", svcCtx.ResponseContentType)
+func newRedisHttpServer(path string, port int64) *http.Server {
+	//get item
+	router := http.NewServeMux()
+	if metricsPath := config.Cfg.Http.MetricsPath; len(metricsPath) > 0 && metricsPath != path {
+		router.Handle(metricsPath, metrics.Handler())
+	}
+	if adminPath := config.Cfg.Http.AdminPath; len(adminPath) > 0 && adminPath != path {
+		if len(config.Cfg.Jwt.Secret) == 0 {
+			log.Warn().Str("adminPath", adminPath).Msg("admin endpoints not served, because Jwt.Secret is empty")
+		} else {
+			router.Handle(adminPath, AdminHandler(adminPath))
 		}
-		w.WriteHeader(httpStatus)
-		w.Write(b)
-	})
+	}
+	if healthPath := config.Cfg.Http.HealthPath; len(healthPath) > 0 && healthPath != path {
+		router.Handle(healthPath, healthHandler(healthPath))
+	}
+	router.Handle(path, NewHandler(Option.WithPrefix(path)))
 
-	server := &http.Server{
+	return &http.Server{
 		Addr:              ":" + strconv.FormatInt(port, 10),
 		Handler:           router,
 		ReadTimeout:       50 * time.Second,
@@ -88,19 +55,39 @@ func RedisHttpStart(path string, port int64) {
 		WriteTimeout:      50 * time.Second, //10ms Redundant time
 		IdleTimeout:       15 * time.Second,
 	}
-	if err := server.ListenAndServe(); err != nil {
-		log.Error().Err(err).Msg("http server ListenAndServe error")
-	}
-	log.Info().Any("port", port).Any("path", path).Msg("Step3.E: http server start completed!")
 }
-func init() {
+
+// Start listens to the port, and serves in background, if config.Cfg.Http.Enable is true. the error of listening is returned.
+// ctx is not the base context of requests, so that in-flight requests are not aborted when it is canceled, but drained by Shutdown
+func Start(ctx context.Context) (err error) {
+	var listener net.Listener
 	log.Info().Any("Step3.1: http service enabled", config.Cfg.Http.Enable).Send()
-	if !config.Cfg.Http.Enable {
-		return
+	if !config.Cfg.Http.Enable || httpServer != nil {
+		return nil
 	}
 	for !permission.ConfigurationLoaded {
 		time.Sleep(time.Millisecond * 10)
 	}
 	log.Info().Any("port", config.Cfg.Http.Port).Any("path", config.Cfg.Http.Path).Msg("Step3.2: http server is starting")
-	go RedisHttpStart(config.Cfg.Http.Path, config.Cfg.Http.Port)
+	server := newRedisHttpServer(config.Cfg.Http.Path, config.Cfg.Http.Port)
+	if listener, err = net.Listen("tcp", server.Addr); err != nil {
+		return err
+	}
+	httpServer = server
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("http server Serve error")
+		}
+		log.Info().Any("port", config.Cfg.Http.Port).Any("path", config.Cfg.Http.Path).Msg("Step3.E: http server stopped")
+	}()
+	return nil
+}
+
+// Shutdown stops accepting requests, and waits for the in-flight ones to complete, until ctx is done
+func Shutdown(ctx context.Context) (err error) {
+	if httpServer == nil {
+		return nil
+	}
+	err, httpServer = httpServer.Shutdown(ctx), nil
+	return err
 }
@@ -0,0 +1,185 @@
+package https
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+)
+
+// ErrConflict is responded as 409, when a precondition of a transaction fails, or its keys change meanwhile
+var ErrConflict = errors.New("conflict: precondition failed")
+
+// OperationWatch is the permission operation of preconditions, on their keys, i.g. rule "doc::watch".
+// it is an operation of its own, as no data command is, so tables without Data.AutoAuth should grant it: permission.Grant("doc", OperationWatch)
+const OperationWatch = "watch"
+
+// Precondition of a transaction, on the hash field Field of Key, or on Key itself if Field is empty. Type is one of
+//   - "exists", "notExists": the field or key exists, or not
+//   - "version": the value is the integer Value, 0 if it does not exist
+//   - "hash": the value is msgpack encoded with the sha256 Value in hex, empty if it does not exist
+type Precondition struct {
+	Type  string      `json:"type" msgpack:"type"`
+	Key   string      `json:"key" msgpack:"key"`
+	Field string      `json:"field,omitempty" msgpack:"field,omitempty"`
+	Value interface{} `json:"value,omitempty" msgpack:"value,omitempty"`
+}
+
+// Transaction is the body of a TXN request: the operations run atomically, if all preconditions hold
+type Transaction struct {
+	Preconditions []*Precondition `json:"preconditions" msgpack:"preconditions"`
+	Ops           []*BatchOp      `json:"ops" msgpack:"ops"`
+}
+
+// transaction watches the keys of the preconditions, checks them, then runs the operations in MULTI/EXEC.
+// i.g. POST /TXN-! with body {"preconditions":[{"type":"version","key":"doc","field":"version","value":3}],
+// "ops":[{"cmd":"HSET","key":"doc","field":"text","value":"..."},{"cmd":"HSET","key":"doc","field":"version","value":4}]}
+func (svcCtx *HttpContext) transaction() (results []*BatchResult, err error) {
+	var (
+		txn      = &Transaction{}
+		rds      *redis.Client
+		keys     []string
+		cmds     []redis.Cmder
+		converts []func(value interface{}) interface{}
+	)
+	if body := svcCtx.MsgpackBodyBytes(); len(body) > 0 {
+		err = msgpack.Unmarshal(body, txn)
+	} else if body = svcCtx.JsonBodyBytes(); len(body) > 0 {
+		err = json.Unmarshal(body, txn)
+	} else {
+		return nil, errors.New("missing transaction")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(txn.Ops)+len(txn.Preconditions) > int(config.Cfg.Http.MaxBatchOps) {
+		return nil, ErrBatchTooLarge
+	}
+	if rds, err = svcCtx.rds(); err != nil {
+		return nil, err
+	}
+	//keys are resolved and permitted before watching
+	watched := make([]*HttpContext, len(txn.Preconditions))
+	for i, precondition := range txn.Preconditions {
+		if watched[i], err = svcCtx.watch(precondition); err != nil {
+			return nil, err
+		}
+		keys = append(keys, watched[i].DataKey())
+	}
+	err = rds.Watch(svcCtx.Ctx, func(tx *redis.Tx) (err error) {
+		for i, precondition := range txn.Preconditions {
+			if err = checkPrecondition(tx, watched[i], precondition); err != nil {
+				return err
+			}
+		}
+		cmds, converts = make([]redis.Cmder, len(txn.Ops)), make([]func(value interface{}) interface{}, len(txn.Ops))
+		_, err = tx.TxPipelined(svcCtx.Ctx, func(pipe redis.Pipeliner) (err error) {
+			for i, op := range txn.Ops {
+				//all or nothing, a bad operation aborts the transaction
+				if cmds[i], converts[i], err = svcCtx.queueOp(pipe, op); err != nil {
+					return fmt.Errorf("operation %d: %w", i, err)
+				}
+			}
+			return nil
+		})
+		return err
+	}, keys...)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, ErrConflict
+	} else if err != nil {
+		return nil, err
+	}
+	results = make([]*BatchResult, len(txn.Ops))
+	for i, op := range txn.Ops {
+		results[i] = &BatchResult{}
+		if results[i].Result, err = commands[op.cmd()].result(cmds[i], converts[i]); err != nil {
+			results[i].Result, results[i].Error = nil, err.Error()
+		}
+	}
+	return results, nil
+}
+
+// watch resolves the key of the precondition, and checks the permission of OperationWatch on it
+func (svcCtx *HttpContext) watch(precondition *Precondition) (svc *HttpContext, err error) {
+	var operation string
+	svc = svcCtx.child("WATCH", precondition.Key, precondition.Field, nil)
+	if operation, err = svc.KeyFieldAtJwt(); err != nil {
+		return nil, err
+	}
+	if !svc.permitted(operation) {
+		return nil, ErrOperationNotPermited
+	}
+	return svc, nil
+}
+
+func checkPrecondition(tx *redis.Tx, svc *HttpContext, precondition *Precondition) (err error) {
+	var (
+		raw    []byte
+		exists bool = true
+	)
+	if len(svc.Field) > 0 {
+		raw, err = tx.HGet(svc.Ctx, svc.DataKey(), svc.Field).Bytes()
+	} else if precondition.Type == "exists" || precondition.Type == "notExists" {
+		var n int64
+		n, err = tx.Exists(svc.Ctx, svc.DataKey()).Result()
+		exists = n > 0
+	} else {
+		raw, err = tx.Get(svc.Ctx, svc.DataKey()).Bytes()
+	}
+	if err == redis.Nil {
+		exists, err = false, nil
+	} else if err != nil {
+		return err
+	}
+	var holds bool
+	switch precondition.Type {
+	case "exists":
+		holds = exists
+	case "notExists":
+		holds = !exists
+	case "version":
+		var (
+			version, expected int64
+			stored            interface{}
+		)
+		//versions kept by HINCRBY are plain integers. they are tried first, as "4" is also the msgpack fixint 52.
+		//versions written from json are msgpack floats, so they are parsed as the params are
+		if exists {
+			if version, err = strconv.ParseInt(string(raw), 10, 64); err != nil {
+				if err = msgpack.Unmarshal(raw, &stored); err != nil {
+					return fmt.Errorf("version of %s is not an integer", svc.Key)
+				}
+				if version, err = strconv.ParseInt(paramString(stored), 10, 64); err != nil {
+					return fmt.Errorf("version of %s is not an integer", svc.Key)
+				}
+			}
+		}
+		if precondition.Value == nil {
+			precondition.Value = 0
+		}
+		if expected, err = strconv.ParseInt(paramString(precondition.Value), 10, 64); err != nil {
+			return fmt.Errorf("precondition version should be integer")
+		}
+		holds = version == expected
+	case "hash":
+		var hash string
+		if exists {
+			sum := sha256.Sum256(raw)
+			hash = hex.EncodeToString(sum[:])
+		}
+		expected, _ := precondition.Value.(string)
+		holds = hash == expected
+	default:
+		return fmt.Errorf("unknown precondition %q", precondition.Type)
+	}
+	if !holds {
+		return fmt.Errorf("%w: %s %s %s", ErrConflict, precondition.Type, svc.Key, svc.Field)
+	}
+	return nil
+}
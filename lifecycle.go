@@ -0,0 +1,87 @@
+package saavuu
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+)
+
+var (
+	lifecycleMut sync.Mutex
+	// stopped is closed when Shutdown completes
+	stopped chan struct{}
+)
+
+// Start starts the api workers and the http server (if config.Cfg.Http.Enable), and returns.
+// SIGINT, SIGTERM or ctx done triggers Shutdown, with config.Cfg.ShutdownTimeout as deadline.
+// use Wait to block until shutdown completes:
+//
+//	saavuu.Start(context.Background())
+//	saavuu.Wait()
+func Start(ctx context.Context) (err error) {
+	lifecycleMut.Lock()
+	if stopped != nil {
+		lifecycleMut.Unlock()
+		return api.ErrAlreadyStarted
+	}
+	stopped = make(chan struct{})
+	done := stopped
+	lifecycleMut.Unlock()
+
+	if err = api.Start(ctx); err == nil {
+		err = https.Start(ctx)
+	}
+	if err != nil {
+		Shutdown(context.Background())
+		return err
+	}
+	go func() {
+		signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		select {
+		case <-signalCtx.Done():
+		case <-done:
+			//shutdown by calling Shutdown directly
+			return
+		}
+		log.Info().Msg("shutdown signal received")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Cfg.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if err := Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("shutdown not completed")
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the http server first, so that no new calls come in; then stops reading api streams and dispatching delayed tasks,
+// and waits for in-flight handlers to send back their results, until ctx is done
+func Shutdown(ctx context.Context) (err error) {
+	lifecycleMut.Lock()
+	defer lifecycleMut.Unlock()
+	if stopped == nil {
+		return nil
+	}
+	err = errors.Join(https.Shutdown(ctx), api.Shutdown(ctx))
+	close(stopped)
+	stopped = nil
+	return err
+}
+
+// Wait blocks until Shutdown completes. it returns immediately if not started
+func Wait() {
+	lifecycleMut.Lock()
+	done := stopped
+	lifecycleMut.Unlock()
+	if done != nil {
+		<-done
+	}
+}
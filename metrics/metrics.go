@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets in seconds, from 1ms to 10s
+var DefBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is a metric family that writes itself in prometheus text format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	collectorsMut sync.Mutex
+	collectors    = map[string]collector{}
+)
+
+func register(name string, c collector) {
+	collectorsMut.Lock()
+	defer collectorsMut.Unlock()
+	collectors[name] = c
+}
+
+// Sample is one value of a metric collected by GaugeFunc. LabelValues are in the order of the label names
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// series is one value of a metric family, identified by its label values
+type series struct {
+	labelValues []string
+	value       float64
+	// buckets, sum and count are used by histogram only
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+type family struct {
+	name, help, kind string
+	labelNames       []string
+	mut              sync.Mutex
+	series           map[string]*series
+}
+
+func newFamily(name, help, kind string, labelNames []string) *family {
+	return &family{name: name, help: help, kind: kind, labelNames: labelNames, series: map[string]*series{}}
+}
+
+// should be called with f.mut locked
+func (f *family) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// sorted keys make the output stable between scrapes
+func (f *family) sortedSeries() (list []*series) {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		list = append(list, f.series[key])
+	}
+	return list
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + f.name + " " + escape(f.help, false) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ *family }
+
+// NewCounterVec creates and registers a counter. the name should end with "_total"
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{newFamily(name, help, "counter", labelNames)}
+	register(name, c)
+	return c
+}
+
+// Add adds v to the counter with the label values. v should not be negative
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.get(labelValues).value += v
+}
+
+// Inc adds 1 to the counter with the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.writeHeader(w)
+	for _, s := range c.sortedSeries() {
+		writeSample(w, c.name, c.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	*family
+	upperBounds []float64
+}
+
+// NewHistogramVec creates and registers a histogram. buckets are the upper bounds, DefBuckets is used if empty
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	upperBounds := append([]float64{}, buckets...)
+	sort.Float64s(upperBounds)
+	h := &HistogramVec{family: newFamily(name, help, "histogram", labelNames), upperBounds: upperBounds}
+	register(name, h)
+	return h
+}
+
+// Observe adds v to the histogram with the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	s := h.get(labelValues)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.upperBounds))
+	}
+	//buckets are not cumulative when stored, they are accumulated on write
+	if i := sort.SearchFloat64s(h.upperBounds, v); i < len(h.upperBounds) {
+		s.buckets[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.writeHeader(w)
+	for _, s := range h.sortedSeries() {
+		var cumulative uint64
+		for i, upperBound := range h.upperBounds {
+			cumulative += s.buckets[i]
+			writeSample(w, h.name+"_bucket", h.labelNames, s.labelValues, "le", formatFloat(upperBound), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labelNames, s.labelValues, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labelNames, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// gaugeFunc is a gauge whose values are collected on every scrape
+type gaugeFunc struct {
+	*family
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge, collect is called on every scrape.
+// kind is "gauge" normally, or "counter" if the collected values only increase
+func NewGaugeFunc(name, help, kind string, collect func() []Sample, labelNames ...string) {
+	register(name, &gaugeFunc{family: newFamily(name, help, kind, labelNames), collect: collect})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	samples := g.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].LabelValues, "\xff") < strings.Join(samples[j].LabelValues, "\xff")
+	})
+	g.writeHeader(w)
+	for _, s := range samples {
+		writeSample(w, g.name, g.labelNames, s.LabelValues, "", "", s.Value)
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	var labels []string
+	for i, labelName := range labelNames {
+		var labelValue string
+		if i < len(labelValues) {
+			labelValue = labelValues[i]
+		}
+		labels = append(labels, labelName+`="`+escape(labelValue, true)+`"`)
+	}
+	if len(extraName) > 0 {
+		labels = append(labels, extraName+`="`+extraValue+`"`)
+	}
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteString("{" + strings.Join(labels, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape as the text format requires. quotes are escaped in label values only
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// WriteText writes all registered metrics in prometheus text format, sorted by name
+func WriteText(w io.Writer) error {
+	collectorsMut.Lock()
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	list := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		list = append(list, collectors[name])
+	}
+	collectorsMut.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range list {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics in prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
@@ -1,7 +1,7 @@
 package permission
 
 import (
-	"fmt"
+	"sync"
 	"time"
 
 	cmap "github.com/orcaman/concurrent-map/v2"
@@ -10,14 +10,17 @@ import (
 	"github.com/yangkequn/saavuu/data"
 )
 
-var rdsPermit = data.New[string, string](data.Option.WithKey("_permissions"))
+var (
+	rdsPermitMut sync.Mutex
+	rdsPermit    *data.Ctx[string, string]
+)
 var permitmap cmap.ConcurrentMap[string, bool] = cmap.New[bool]()
 
 // this version of IsPermitted is design for fast searching & modifying
 func IsPermitted(dataKey string, operation string) (ok bool) {
 	var (
-		autoPermit                bool   = config.Cfg.Data.AutoAuth
-		keyAllowed, keyDisAllowed string = fmt.Sprintf("%s::%s::on", dataKey, operation), fmt.Sprintf("%s::%s::off", dataKey, operation)
+		autoPermit                bool = config.Cfg.Data.AutoAuth
+		keyAllowed, keyDisAllowed      = permitKeys(dataKey, operation)
 	)
 	if _, ok := permitmap.Get(keyAllowed); ok {
 		return true
@@ -27,20 +30,39 @@ func IsPermitted(dataKey string, operation string) (ok bool) {
 	}
 	if autoPermit {
 		permitmap.Set(keyAllowed, true)
-		rdsPermit.HSet(keyAllowed, time.Now().Format("2006-01-02 15:04:05"))
+		if table := permissionTable(); table != nil {
+			table.HSet(keyAllowed, time.Now().Format("2006-01-02 15:04:05"))
+		}
 	}
 	return autoPermit
 }
 
+// permissionTable is created once the default redis is connected, which may be later than init, i.g. with command line flags.
+// nil if not connected
+func permissionTable() *data.Ctx[string, string] {
+	rdsPermitMut.Lock()
+	defer rdsPermitMut.Unlock()
+	if _, ok := config.Rds[""]; ok && rdsPermit == nil {
+		rdsPermit = data.New[string, string](data.Option.WithKey("_permissions"))
+	}
+	return rdsPermit
+}
+
 var ConfigurationLoaded bool = false
 
+// LoadPermissionTable loads the permission table from redis. it is reloaded every minute since init
 func LoadPermissionTable() {
 	var (
-		keys []string
-		err  error
+		keys  []string
+		err   error = ErrPermissionTableUnavailable
+		table       = permissionTable()
 	)
 
-	if keys, err = rdsPermit.Keys(); !ConfigurationLoaded {
+	//rules are fields of the hash, as written by IsPermitted and Grant
+	if table != nil {
+		keys, err = table.HKeys()
+	}
+	if !ConfigurationLoaded {
 		if err != nil {
 			log.Warn().AnErr("Step2.1: start permission loading from redis failed", err).Send()
 		} else {
@@ -48,6 +70,10 @@ func LoadPermissionTable() {
 		}
 		ConfigurationLoaded = true
 	}
+	//keep the loaded table if redis is unavailable for the moment
+	if err != nil {
+		return
+	}
 	latestKeys := map[string]bool{}
 	for _, key := range keys {
 		latestKeys[key] = true
@@ -60,12 +86,13 @@ func LoadPermissionTable() {
 			permitmap.Remove(key)
 		}
 	}
-	go func() {
-		time.Sleep(time.Minute)
-		LoadPermissionTable()
-	}()
 }
 
 func init() {
 	LoadPermissionTable()
+	go func() {
+		for range time.Tick(time.Minute) {
+			LoadPermissionTable()
+		}
+	}()
 }
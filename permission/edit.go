@@ -0,0 +1,88 @@
+package permission
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrPermissionTableUnavailable = errors.New("permission table unavailable: redis not configured")
+
+func permitKeys(dataKey string, operation string) (keyAllowed, keyDisAllowed string) {
+	return fmt.Sprintf("%s::%s::on", dataKey, operation), fmt.Sprintf("%s::%s::off", dataKey, operation)
+}
+
+// Grant allows the operation on the data key, on every instance within a minute
+func Grant(dataKey string, operation string) (err error) {
+	keyAllowed, keyDisAllowed := permitKeys(dataKey, operation)
+	return setRule(keyAllowed, keyDisAllowed)
+}
+
+// Deny disallows the operation on the data key, even if Data.AutoAuth is on
+func Deny(dataKey string, operation string) (err error) {
+	keyAllowed, keyDisAllowed := permitKeys(dataKey, operation)
+	return setRule(keyDisAllowed, keyAllowed)
+}
+
+// Revoke removes the rule of the operation on the data key, allowed or not
+func Revoke(dataKey string, operation string) (err error) {
+	keyAllowed, keyDisAllowed := permitKeys(dataKey, operation)
+	table := permissionTable()
+	if table == nil {
+		return ErrPermissionTableUnavailable
+	}
+	if err = table.HDel(keyAllowed, keyDisAllowed); err != nil {
+		return err
+	}
+	permitmap.Remove(keyAllowed)
+	permitmap.Remove(keyDisAllowed)
+	return nil
+}
+
+func setRule(key, opposite string) (err error) {
+	table := permissionTable()
+	if table == nil {
+		return ErrPermissionTableUnavailable
+	}
+	if err = table.HDel(opposite); err != nil {
+		return err
+	}
+	if err = table.HSet(key, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		return err
+	}
+	permitmap.Remove(opposite)
+	permitmap.Set(key, true)
+	return nil
+}
+
+// Rule is an entry of the permission table
+type Rule struct {
+	DataKey   string
+	Operation string
+	Allowed   bool
+	// Since is the time the rule was set
+	Since string
+}
+
+// Rules returns the permission table
+func Rules() (rules []*Rule, err error) {
+	var entries map[string]string
+	table := permissionTable()
+	if table == nil {
+		return nil, ErrPermissionTableUnavailable
+	}
+	if entries, err = table.HGetAll(); err != nil {
+		return nil, err
+	}
+	for key, since := range entries {
+		//dataKey may contain "::", operation and state may not
+		parts := strings.Split(key, "::")
+		if len(parts) < 3 {
+			continue
+		}
+		n := len(parts)
+		rules = append(rules, &Rule{DataKey: strings.Join(parts[:n-2], "::"), Operation: parts[n-2], Allowed: parts[n-1] == "on", Since: since})
+	}
+	return rules, nil
+}
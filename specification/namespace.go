@@ -0,0 +1,39 @@
+package specification
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidNamespace = errors.New("invalid namespace")
+	ErrNamespacedName   = errors.New("name should not carry a namespace")
+)
+
+// Namespaced prefixes the api name or data key with the namespace, i.g. "acme:api:demo", "acme:user".
+// empty namespace, or name already in the namespace, returns name as it is
+func Namespaced(namespace, name string) string {
+	if len(namespace) == 0 || len(name) == 0 || strings.HasPrefix(name, namespace+":") {
+		return name
+	}
+	return namespace + ":" + name
+}
+
+// ApiNamespace is the namespace of the api name, i.g. "acme" of "acme:api:demo". empty if the name is not namespaced
+func ApiNamespace(apiName string) string {
+	if ind := strings.Index(apiName, ":api:"); ind > 0 && ValidNamespace(apiName[:ind]) && len(apiName) > ind+len(":api:") {
+		return apiName[:ind]
+	}
+	return ""
+}
+
+// ValidNamespace is true if the namespace is letters, digits, '_' or '-'.
+// ':' is not allowed, so that no namespace reaches the keys of another one
+func ValidNamespace(namespace string) bool {
+	for _, c := range namespace {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return len(namespace) > 0
+}
@@ -0,0 +1,89 @@
+package specification
+
+import (
+	"strconv"
+	"time"
+)
+
+// ProtocolVersion is the version of the stream protocol below, shared by callers and workers of any language.
+// it changes whenever an existing worker would misbehave with the new protocol.
+// golden vectors are in test/testdata/protocol.json, and the conformance package verifies a running worker
+//
+// version 1:
+//
+// call: the caller adds a message to the stream of the api, named by ApiStreamName, i.g. "api:demo" or "api:demo:p2".
+// the message has the fields
+//   - FieldData: the msgpack encoded input, a map or struct
+//   - FieldIdempotencyKey: optional, calls of the same api and key run once
+//   - FieldTraceParent: optional, the W3C traceparent of the caller
+//
+// workers read the stream by XREADGROUP of group ConsumerGroup, as consumer Consumer, with NOACK.
+// a worker puts the msgpack encoded output with RPUSH to the list named by the message ID,
+// then sets the list to expire in ReplyExpire. the caller waits for it by BLPOP, up to CallTimeout.
+// a message with empty FieldData, and without FieldTimeAt, is a placeholder that creates the stream, and is skipped.
+//
+// delayed task: the message has FieldTimeAt, the time to run at, in unix nanoseconds as decimal string, and FieldData.
+// the worker puts FieldData to the hash ApiDelayHashName, with FieldTimeAt as field,
+// and runs it at the time, removing the field. no caller waits for the result of a delayed task.
+//
+// cancellation: the message has the FieldTimeAt of the delayed task, and empty FieldData.
+// the worker removes the field from ApiDelayHashName, so the task never runs
+//
+// idempotency: a call with FieldIdempotencyKey runs once per api and key. the worker keeps a msgpack encoded map at ApiIdempotencyKeyName:
+// "input", a digest of FieldData chosen by the worker, equal for equal inputs, and once the call succeeds, "done" true and "result", the output.
+// the first worker sets the map without "done" by SET NX, expiring in a minute, runs the call, then sets the result, expiring in config Api.IdempotencyRetention.
+// a failed call removes the map, so that it can be retried. later calls with the same key and input are replied "result",
+// waiting for it while the call is in flight. calls with the same key and another input are not run
+const ProtocolVersion = 1
+
+const (
+	FieldData           = "data"
+	FieldTimeAt         = "timeAt"
+	FieldIdempotencyKey = "idem"
+	FieldTraceParent    = "traceparent"
+
+	ConsumerGroup = "group0"
+	Consumer      = "saavuu"
+
+	// ReplyExpire is how long the reply list lives, when the caller gave up waiting
+	ReplyExpire = 20 * time.Second
+	// CallTimeout is how long the caller waits for the reply
+	CallTimeout = 6 * time.Second
+)
+
+// ApiIdempotencyKeyName is the key of the call of the api with the idempotency key, i.g. "api:demo:idem:8e03978e"
+func ApiIdempotencyKeyName(apiName, idemKey string) string {
+	return apiName + ":idem:" + idemKey
+}
+
+// ApiDelayHashName is the hash of delayed tasks of the api, i.g. "api:demo:delay"
+func ApiDelayHashName(apiName string) string {
+	return apiName + ":delay"
+}
+
+// TimeAtString is FieldTimeAt of the time, i.g. "1700000000000000000"
+func TimeAtString(timeAt time.Time) string {
+	return strconv.FormatInt(timeAt.UnixNano(), 10)
+}
+
+// CallFields are the fields of a call message. idemKey and traceParent are omitted if empty
+func CallFields(data []byte, idemKey, traceParent string) (values []string) {
+	values = []string{FieldData, string(data)}
+	if len(idemKey) > 0 {
+		values = append(values, FieldIdempotencyKey, idemKey)
+	}
+	if len(traceParent) > 0 {
+		values = append(values, FieldTraceParent, traceParent)
+	}
+	return values
+}
+
+// DelayedTaskFields are the fields of a delayed task message
+func DelayedTaskFields(timeAt time.Time, data []byte) []string {
+	return []string{FieldTimeAt, TimeAtString(timeAt), FieldData, string(data)}
+}
+
+// CancelFields are the fields of the message canceling the delayed task at timeAt
+func CancelFields(timeAt time.Time) []string {
+	return DelayedTaskFields(timeAt, nil)
+}
@@ -2,6 +2,7 @@ package specification
 
 import (
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -33,6 +34,10 @@ var DisAllowedServiceNames = map[string]bool{
 func ApiName(ServiceName string) string {
 	//remove  prefix. "api:" is the case of encoded service name. other wise for the case of parameter type name
 	var prefixes = []string{"api:", "input", "in", "req", "arg", "param", "src", "data"}
+	//namespaced api name, i.g. "acme:api:demo", is kept as it is
+	if len(ApiNamespace(ServiceName)) > 0 {
+		return ServiceName
+	}
 	if ServiceNameLowercase := strings.ToLower(ServiceName); len(ServiceNameLowercase) > 0 {
 		for _, prefix := range prefixes {
 			if strings.HasPrefix(ServiceNameLowercase, prefix) {
@@ -61,3 +66,38 @@ func ApiNameByType(i interface{}) (name string) {
 	return ApiName(_type.Name())
 
 }
+
+// ApiStreamName returns the stream of the api for the given priority lane.
+// lane 0 keeps the plain api name, so that callers without priority and 3rd language workers are unaffected.
+// other lanes are suffixed with ":p" + lane, i.g. "api:demo:p2"
+func ApiStreamName(apiName string, priority int) string {
+	if priority <= 0 {
+		return apiName
+	}
+	return apiName + ":p" + strconv.Itoa(priority)
+}
+
+// ApiNameOfStream is the inverse of ApiStreamName.
+// the lane suffix should follow the service name, which follows the last "api:", i.g. "api:p1" is api named p1 in lane 0,
+// so is "x:api:api:p1" api named p1 in namespace x, as ApiNamespace reads it. stream readers know the lanes they read, rather than parse them
+func ApiNameOfStream(stream string) (apiName string, priority int) {
+	var prefixEnd int
+	if ind := strings.LastIndex(stream, ":api:"); ind >= 0 {
+		prefixEnd = ind + len(":api:")
+	} else if strings.HasPrefix(stream, "api:") {
+		prefixEnd = len("api:")
+	}
+	ind := strings.LastIndex(stream, ":p")
+	if ind < prefixEnd {
+		return stream, 0
+	}
+	if priority, err := strconv.Atoi(stream[ind+2:]); err == nil && priority > 0 {
+		return stream[:ind], priority
+	}
+	return stream, 0
+}
+
+// ApiDeadLetterStreamName is the stream of calls whose handler returned an error, i.g. "api:demo:dlq"
+func ApiDeadLetterStreamName(apiName string) string {
+	return apiName + ":dlq"
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/permission"
+)
+
+func TestAdminRejectsEmptyKey(t *testing.T) {
+	var (
+		secret  = config.Cfg.Jwt.Secret
+		handler = https.AdminHandler("/admin/")
+	)
+	defer func() { config.Cfg.Jwt.Secret = secret }()
+	//anyone can sign the admin claim with the empty key
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"admin": true}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminGet := func(token string) int {
+		rsp, req := httptest.NewRecorder(), httptest.NewRequest("GET", "/admin/apis", nil)
+		req.Header.Set("Authorization", token)
+		handler.ServeHTTP(rsp, req)
+		return rsp.Code
+	}
+
+	config.Cfg.Jwt.Secret = ""
+	if code := adminGet(forged); code != http.StatusForbidden {
+		t.Error("admin should be disabled without Jwt.Secret, but responds", code)
+	}
+
+	config.Cfg.Jwt.Secret = "admin-test-secret"
+	if code := adminGet(forged); code != http.StatusUnauthorized {
+		t.Error("token signed with the empty key should be 401, but", code)
+	}
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"admin": true}).SignedString([]byte(config.Cfg.Jwt.Secret))
+	if code := adminGet(signed); code != http.StatusOK {
+		t.Error("token signed with Jwt.Secret should be accepted, but", code)
+	}
+}
+
+func TestAdminPermissionsRequireKeyAndOperation(t *testing.T) {
+	var (
+		secret  = config.Cfg.Jwt.Secret
+		handler = https.AdminHandler("/admin/")
+	)
+	config.Cfg.Jwt.Secret = "admin-test-secret"
+	defer func() { config.Cfg.Jwt.Secret = secret }()
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"admin": true}).SignedString([]byte(config.Cfg.Jwt.Secret))
+	for _, route := range []string{"PUT /admin/permissions?key=adminTestKey", "PUT /admin/permissions?operation=hget&allow=false", "PUT /admin/permissions?key=&operation=", "DELETE /admin/permissions?key=adminTestKey"} {
+		method, url, _ := strings.Cut(route, " ")
+		rsp, req := httptest.NewRecorder(), httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", signed)
+		if handler.ServeHTTP(rsp, req); rsp.Code != http.StatusBadRequest {
+			t.Errorf("%s should be 400, but %d %s", route, rsp.Code, rsp.Body.String())
+		}
+	}
+	rules, err := permission.Rules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range rules {
+		if len(rule.DataKey) == 0 || len(rule.Operation) == 0 {
+			t.Errorf("rule of empty key or operation is written: %+v", rule)
+		}
+	}
+}
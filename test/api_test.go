@@ -1,7 +1,10 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -90,3 +93,90 @@ func TestCallAtCancel(t *testing.T) {
 	}
 	time.Sleep(30 * time.Second)
 }
+
+type DemoIdempotent struct {
+	Amount int64
+}
+
+var demoIdempotentCalled int64
+
+var ApiDemoIdempotent = api.Api(func(InParam *DemoIdempotent) (ret int64, err error) {
+	return atomic.AddInt64(&demoIdempotentCalled, InParam.Amount), nil
+})
+
+func TestIdempotencyKey(t *testing.T) {
+	var (
+		first, second int64
+		err           error
+		key           = "TestIdempotencyKey" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	)
+	rpc := api.Rpc[*DemoIdempotent, int64](api.Option.WithIdempotencyKey(key))
+	if first, err = rpc(&DemoIdempotent{Amount: 1}); err != nil {
+		t.Error(err)
+	}
+	if second, err = rpc(&DemoIdempotent{Amount: 1}); err != nil {
+		t.Error(err)
+	} else if second != first {
+		t.Error("call with the same idempotency key takes effect twice")
+	}
+}
+
+type DemoIdempotentPerCall struct {
+	Amount               int64
+	HeaderIdempotencyKey string
+}
+
+var demoIdempotentPerCallCalled int64
+
+var ApiDemoIdempotentPerCall = api.Api(func(InParam *DemoIdempotentPerCall) (ret int64, err error) {
+	return atomic.AddInt64(&demoIdempotentPerCallCalled, InParam.Amount), nil
+})
+
+// TestIdempotencyKeyPerCall calls through one Rpc func, with the idempotency key of each call in the input
+func TestIdempotencyKeyPerCall(t *testing.T) {
+	var (
+		prefix = "TestIdempotencyKeyPerCall" + strconv.FormatInt(time.Now().UnixNano(), 10)
+		rpc    = api.Rpc[*DemoIdempotentPerCall, int64]()
+	)
+	first, err := rpc(&DemoIdempotentPerCall{Amount: 1, HeaderIdempotencyKey: prefix + "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//another key with another input is another call, rather than a reused key
+	second, err := rpc(&DemoIdempotentPerCall{Amount: 2, HeaderIdempotencyKey: prefix + "2"})
+	if err != nil {
+		t.Fatal("call with another key and input fails:", err)
+	} else if second != first+2 {
+		t.Errorf("call with another key should run, returning %d, but %d", first+2, second)
+	}
+	//another key with the same input runs again, rather than getting the stale result
+	if third, err := rpc(&DemoIdempotentPerCall{Amount: 2, HeaderIdempotencyKey: prefix + "3"}); err != nil {
+		t.Error(err)
+	} else if third != second+2 {
+		t.Errorf("call with another key and the same input should run, returning %d, but %d", second+2, third)
+	}
+	if retried, err := rpc(&DemoIdempotentPerCall{Amount: 1, HeaderIdempotencyKey: prefix + "1"}); err != nil {
+		t.Error(err)
+	} else if retried != first {
+		t.Error("retry of the first call should get its result", first, "but", retried)
+	}
+}
+
+func TestApiStartReturns(t *testing.T) {
+	if err := api.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- api.Start(context.Background()) }()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Error("Start returns", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Start is still blocked after 3s")
+	}
+	if err := api.Start(context.Background()); err != api.ErrAlreadyStarted {
+		t.Error("second Start returns", err)
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+type InBackpressure struct {
+	Text string
+}
+
+// backlog fills the stream with entries not yet read by the consumer group
+func backlog(t *testing.T, stream string, entries int) {
+	var (
+		rds = config.Rds[""]
+		ctx = context.Background()
+	)
+	rds.Del(ctx, stream)
+	if err := rds.XGroupCreateMkStream(ctx, stream, specification.ConsumerGroup, "$").Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		rds.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: []string{specification.FieldData, ""}})
+	}
+	t.Cleanup(func() { rds.Del(ctx, stream) })
+}
+
+func TestOverloadedByRegisteredApi(t *testing.T) {
+	api.Api(func(in *InBackpressure) (string, error) { return in.Text, nil }, *api.Option.WithName("backpressureRegistered").WithHighWaterMark(2))
+	defer api.Unregister("api:backpressureRegistered")
+	backlog(t, "api:backpressureRegistered", 2)
+	//the caller does not set the high-water mark, the registration of the api does
+	rpc := api.Rpc[*InBackpressure, string](api.Option.WithName("backpressureRegistered"))
+	if _, err := rpc(&InBackpressure{Text: "hello"}); err != api.ErrOverloaded {
+		t.Error("call beyond the high-water mark of the registered api should be ErrOverloaded, but", err)
+	}
+}
+
+func TestOverloadedHttp(t *testing.T) {
+	highWaterMark := config.Cfg.Api.HighWaterMark
+	config.Cfg.Api.HighWaterMark = 1
+	defer func() { config.Cfg.Api.HighWaterMark = highWaterMark }()
+	backlog(t, "api:backpressureHttp", 1)
+	rsp, req := httptest.NewRecorder(), httptest.NewRequest("POST", "/API-!backpressureHttp", nil)
+	https.NewHandler().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusServiceUnavailable || rsp.Header().Get("Retry-After") != "1" {
+		t.Error("overloaded api should respond 503 with Retry-After, but", rsp.Code, rsp.Header(), rsp.Body.String())
+	}
+}
+
+func TestOverloadedByDefault(t *testing.T) {
+	maxLen := config.Cfg.Api.StreamMaxLen
+	config.Cfg.Api.StreamMaxLen = 4
+	defer func() { config.Cfg.Api.StreamMaxLen = maxLen }()
+	//the default high-water mark is beyond the capacity, so 3/4 of the capacity applies
+	backlog(t, "api:backpressureDefault", 3)
+	rpc := api.Rpc[*InBackpressure, string](api.Option.WithName("backpressureDefault"))
+	if _, err := rpc(&InBackpressure{Text: "hello"}); err != api.ErrOverloaded {
+		t.Error("call should be rejected before the stream trims unprocessed calls, but", err)
+	}
+}
+
+func TestTrimmedUnreadCounted(t *testing.T) {
+	maxLen, highWaterMark := config.Cfg.Api.StreamMaxLen, config.Cfg.Api.HighWaterMark
+	config.Cfg.Api.StreamMaxLen, config.Cfg.Api.HighWaterMark = 2, 0
+	defer func() { config.Cfg.Api.StreamMaxLen, config.Cfg.Api.HighWaterMark = maxLen, highWaterMark }()
+	f := api.Api(func(in *InBackpressure) (string, error) { return in.Text, nil }, *api.Option.WithName("backpressureTrim"))
+	defer api.Unregister("api:backpressureTrim")
+	backlog(t, "api:backpressureTrim", 2)
+	series := `saavuu_stream_trimmed_unread_total{api="api:backpressureTrim"}`
+	before := valueOf(scrape(t), series)
+	//without high-water mark, the call is added, and the oldest unprocessed call trimmed
+	if err := api.CallAt(f, time.Now().Add(time.Hour))(&InBackpressure{Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if trimmed := valueOf(scrape(t), series) - before; trimmed != 1 {
+		t.Error("1 unprocessed call should be counted as trimmed, but", trimmed)
+	}
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/data"
+	"github.com/yangkequn/saavuu/https"
+)
+
+func TestHttpCaching(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		get     = func(header, value string) *httptest.ResponseRecorder {
+			req, rsp := httptest.NewRequest("GET", "/HGET-!cachedDoc?F=d1", nil), httptest.NewRecorder()
+			if len(header) > 0 {
+				req.Header.Set(header, value)
+			}
+			handler.ServeHTTP(rsp, req)
+			return rsp
+		}
+	)
+	config.Cfg.Http.CacheControl = map[string]string{"cached*": "public, max-age=60", "*": "no-cache"}
+	defer func() { config.Cfg.Http.CacheControl = nil }()
+	data.New[string, string](data.Option.WithKey("cachedDoc").WithTrackModified())
+	body, _ := msgpack.Marshal("v1")
+	req := httptest.NewRequest("PUT", "/HSET-!cachedDoc?F=d1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	defer handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/DEL-!cachedDoc", nil))
+
+	rsp := get("", "")
+	tag, lastModified := rsp.Header().Get("ETag"), rsp.Header().Get("Last-Modified")
+	if rsp.Code != http.StatusOK || len(tag) == 0 || len(lastModified) == 0 || rsp.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Fatal("response is", rsp.Code, rsp.Header())
+	}
+	if rsp = get("If-None-Match", tag); rsp.Code != http.StatusNotModified || rsp.Body.Len() > 0 {
+		t.Error("If-None-Match of the ETag responds", rsp.Code, rsp.Body.String())
+	}
+	if rsp = get("If-None-Match", `"other"`); rsp.Code != http.StatusOK {
+		t.Error("If-None-Match of another ETag responds", rsp.Code)
+	}
+	if rsp = get("If-Modified-Since", lastModified); rsp.Code != http.StatusNotModified {
+		t.Error("If-Modified-Since of Last-Modified responds", rsp.Code)
+	}
+	if rsp = get("Accept", "application/msgpack"); rsp.Header().Get("ETag") == tag {
+		t.Error("msgpack and json responses have the same ETag")
+	}
+}
+
+func TestModifiedNamespaced(t *testing.T) {
+	var (
+		claim, secret = config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret
+		handler       = https.NewHandler()
+		rds           = config.Rds[""]
+		ctx           = context.Background()
+	)
+	config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret = "ns", "modified-test-secret"
+	defer func() { config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret = claim, secret }()
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"ns": "acme"}).SignedString([]byte(config.Cfg.Jwt.Secret))
+	request := func(method, url string) {
+		body, _ := msgpack.Marshal("v1")
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Authorization", token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	data.New[string, string](data.Option.WithKey("modifiedDoc").WithTrackModified())
+
+	request("PUT", "/HSET-!modifiedDoc?F=d1")
+	defer request("DELETE", "/DEL-!modifiedDoc")
+	if modified, err := data.ModifiedAt(ctx, rds, "acme", "acme:modifiedDoc"); err != nil || modified.IsZero() {
+		t.Fatal("modification time of acme:modifiedDoc should be in acme:_modified, but", modified, err)
+	}
+	if exists, _ := rds.HExists(ctx, data.ModifiedKey, "acme:modifiedDoc").Result(); exists {
+		t.Error("modification time of acme:modifiedDoc should not be in the global _modified")
+	}
+	request("DELETE", "/DEL-!modifiedDoc")
+	if exists, _ := rds.HExists(ctx, data.ModifiedHash("acme"), "acme:modifiedDoc").Result(); exists {
+		t.Error("DEL should remove acme:modifiedDoc from acme:_modified")
+	}
+
+	//keys deleted by data.Ctx are removed too
+	db := data.New[string, string](data.Option.WithKey("modifiedDoc").WithNamespace("acme").WithTrackModified())
+	if err := db.Set("d2", "v2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if modified, _ := data.ModifiedAt(ctx, rds, "acme", "acme:modifiedDoc:d2"); modified.IsZero() {
+		t.Error("Set should touch acme:modifiedDoc:d2 in acme:_modified")
+	}
+	if err := db.Del("d2"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := rds.HExists(ctx, data.ModifiedHash("acme"), "acme:modifiedDoc:d2").Result(); exists {
+		t.Error("Del should remove acme:modifiedDoc:d2 from acme:_modified")
+	}
+}
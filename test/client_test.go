@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/https/client"
+)
+
+func TestClientCallAPI(t *testing.T) {
+	var (
+		server = httptest.NewServer(https.NewHandler())
+		c      = client.New(server.URL)
+		result string
+		err    error
+	)
+	defer server.Close()
+	result, err = client.CallAPI[*Demo1, string](context.Background(), c, "demo1", &Demo1{Text: "TestClientCallAPI", Attach: &Demo{Text: "attach"}})
+	if err != nil {
+		t.Error(err)
+	} else if result != "hello world" {
+		t.Error("result is not hello world")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	var (
+		server = httptest.NewServer(https.NewHandler())
+		key    = client.NewKey[string, *Demo](client.New(server.URL), "clientTestKey")
+	)
+	defer server.Close()
+	defer key.Del()
+	if err := key.HSet("f1", &Demo{Text: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := key.HGet("f1"); err != nil || value == nil || value.Text != "v1" {
+		t.Error("HGet should be v1, but", value, err)
+	}
+	if values, err := key.HGetAll(); err != nil || len(values) != 1 || values["f1"].Text != "v1" {
+		t.Error("HGetAll should be f1 of v1, but", values, err)
+	}
+	if _, err := key.HGet("missing"); !errors.Is(err, client.ErrNotFound) {
+		t.Error("HGet of missing field should be ErrNotFound, but", err)
+	}
+	if err := key.HDel("f1"); err != nil {
+		t.Error(err)
+	}
+	if length, err := key.HLen(); err != nil || length != 0 {
+		t.Error("HLen after HDel should be 0, but", length, err)
+	}
+}
+
+func TestClientNotPermitted(t *testing.T) {
+	var (
+		server   = httptest.NewServer(https.NewHandler())
+		key      = client.NewKey[string, string](client.New(server.URL), "clientTestDenied")
+		autoAuth = config.Cfg.Data.AutoAuth
+	)
+	defer server.Close()
+	config.Cfg.Data.AutoAuth = false
+	defer func() { config.Cfg.Data.AutoAuth = autoAuth }()
+	if _, err := key.HGet("f1"); !errors.Is(err, client.ErrNotPermitted) {
+		t.Error("HGet of key not permitted should be ErrNotPermitted, but", err)
+	}
+}
+
+// TestClientRetries runs against a stub server, as overloads and expired tokens are hard to make on a real one
+func TestClientRetries(t *testing.T) {
+	var (
+		requests int32
+		tokens   []string
+		server   = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&requests, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				tokens = append(tokens, r.Header.Get("Authorization"))
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				tokens = append(tokens, r.Header.Get("Authorization"))
+				w.Write([]byte("done"))
+			}
+		}))
+		token = client.TokenFunc(func(ctx context.Context, refresh bool) (string, error) {
+			if refresh {
+				return "refreshed", nil
+			}
+			return "expired", nil
+		})
+		c = client.New(server.URL, client.Option.WithToken(token).WithRetries(2, time.Millisecond))
+	)
+	defer server.Close()
+	//503 is retried even for posts, 401 refreshes the token once
+	result, err := client.CallAPI[*Demo, string](context.Background(), c, "clientRetries", &Demo{Text: "retry"})
+	if err != nil || result != "done" {
+		t.Fatal("call should succeed after retries, but", result, err)
+	}
+	if len(tokens) != 2 || tokens[0] != "expired" || tokens[1] != "refreshed" {
+		t.Error("token should be refreshed after 401, but", tokens)
+	}
+
+	//posts without idempotency key are not retried on 500
+	atomic.StoreInt32(&requests, 0)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	c = client.New(failing.URL, client.Option.WithRetries(2, time.Millisecond))
+	if _, err = client.CallAPI[*Demo, string](context.Background(), c, "clientRetries", &Demo{Text: "retry"}); !errors.Is(err, client.ErrServer) {
+		t.Error("500 should be ErrServer, but", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Error("post without idempotency key should not be retried, but sent", n, "times")
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+)
+
+type InCoalesce struct {
+	Text string
+}
+
+// InCoalesceHeader receives header fields, which differ between the requests
+type InCoalesceHeader struct {
+	Text              string
+	HeaderTraceparent string
+	HeaderRequestId   string
+}
+
+var coalescedRuns int32
+
+func coalesced(text string) (string, error) {
+	atomic.AddInt32(&coalescedRuns, 1)
+	//long enough for the identical calls to arrive while this one is in flight
+	time.Sleep(300 * time.Millisecond)
+	return text + " coalesced", nil
+}
+
+// registered before Start, so that its stream is read from the start
+var (
+	_ = api.Api(func(in *InCoalesce) (string, error) { return coalesced(in.Text) }, *api.Option.WithName("coalesceTest").WithCoalescing())
+	_ = api.Api(func(in *InCoalesceHeader) (string, error) { return coalesced(in.Text) }, *api.Option.WithName("coalesceHeaderTest").WithCoalescing())
+)
+
+// concurrently runs call n times, and checks every call gets the result, while the api runs once
+func checkCoalesced(t *testing.T, n int, call func(text string) (string, error)) {
+	var (
+		wg      sync.WaitGroup
+		results = make([]string, n)
+		errs    = make([]error, n)
+		text    = "call at " + time.Now().String()
+	)
+	atomic.StoreInt32(&coalescedRuns, 0)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = call(text)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if errs[i] != nil || results[i] != text+" coalesced" {
+			t.Errorf("call %d should get the shared result, but %q %v", i, results[i], errs[i])
+		}
+	}
+	if runs := atomic.LoadInt32(&coalescedRuns); runs != 1 {
+		t.Errorf("%d identical calls should run the api once, but %d times", n, runs)
+	}
+}
+
+// postCoalesce posts the text to the api, with a traceparent and request id of its own, as every request has
+func postCoalesce(handler http.Handler, apiName, text string, header http.Header) (string, error) {
+	body, _ := msgpack.Marshal(&InCoalesce{Text: text})
+	req, rsp := httptest.NewRequest("POST", "/API-!"+apiName, bytes.NewReader(body)), httptest.NewRecorder()
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Request-ID", fmt.Sprint(time.Now().UnixNano()))
+	req.Header.Set("traceparent", fmt.Sprintf("00-%032x-%016x-01", time.Now().UnixNano(), time.Now().UnixNano()))
+	handler.ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		return "", fmt.Errorf("status %d %s", rsp.Code, rsp.Body.String())
+	}
+	return rsp.Body.String(), nil
+}
+
+func TestCoalescingLocal(t *testing.T) {
+	handler := https.NewHandler()
+	checkCoalesced(t, 8, func(text string) (string, error) { return postCoalesce(handler, "coalesceTest", text, nil) })
+}
+
+// TestCoalescingWithHeader checks that the header fields of each request are left out of the identity of the call
+func TestCoalescingWithHeader(t *testing.T) {
+	handler := https.NewHandler()
+	checkCoalesced(t, 8, func(text string) (string, error) { return postCoalesce(handler, "coalesceHeaderTest", text, nil) })
+
+	//a retry carries another traceparent and request id, but is the same call
+	secret := config.Cfg.Jwt.Secret
+	config.Cfg.Jwt.Secret = "coalescing-test-secret"
+	defer func() { config.Cfg.Jwt.Secret = secret }()
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "u1"}).SignedString([]byte(config.Cfg.Jwt.Secret))
+	header := http.Header{"Authorization": {token}, "Idempotency-Key": {fmt.Sprint("TestCoalescingWithHeader", time.Now().UnixNano())}}
+	atomic.StoreInt32(&coalescedRuns, 0)
+	for i := 0; i < 2; i++ {
+		if ret, err := postCoalesce(handler, "coalesceHeaderTest", "retried", header); err != nil || ret != "retried coalesced" {
+			t.Errorf("retry %d with the idempotency key should get the result, but %q %v", i, ret, err)
+		}
+	}
+	if runs := atomic.LoadInt32(&coalescedRuns); runs != 1 {
+		t.Error("retried call should run once, but", runs)
+	}
+}
+
+func TestCoalescingStream(t *testing.T) {
+	rpc := api.Rpc[*InCoalesce, string](api.Option.WithName("coalesceTest"))
+	checkCoalesced(t, 8, func(text string) (string, error) {
+		return rpc(&InCoalesce{Text: text})
+	})
+}
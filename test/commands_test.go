@@ -0,0 +1,145 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/https"
+)
+
+// commandCase is a request to the gateway, and the response body it should get
+type commandCase struct {
+	method, url string
+	// body is msgpack encoded, if not nil
+	body interface{}
+	want string
+	// check replaces want, if the response varies
+	check func(status int, body string) bool
+}
+
+func TestCommands(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		status  = func(code int) func(int, string) bool {
+			return func(status int, body string) bool { return status == code }
+		}
+	)
+	for _, key := range []string{"cmdTest:s", "cmdTestHash", "cmdTestCounter", "cmdTestList", "cmdTestSet", "cmdTestZ", "cmdTestDoc"} {
+		defer handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/DEL-!"+key, nil))
+	}
+	for _, c := range []commandCase{
+		// strings
+		{"PUT", "/SET-!cmdTest?F=s", "sv", "true", nil},
+		{"GET", "/GET-!cmdTest?F=s", nil, "sv", nil},
+		// hashes
+		{"PUT", "/HSET-!cmdTestHash?F=f1", "v1", "true", nil},
+		{"PUT", "/HSET-!cmdTestHash?F=f2", "v2", "true", nil},
+		{"PUT", "/HSETNX-!cmdTestHash?F=f1", "x", "false", nil},
+		{"GET", "/HGET-!cmdTestHash?F=f1", nil, "v1", nil},
+		{"GET", "/HGETALL-!cmdTestHash", nil, `{"f1":"v1","f2":"v2"}`, nil},
+		{"GET", "/HMGET-!cmdTestHash?F=f1,f2", nil, `["v1","v2"]`, nil},
+		{"GET", "/HKEYS-!cmdTestHash", nil, `["f1","f2"]`, nil},
+		{"GET", "/HSCAN-!cmdTestHash?Count=10", nil, `{"cursor":"","fields":{"f1":"v1","f2":"v2"}}`, nil},
+		{"GET", "/HSCAN-!cmdTestHash?Cursor=%21%21", nil, "", status(http.StatusInternalServerError)},
+		{"GET", "/HEXISTS-!cmdTestHash?F=f2", nil, "true", nil},
+		{"GET", "/HLEN-!cmdTestHash", nil, "2", nil},
+		{"GET", "/HVALS-!cmdTestHash", nil, `["v1","v2"]`, nil},
+		{"DELETE", "/HDEL-!cmdTestHash?F=f2", nil, "true", nil},
+		{"GET", "/HRANDFIELD-!cmdTestHash?Count=1", nil, `["f1"]`, nil},
+		{"PUT", "/HINCRBY-!cmdTestCounter?F=n&Increment=3", nil, "3", nil},
+		{"PUT", "/HINCRBY-!cmdTestCounter?F=n&Increment=2", nil, "5", nil},
+		{"PUT", "/HINCRBYFLOAT-!cmdTestCounter?F=n&Increment=0.5", nil, "5.5", nil},
+		// projection of stored values by Queries
+		{"PUT", "/HSET-!cmdTestDoc?F=d1", map[string]interface{}{"name": "n1", "address": map[string]interface{}{"city": "c1", "zip": "z1"}}, "true", nil},
+		{"GET", "/HGET-!cmdTestDoc?F=d1&Queries=name", nil, `{"name":"n1"}`, nil},
+		{"GET", "/HGET-!cmdTestDoc?F=d1&Queries=$.address.city", nil, `{"address":{"city":"c1"}}`, nil},
+		{"GET", "/HGETALL-!cmdTestDoc?Queries=address.zip", nil, `{"d1":{"address":{"zip":"z1"}}}`, nil},
+		{"GET", "/HMGET-!cmdTestDoc?F=d1&Queries=name,address.*", nil, `[{"address":{"city":"c1","zip":"z1"},"name":"n1"}]`, nil},
+		{"GET", "/HGET-!cmdTestDoc?F=d1&Queries=name..city", nil, "", status(http.StatusInternalServerError)},
+		// lists
+		{"PUT", "/RPUSH-!cmdTestList", "a", "true", nil},
+		{"PUT", "/RPUSH-!cmdTestList", "b", "true", nil},
+		{"GET", "/LRANGE-!cmdTestList?Start=0&Stop=-1", nil, `["a","b"]`, nil},
+		{"GET", "/LLEN-!cmdTestList", nil, "2", nil},
+		{"PUT", "/LPUSH-!cmdTestList", "z", "true", nil},
+		{"GET", "/LINDEX-!cmdTestList?Index=0", nil, "z", nil},
+		{"PUT", "/LSET-!cmdTestList?Index=1", "a2", "true", nil},
+		{"PUT", "/RPUSH-!cmdTestList", "z", "true", nil},
+		{"GET", "/LRANGE-!cmdTestList?Start=0&Stop=-1", nil, `["z","a2","b","z"]`, nil},
+		{"DELETE", "/LREM-!cmdTestList?Count=0&Member=z", nil, "2", nil},
+		{"DELETE", "/LPOP-!cmdTestList", nil, "a2", nil},
+		{"PUT", "/RPUSH-!cmdTestList", "c", "true", nil},
+		{"DELETE", "/RPOP-!cmdTestList", nil, "c", nil},
+		{"DELETE", "/LTRIM-!cmdTestList?Start=1&Stop=-1", nil, "true", nil},
+		{"GET", "/LLEN-!cmdTestList", nil, "0", nil},
+		{"DELETE", "/LPOP-!cmdTestList", nil, "redis: nil", nil},
+		// sets
+		{"PUT", "/SADD-!cmdTestSet", "m1", "true", nil},
+		{"GET", "/SISMEMBER-!cmdTestSet?Member=m1", nil, "true", nil},
+		{"GET", "/SMEMBERS-!cmdTestSet", nil, `["m1"]`, nil},
+		{"GET", "/SSCAN-!cmdTestSet", nil, `{"cursor":"","members":["m1"]}`, nil},
+		{"DELETE", "/SREM-!cmdTestSet?Member=m1", nil, "true", nil},
+		{"GET", "/SISMEMBER-!cmdTestSet?Member=m1", nil, "false", nil},
+		// sorted sets
+		{"POST", "/ZADD-!cmdTestZ?Score=1", "z1", "true", nil},
+		{"POST", "/ZADD-!cmdTestZ?Score=2", "z2", "true", nil},
+		{"GET", "/ZRANGE-!cmdTestZ?Start=0&Stop=-1", nil, `["z1","z2"]`, nil},
+		{"GET", "/ZRANGE-!cmdTestZ?Start=0&Stop=-1&WITHSCORES=true", nil, `{"members":["z1","z2"],"scores":[1,2]}`, nil},
+		{"GET", "/ZREVRANGE-!cmdTestZ?Start=0&Stop=-1", nil, `["z2","z1"]`, nil},
+		{"GET", "/ZRANGEBYSCORE-!cmdTestZ?Min=1&Max=1", nil, `["z1"]`, nil},
+		{"GET", "/ZRANGEBYSCORE-!cmdTestZ?Min=-inf&Max=%2Binf&Offset=1&Count=1", nil, `["z2"]`, nil},
+		{"GET", "/ZSCAN-!cmdTestZ", nil, `{"cursor":"","members":["z1","z2"],"scores":[1,2]}`, nil},
+		{"GET", "/ZREVRANGEBYSCORE-!cmdTestZ?Min=-inf&Max=%2Binf", nil, `["z2","z1"]`, nil},
+		{"GET", "/ZCARD-!cmdTestZ", nil, "2", nil},
+		{"GET", "/ZRANK-!cmdTestZ?Member=z2", nil, "1", nil},
+		{"GET", "/ZREVRANK-!cmdTestZ?Member=z2", nil, "0", nil},
+		{"GET", "/ZCOUNT-!cmdTestZ?Min=1&Max=2", nil, "2", nil},
+		{"GET", "/ZSCORE-!cmdTestZ?Member=z2", nil, "2", nil},
+		{"PUT", "/ZINCRBY-!cmdTestZ?Increment=1.5&Member=z1", nil, "2.5", nil},
+		{"POST", "/ZADD-!cmdTestZ?Score=3", "z3", "true", nil},
+		{"POST", "/ZADD-!cmdTestZ?Score=4", "z4", "true", nil},
+		{"GET", "/ZLEXCOUNT-!cmdTestZ?Min=-&Max=%2B", nil, "4", nil},
+		{"DELETE", "/ZREMRANGEBYRANK-!cmdTestZ?Start=-1&Stop=-1", nil, "true", nil},
+		{"DELETE", "/ZPOPMAX-!cmdTestZ", nil, `{"members":["z3"],"scores":[3]}`, nil},
+		{"DELETE", "/ZPOPMIN-!cmdTestZ?Count=1", nil, `{"members":["z2"],"scores":[2]}`, nil},
+		{"GET", "/ZRANGE-!cmdTestZ?Start=0&Stop=-1", nil, `["z1"]`, nil},
+		{"DELETE", "/ZREM-!cmdTestZ?Member=z1", nil, "true", nil},
+		{"DELETE", "/ZREMRANGEBYSCORE-!cmdTestZ?Min=0&Max=10", nil, "true", nil},
+		{"GET", "/ZCARD-!cmdTestZ", nil, "0", nil},
+		// keys
+		{"PUT", "/EXPIRE-!cmdTestHash?Seconds=100", nil, "true", nil},
+		{"GET", "/TTL-!cmdTestHash", nil, "", func(status int, body string) bool {
+			ttl, err := strconv.Atoi(body)
+			return err == nil && ttl > 90 && ttl <= 100
+		}},
+		{"GET", "/TTL-!cmdTestNoSuchKey", nil, "-2", nil},
+		{"DELETE", "/DEL-!cmdTestHash", nil, "true", nil},
+		{"GET", "/HGET-!cmdTestHash?F=f1", nil, "redis: nil", nil},
+		{"GET", "/TIME-!now", nil, "", func(status int, body string) bool {
+			_, err := strconv.ParseInt(body, 10, 64)
+			return status == http.StatusOK && err == nil
+		}},
+		// commands are served with their method only
+		{"GET", "/HSET-!cmdTestHash?F=f1", nil, "", status(http.StatusInternalServerError)},
+		{"GET", "/NOSUCHCMD-!cmdTestHash", nil, "", status(http.StatusInternalServerError)},
+	} {
+		var body io.Reader
+		if c.body != nil {
+			b, _ := msgpack.Marshal(c.body)
+			body = bytes.NewReader(b)
+		}
+		req, rsp := httptest.NewRequest(c.method, c.url, body), httptest.NewRecorder()
+		if c.body != nil {
+			req.Header.Set("Content-Type", "application/octet-stream")
+		}
+		handler.ServeHTTP(rsp, req)
+		if c.check != nil && !c.check(rsp.Code, rsp.Body.String()) || c.check == nil && rsp.Body.String() != c.want {
+			t.Errorf("%s %s responds %d %q, want %q", c.method, c.url, rsp.Code, rsp.Body.String(), c.want)
+		}
+	}
+}
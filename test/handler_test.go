@@ -0,0 +1,271 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+)
+
+func TestNewHandler(t *testing.T) {
+	var (
+		checked string
+		handler = https.NewHandler(https.Option.WithPrefix("/gw/").WithPermission(func(dataKey string, operation string) bool {
+			checked = dataKey + "::" + operation
+			return false
+		}))
+		rsp = httptest.NewRecorder()
+	)
+	handler.ServeHTTP(rsp, httptest.NewRequest("GET", "/other/HGET-!user?F=1", nil))
+	if rsp.Code != http.StatusNotFound {
+		t.Error("request out of prefix should be 404, but", rsp.Code)
+	}
+	rsp = httptest.NewRecorder()
+	handler.ServeHTTP(rsp, httptest.NewRequest("GET", "/gw/HGET-!user?F=1", nil))
+	if checked != "user::hget" {
+		t.Error("permission checker is not used, checked", checked)
+	} else if !strings.Contains(rsp.Body.String(), "permission denied") {
+		t.Error("denied request responds", rsp.Code, rsp.Body.String())
+	}
+}
+
+func TestHandlerMiddleware(t *testing.T) {
+	var (
+		requestId string
+		handler   = https.NewHandler(https.Option.WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requestId = https.RequestIDFrom(r.Context()); r.URL.Query().Get("panic") == "1" {
+					panic("test panic")
+				}
+				next.ServeHTTP(w, r)
+			})
+		}))
+		rsp = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", "/TIME-!now", nil)
+	)
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(rsp, req)
+	if requestId != "req-1" || rsp.Header().Get("X-Request-ID") != "req-1" {
+		t.Error("X-Request-ID is not propagated, got", requestId, rsp.Header().Get("X-Request-ID"))
+	}
+	rsp = httptest.NewRecorder()
+	handler.ServeHTTP(rsp, httptest.NewRequest("GET", "/TIME-!now?panic=1", nil))
+	if rsp.Code != http.StatusInternalServerError || len(requestId) != 32 {
+		t.Error("panic should be recovered as 500 with a generated request ID, got", rsp.Code, requestId)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var (
+		handler = https.NewHandler(https.Option.WithPermission(func(dataKey string, operation string) bool {
+			return dataKey != "batchDenied"
+		}))
+		rsp     = httptest.NewRecorder()
+		results []*https.BatchResult
+	)
+	body, _ := json.Marshal([]*https.BatchOp{
+		{Cmd: "HSET", Key: "batchTest", Field: "f1", Value: "v1"},
+		{Cmd: "HGET", Key: "batchTest", Field: "f1"},
+		{Cmd: "HGET", Key: "batchDenied", Field: "f1"},
+		{Cmd: "NOSUCHCMD", Key: "batchTest"},
+		//json numbers of params are formatted as integers, not as 1e+06
+		{Cmd: "HINCRBY", Key: "batchTest", Field: "n", Params: map[string]interface{}{"Increment": 1000000}},
+		{Cmd: "DEL", Key: "batchTest"},
+	})
+	req := httptest.NewRequest("POST", "/BATCH-!", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(rsp, req)
+	if err := json.Unmarshal(rsp.Body.Bytes(), &results); err != nil || len(results) != 6 {
+		t.Fatal("bad batch response", rsp.Code, rsp.Body.String())
+	}
+	if results[0].Result != "true" || results[1].Result != "v1" {
+		t.Error("batch results are not in order", rsp.Body.String())
+	}
+	if len(results[2].Error) == 0 || len(results[3].Error) == 0 {
+		t.Error("denied and bad operations should fail alone", rsp.Body.String())
+	}
+	if results[4].Result != float64(1000000) {
+		t.Error("large integer param is not parsed", rsp.Body.String())
+	}
+}
+
+// TestBatchQueries checks that an operation of a batch responds as the single command does, with the Queries projection applied
+func TestBatchQueries(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		do      = func(method, url string, body interface{}) *httptest.ResponseRecorder {
+			b, _ := json.Marshal(body)
+			req, rsp := httptest.NewRequest(method, url, bytes.NewReader(b)), httptest.NewRecorder()
+			req.Header.Set("Content-Type", "application/json")
+			handler.ServeHTTP(rsp, req)
+			return rsp
+		}
+		results []*https.BatchResult
+	)
+	defer do("DELETE", "/DEL-!batchQueries", nil)
+	value := map[string]interface{}{"name": "n1", "age": 3, "address": map[string]interface{}{"city": "c1", "zip": "z1"}}
+	if rsp := do("POST", "/BATCH-!", []*https.BatchOp{{Cmd: "HSET", Key: "batchQueries", Field: "f1", Value: value}}); rsp.Code != http.StatusOK {
+		t.Fatal("HSET responds", rsp.Code, rsp.Body.String())
+	}
+	single := do("GET", "/HGET-!batchQueries?F=f1&Queries=name,address.city", nil).Body.String()
+	rsp := do("POST", "/BATCH-!", []*https.BatchOp{
+		{Cmd: "HGET", Key: "batchQueries", Field: "f1", Params: map[string]interface{}{"Queries": "name,address.city"}},
+		{Cmd: "HGETALL", Key: "batchQueries", Params: map[string]interface{}{"Queries": "name"}},
+		{Cmd: "HGET", Key: "batchQueries", Field: "f1", Params: map[string]interface{}{"Queries": "name,,age"}},
+	})
+	if err := json.Unmarshal(rsp.Body.Bytes(), &results); err != nil || len(results) != 3 {
+		t.Fatal("bad batch response", rsp.Code, rsp.Body.String())
+	}
+	if projected, _ := json.Marshal(results[0].Result); string(projected) != single || single != `{"address":{"city":"c1"},"name":"n1"}` {
+		t.Errorf("HGET in batch responds %s, while the single command responds %s", projected, single)
+	}
+	if projected, _ := json.Marshal(results[1].Result); string(projected) != `{"f1":{"name":"n1"}}` {
+		t.Error("every field of HGETALL in batch should be projected, but", string(projected))
+	}
+	if len(results[2].Error) == 0 {
+		t.Error("bad Queries of the operation should fail it, but", results[2].Result)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		txn     = func(txn *https.Transaction) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(txn)
+			req := httptest.NewRequest("POST", "/TXN-!", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rsp := httptest.NewRecorder()
+			handler.ServeHTTP(rsp, req)
+			return rsp
+		}
+		create = &https.Transaction{
+			Preconditions: []*https.Precondition{{Type: "notExists", Key: "txnTest", Field: "version"}},
+			Ops:           []*https.BatchOp{{Cmd: "HSET", Key: "txnTest", Field: "version", Value: 1}, {Cmd: "HSET", Key: "txnTest", Field: "text", Value: "v1"}},
+		}
+	)
+	defer txn(&https.Transaction{Ops: []*https.BatchOp{{Cmd: "DEL", Key: "txnTest"}}})
+	if rsp := txn(create); rsp.Code != http.StatusOK {
+		t.Fatal("transaction should succeed", rsp.Code, rsp.Body.String())
+	}
+	if rsp := txn(create); rsp.Code != http.StatusConflict {
+		t.Error("failed precondition should be 409", rsp.Code, rsp.Body.String())
+	}
+	update := &https.Transaction{
+		Preconditions: []*https.Precondition{{Type: "version", Key: "txnTest", Field: "version", Value: 1}},
+		Ops:           []*https.BatchOp{{Cmd: "HSET", Key: "txnTest", Field: "version", Value: 2}},
+	}
+	if rsp := txn(update); rsp.Code != http.StatusOK {
+		t.Error("version precondition should hold", rsp.Code, rsp.Body.String())
+	}
+	if rsp := txn(update); rsp.Code != http.StatusConflict {
+		t.Error("stale version should be 409", rsp.Code, rsp.Body.String())
+	}
+	//large versions from json are integers, not 1e+06
+	update.Preconditions[0].Value, update.Ops[0].Value = 2, 1000000
+	if rsp := txn(update); rsp.Code != http.StatusOK {
+		t.Error("version precondition should hold", rsp.Code, rsp.Body.String())
+	}
+	update.Preconditions[0].Value, update.Ops[0].Value = 1000000, 1000001
+	if rsp := txn(update); rsp.Code != http.StatusOK {
+		t.Error("large version precondition should hold", rsp.Code, rsp.Body.String())
+	}
+	//preconditions are permitted by operation "watch", apart from the operations
+	var operations []string
+	handler = https.NewHandler(https.Option.WithPermission(func(dataKey string, operation string) bool {
+		operations = append(operations, operation)
+		return operation != https.OperationWatch
+	}))
+	if rsp := txn(update); rsp.Code == http.StatusOK || len(operations) != 1 || operations[0] != https.OperationWatch {
+		t.Error("precondition without watch permission should be denied", rsp.Code, rsp.Body.String(), operations)
+	}
+}
+
+// TestTransactionHIncrByVersion checks version counters kept by HINCRBY, plain integers rather than msgpack
+func TestTransactionHIncrByVersion(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		ctx     = context.Background()
+		rds     = config.Rds[""]
+	)
+	rds.Del(ctx, "txnCounter")
+	defer rds.Del(ctx, "txnCounter")
+	//"4" would be the msgpack fixint 52
+	if err := rds.HIncrBy(ctx, "txnCounter", "version", 4).Err(); err != nil {
+		t.Fatal(err)
+	}
+	//the version is bumped by the transaction it guards
+	txn := func(version int64) int {
+		body, _ := json.Marshal(&https.Transaction{
+			Preconditions: []*https.Precondition{{Type: "version", Key: "txnCounter", Field: "version", Value: version}},
+			Ops:           []*https.BatchOp{{Cmd: "HINCRBY", Key: "txnCounter", Field: "version", Params: map[string]interface{}{"Increment": 1000}}},
+		})
+		req, rsp := httptest.NewRequest("POST", "/TXN-!", bytes.NewReader(body)), httptest.NewRecorder()
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rsp, req)
+		return rsp.Code
+	}
+	if code := txn(52); code != http.StatusConflict {
+		t.Error("version 4 should not be read as the msgpack fixint 52, but", code)
+	}
+	if code := txn(4); code != http.StatusOK {
+		t.Error("version 4 kept by HINCRBY should hold, but", code)
+	}
+	if code := txn(4); code != http.StatusConflict {
+		t.Error("version bumped to 1004 should not hold 4, but", code)
+	}
+	if code := txn(1004); code != http.StatusOK {
+		t.Error("version 1004 bumped by HINCRBY of the transaction should hold, but", code)
+	}
+}
+
+func TestIdempotencyKeyScope(t *testing.T) {
+	var (
+		secret  = config.Cfg.Jwt.Secret
+		handler = https.NewHandler()
+		key     = "TestIdempotencyKeyScope" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	)
+	config.Cfg.Jwt.Secret = "idempotency-test-secret"
+	defer func() { config.Cfg.Jwt.Secret = secret }()
+	call := func(subject string, amount int64) (int, string) {
+		claims := jwt.MapClaims{"sub": subject}
+		if len(subject) == 0 {
+			claims = jwt.MapClaims{"id": "no subject"}
+		}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Cfg.Jwt.Secret))
+		body, _ := json.Marshal(map[string]interface{}{"Amount": amount})
+		req, rsp := httptest.NewRequest("POST", "/API-!demoIdempotent", bytes.NewReader(body)), httptest.NewRecorder()
+		req.Header.Set("Content-Type", "application/json")
+		if subject != "anonymous" {
+			req.Header.Set("Authorization", token)
+		}
+		req.Header.Set("Idempotency-Key", key)
+		handler.ServeHTTP(rsp, req)
+		return rsp.Code, rsp.Body.String()
+	}
+	_, first := call("u1", 1)
+	if code, again := call("u1", 1); code != http.StatusOK || again != first {
+		t.Error("retry with the same key and input should get the stored result", first, "but", code, again)
+	}
+	if code, body := call("u1", 2); code != http.StatusUnprocessableEntity {
+		t.Error("the same key with another input should be 422, but", code, body)
+	}
+	//the key of another subject is another key
+	if code, other := call("u2", 1); code != http.StatusOK || other == first {
+		t.Error("the same key of another subject should run again, but", code, other, first)
+	}
+	//callers without subject would share one key space
+	for _, subject := range []string{"anonymous", ""} {
+		if code, body := call(subject, 1); code != http.StatusUnauthorized {
+			t.Errorf("key of caller %q without subject should be 401, but %d %s", subject, code, body)
+		}
+	}
+}
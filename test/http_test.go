@@ -2,15 +2,17 @@ package test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"testing"
 
 	"github.com/vmihailenco/msgpack/v5"
 	"github.com/yangkequn/saavuu/config"
-	_ "github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/https"
 )
 
 func TestHTTPPostMsgPack(t *testing.T) {
@@ -172,3 +174,42 @@ func TestHTTPCheckNonEmpty(t *testing.T) {
 		t.Error("Text is empty, but status code is 200, which not trigger nonEmpty Check")
 	}
 }
+
+func TestHTTPStart(t *testing.T) {
+	var (
+		port, enable = config.Cfg.Http.Port, config.Cfg.Http.Enable
+		occupied, _  = net.Listen("tcp", "127.0.0.1:0")
+	)
+	if occupied == nil {
+		t.Skip("no port to listen")
+	}
+	https.Shutdown(context.Background())
+	defer func() {
+		config.Cfg.Http.Port, config.Cfg.Http.Enable = port, enable
+		https.Shutdown(context.Background())
+		https.Start(context.Background())
+	}()
+	config.Cfg.Http.Enable = true
+	//the error of listening is returned, rather than logged in background
+	config.Cfg.Http.Port = int64(occupied.Addr().(*net.TCPAddr).Port)
+	if err := https.Start(context.Background()); err == nil {
+		t.Error("Start should fail on a port in use")
+	}
+	occupied.Close()
+
+	//requests outlive the context of Start, and are drained by Shutdown instead
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := https.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	resp, err := http.Get("http://127.0.0.1:" + strconv.Itoa(int(config.Cfg.Http.Port)) + "/TIME-!now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Error("request after the context of Start is canceled responds", resp.StatusCode, string(body))
+	}
+}
@@ -0,0 +1,19 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yangkequn/saavuu"
+)
+
+func TestMain(m *testing.M) {
+	saavuu.Start(context.Background())
+	code := m.Run()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	saavuu.Shutdown(ctx)
+	cancel()
+	os.Exit(code)
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/data"
+	"github.com/yangkequn/saavuu/https"
+)
+
+func TestMediaRanges(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		media   = make([]byte, 700<<10)
+		get     = func(url, rangeHeader string) *httptest.ResponseRecorder {
+			req, rsp := httptest.NewRequest("GET", url, nil), httptest.NewRecorder()
+			if len(rangeHeader) > 0 {
+				req.Header.Set("Range", rangeHeader)
+			}
+			handler.ServeHTTP(rsp, req)
+			return rsp
+		}
+	)
+	for i := range media {
+		media[i] = byte(i * 7)
+	}
+	//a string value, msgpack encoded, is read by GETRANGE past its msgpack header
+	body, _ := msgpack.Marshal(media[:1000])
+	req := httptest.NewRequest("PUT", "/SET-!mediaTest?F=v", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	defer handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/DEL-!mediaTest:v", nil))
+	rsp := get("/GET-!mediaTest-!MP4?F=v", "bytes=10-19")
+	if rsp.Code != http.StatusPartialContent || !bytes.Equal(rsp.Body.Bytes(), media[10:20]) || rsp.Header().Get("Content-Range") != "bytes 10-19/1000" {
+		t.Error("range of value responds", rsp.Code, rsp.Header(), rsp.Body.Bytes())
+	}
+
+	//a blob is stored in chunks, and a range is read from the chunks it spans
+	req = httptest.NewRequest("PUT", "/SETBLOB-!mediaTest?F=b", bytes.NewReader(media))
+	req.Header.Set("Content-Type", "video/mp4")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	defer data.New[string, []byte](data.Option.WithKey("mediaTest")).DelBlob("b")
+	if rsp = get("/BLOB-!mediaTest?F=b", ""); rsp.Code != http.StatusOK || !bytes.Equal(rsp.Body.Bytes(), media) || rsp.Header().Get("Content-Type") != "video/mp4" {
+		t.Error("blob responds", rsp.Code, rsp.Header(), rsp.Body.Len())
+	}
+	start, end := int64(data.DefaultChunkSize-10), int64(data.DefaultChunkSize+10)
+	if rsp = get("/BLOB-!mediaTest?F=b", "bytes=262134-262154"); rsp.Code != http.StatusPartialContent || !bytes.Equal(rsp.Body.Bytes(), media[start:end+1]) {
+		t.Error("range of blob responds", rsp.Code, rsp.Header(), rsp.Body.Len())
+	}
+	if rsp = get("/BLOB-!mediaTest?F=b", "bytes=-5"); !bytes.Equal(rsp.Body.Bytes(), media[len(media)-5:]) {
+		t.Error("suffix range of blob responds", rsp.Code, rsp.Body.Bytes())
+	}
+	if rsp = get("/BLOB-!mediaTest?F=b", "bytes=800000-"); rsp.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Error("range beyond the blob responds", rsp.Code)
+	}
+}
+
+// failingReader fails after the bytes it has
+type failingReader struct{ *bytes.Reader }
+
+func (r failingReader) Read(p []byte) (int, error) {
+	if r.Len() == 0 {
+		return 0, errors.New("upload broken")
+	}
+	return r.Reader.Read(p)
+}
+
+func TestBlobGenerations(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		rds     = config.Rds[""]
+		handler = https.NewHandler()
+		key     = "blobGenTest:b"
+		chunks  = func(manifest *data.BlobManifest) (n int64) {
+			for chunk := int64(0); chunk < manifest.Chunks; chunk++ {
+				n += rds.Exists(ctx, manifest.ChunkKey(key, chunk)).Val()
+			}
+			return n
+		}
+	)
+	first, err := data.WriteBlob(ctx, rds, key, bytes.NewReader([]byte("0123456789")), 4, "text/plain")
+	if err != nil || first.Chunks != 3 || chunks(first) != 3 {
+		t.Fatal("first blob is not written in 3 chunks", first, err)
+	}
+	//the new blob is written apart from the one being read, and the previous generation is removed once swapped
+	second, err := data.WriteBlob(ctx, rds, key, bytes.NewReader([]byte("abcdef")), 4, "text/plain")
+	if err != nil || second.Generation == first.Generation || chunks(second) != 2 || chunks(first) != 0 {
+		t.Error("second blob should replace the first generation", second, err, chunks(first))
+	}
+	//a broken upload leaves the current blob as it is, and no chunk behind
+	broken, err := data.WriteBlob(ctx, rds, key, failingReader{bytes.NewReader([]byte("0123456789"))}, 4, "text/plain")
+	if err == nil || chunks(broken) != 0 {
+		t.Error("broken upload should fail and remove its chunks, but", err, chunks(broken))
+	}
+	if current, err := data.ReadBlobManifest(ctx, rds, key); err != nil || current.Generation != second.Generation || chunks(second) != 2 {
+		t.Error("broken upload changes the current blob", current, err)
+	}
+	//DELBLOB removes the manifest with its chunks
+	rsp := httptest.NewRecorder()
+	handler.ServeHTTP(rsp, httptest.NewRequest("DELETE", "/DELBLOB-!blobGenTest?F=b", nil))
+	if rsp.Body.String() != "true" || rds.Exists(ctx, key).Val() != 0 || chunks(second) != 0 {
+		t.Error("DELBLOB should remove the blob with its chunks, but", rsp.Code, rsp.Body.String(), chunks(second))
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/metrics"
+)
+
+type InMetrics struct {
+	Text string
+}
+
+// scrape gets /metrics, and checks every line is in prometheus text format, with HELP and TYPE before the samples of a family
+func scrape(t *testing.T) string {
+	rsp := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rsp, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := rsp.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Error("Content-Type of metrics is", contentType)
+	}
+	var (
+		sample = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{([a-zA-Z_][a-zA-Z0-9_]*="([^"\\]|\\.)*",?)*\})? (\S+)$`)
+		typed  = map[string]string{}
+	)
+	for scanner := bufio.NewScanner(strings.NewReader(rsp.Body.String())); scanner.Scan(); {
+		line := scanner.Text()
+		if fields := strings.Fields(line); strings.HasPrefix(line, "# TYPE ") && len(fields) == 4 {
+			typed[fields[2]] = fields[3]
+			continue
+		} else if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		match := sample.FindStringSubmatch(line)
+		if match == nil {
+			t.Errorf("line %q is not in prometheus text format", line)
+			continue
+		}
+		if _, err := strconv.ParseFloat(match[5], 64); err != nil {
+			t.Errorf("value of line %q is not a float", line)
+		}
+		family := match[1]
+		if kind, ok := typed[strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(family, "_bucket"), "_sum"), "_count")]; ok && kind == "histogram" {
+			continue
+		} else if _, ok := typed[family]; !ok {
+			t.Errorf("sample %q has no TYPE before it", line)
+		}
+	}
+	return rsp.Body.String()
+}
+
+// valueOf is the value of the sample of the scrape, 0 if absent
+func valueOf(text, series string) float64 {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			value, _ := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			return value
+		}
+	}
+	return 0
+}
+
+func TestMetricsFormat(t *testing.T) {
+	counter := metrics.NewCounterVec("saavuu_test_escaped_total", "Help with \\ and\nnewline.", "label")
+	counter.Inc("a\"b\\c\n")
+	histogram := metrics.NewHistogramVec("saavuu_test_seconds", "Test histogram.", []float64{1, 0.1}, "label")
+	for _, v := range []float64{0.05, 0.5, 5} {
+		histogram.Observe(v, "x")
+	}
+	text := scrape(t)
+	for _, line := range []string{
+		`# HELP saavuu_test_escaped_total Help with \\ and\nnewline.`,
+		`# TYPE saavuu_test_escaped_total counter`,
+		`saavuu_test_escaped_total{label="a\"b\\c\n"} 1`,
+		`# TYPE saavuu_test_seconds histogram`,
+		`saavuu_test_seconds_bucket{label="x",le="0.1"} 1`,
+		`saavuu_test_seconds_bucket{label="x",le="1"} 2`,
+		`saavuu_test_seconds_bucket{label="x",le="+Inf"} 3`,
+		`saavuu_test_seconds_sum{label="x"} 5.55`,
+		`saavuu_test_seconds_count{label="x"} 3`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("metrics should have line %q", line)
+		}
+	}
+}
+
+func TestMetricsOfRequests(t *testing.T) {
+	const (
+		requests = `saavuu_http_requests_total{cmd="HSET",code="200"}`
+		count    = `saavuu_http_request_duration_seconds_count{cmd="HSET"}`
+		inf      = `saavuu_http_request_duration_seconds_bucket{cmd="HSET",le="+Inf"}`
+		calls    = `saavuu_api_calls_total{api="api:metricsTest"}`
+	)
+	var (
+		handler = https.NewHandler()
+		before  = scrape(t)
+	)
+	body, _ := msgpack.Marshal("v1")
+	req := httptest.NewRequest("PUT", "/HSET-!metricsTestKey?F=f1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	rsp := httptest.NewRecorder()
+	handler.ServeHTTP(rsp, req)
+	defer handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/DEL-!metricsTestKey", nil))
+	if rsp.Code != http.StatusOK {
+		t.Fatal("HSET responds", rsp.Code, rsp.Body.String())
+	}
+	api.Api(func(in *InMetrics) (string, error) { return in.Text, nil }, *api.Option.WithName("metricsTest"))
+	defer api.Unregister("api:metricsTest")
+	if _, err := api.CallByHTTP("metricsTest", map[string]interface{}{"Text": "metrics"}, httptest.NewRequest("POST", "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	after := scrape(t)
+	for _, series := range []string{requests, count, inf, calls} {
+		if delta := valueOf(after, series) - valueOf(before, series); delta != 1 {
+			t.Errorf("%s should increase by 1, but by %v", series, delta)
+		}
+	}
+	//buckets are cumulative, up to the count
+	var last float64
+	for _, line := range strings.Split(after, "\n") {
+		if strings.HasPrefix(line, `saavuu_http_request_duration_seconds_bucket{cmd="HSET",`) {
+			value, _ := strconv.ParseFloat(line[strings.LastIndex(line, " ")+1:], 64)
+			if value < last {
+				t.Errorf("bucket %q is less than the one before", line)
+			}
+			last = value
+		}
+	}
+	if last != valueOf(after, count) {
+		t.Errorf("+Inf bucket %v should equal the count %v", last, valueOf(after, count))
+	}
+}
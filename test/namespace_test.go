@@ -0,0 +1,110 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/specification"
+)
+
+func TestApiNamespace(t *testing.T) {
+	for name, want := range map[string]string{
+		"acme:api:demo":   "acme",
+		"api:demo":        "",
+		"acme:api:":       "",
+		"a:b:api:demo":    "",
+		"api:acme:api:x":  "",
+		"acme-1:api:demo": "acme-1",
+	} {
+		if got := specification.ApiNamespace(name); got != want {
+			t.Errorf("ApiNamespace(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	var (
+		claim, secret = config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret
+		handler       = https.NewHandler()
+	)
+	config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret = "ns", "namespace-test-secret"
+	defer func() { config.Cfg.Http.NamespaceClaim, config.Cfg.Jwt.Secret = claim, secret }()
+	token := func(namespace string) string {
+		signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"ns": namespace}).SignedString([]byte(config.Cfg.Jwt.Secret))
+		return signed
+	}
+	request := func(method, url, namespace string, body interface{}) (int, string) {
+		var req *http.Request
+		if body != nil {
+			b, _ := msgpack.Marshal(body)
+			req = httptest.NewRequest(method, url, bytes.NewReader(b))
+			req.Header.Set("Content-Type", "application/octet-stream")
+		} else {
+			req = httptest.NewRequest(method, url, nil)
+		}
+		if len(namespace) > 0 {
+			req.Header.Set("Authorization", token(namespace))
+		}
+		rsp := httptest.NewRecorder()
+		handler.ServeHTTP(rsp, req)
+		return rsp.Code, rsp.Body.String()
+	}
+	defer request("DELETE", "/DEL-!nsTestUser", "acme", nil)
+
+	if _, body := request("PUT", "/HSET-!nsTestUser?F=1", "acme", "secret of acme"); body != "true" {
+		t.Fatal("HSET in namespace acme responds", body)
+	}
+	if _, body := request("GET", "/HGET-!nsTestUser?F=1", "acme", nil); body != "secret of acme" {
+		t.Error("acme should read its own key, but", body)
+	}
+	//the same key of another namespace is another key
+	if _, body := request("GET", "/HGET-!nsTestUser?F=1", "evil", nil); body != "redis: nil" {
+		t.Error("evil should not read the key of acme, but", body)
+	}
+	//keys carrying the namespace are prefixed again, rather than kept as they are
+	if _, body := request("GET", "/HGET-!acme:nsTestUser?F=1", "evil", nil); body == "secret of acme" {
+		t.Error("evil reads the key of acme by prefixing it")
+	}
+	if _, body := request("GET", "/HGET-!acme:nsTestUser?F=1", "acme", nil); body == "secret of acme" {
+		t.Error("acme:acme:nsTestUser should not be acme:nsTestUser")
+	}
+	//requests without namespace should not reach namespaced keys
+	if code, body := request("GET", "/HGET-!acme:nsTestUser?F=1", "", nil); code != http.StatusBadRequest {
+		t.Error("namespaced key without namespace should be 400, but", code, body)
+	}
+	//an invalid JWT is rejected, rather than served in the default namespace
+	for _, authorization := range []string{"not a jwt", token("acme") + "x"} {
+		req := httptest.NewRequest("GET", "/HGET-!nsTestUser?F=1", nil)
+		req.Header.Set("Authorization", authorization)
+		rsp := httptest.NewRecorder()
+		if handler.ServeHTTP(rsp, req); rsp.Code != http.StatusUnauthorized {
+			t.Errorf("invalid JWT %q should be 401, but %d %s", authorization, rsp.Code, rsp.Body.String())
+		}
+	}
+	//keys and api names are checked after jwt values are put in, i.g. @id of a JWT without namespace
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "acme:nsTestUser", "api": "acme:api:nsTestDemo"}).SignedString([]byte(config.Cfg.Jwt.Secret))
+	for _, url := range []string{"/HGET-!@id?F=1", "/API-!@api"} {
+		req := httptest.NewRequest("GET", url, nil)
+		if strings.HasPrefix(url, "/API") {
+			req = httptest.NewRequest("POST", url, nil)
+		}
+		req.Header.Set("Authorization", signed)
+		rsp := httptest.NewRecorder()
+		if handler.ServeHTTP(rsp, req); rsp.Code != http.StatusBadRequest || rsp.Body.String() == "secret of acme" {
+			t.Errorf("%s resolved to a namespaced name should be 400, but %d %s", url, rsp.Code, rsp.Body.String())
+		}
+	}
+	//namespaced api names are rejected, whatever the namespace of the request is
+	for _, namespace := range []string{"", "evil", "acme"} {
+		if code, body := request("POST", "/API-!acme:api:nsTestDemo", namespace, nil); code != http.StatusBadRequest {
+			t.Errorf("namespaced api name requested in namespace %q should be 400, but %d %s", namespace, code, body)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/data"
+	"github.com/yangkequn/saavuu/https"
+)
+
+type negotiatedDoc struct {
+	Name  string `msgpack:"name" json:"title"`
+	Count int64  `msgpack:"count" json:"count"`
+}
+
+func TestContentNegotiation(t *testing.T) {
+	var (
+		handler = https.NewHandler()
+		doc     = &negotiatedDoc{Name: "n1", Count: 1 << 40}
+		get     = func(url, accept string) *httptest.ResponseRecorder {
+			req, rsp := httptest.NewRequest("GET", url, nil), httptest.NewRecorder()
+			if len(accept) > 0 {
+				req.Header.Set("Accept", accept)
+			}
+			handler.ServeHTTP(rsp, req)
+			return rsp
+		}
+	)
+	data.New[string, negotiatedDoc](data.Option.WithKey("negotiatedDoc"))
+	body, _ := msgpack.Marshal(doc)
+	req := httptest.NewRequest("PUT", "/HSET-!negotiatedDoc?F=d1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	defer handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/DEL-!negotiatedDoc", nil))
+
+	//values of registered keys are typed, so json follows the tags of the type
+	if rsp := get("/HGET-!negotiatedDoc?F=d1", ""); rsp.Body.String() != `{"title":"n1","count":1099511627776}` {
+		t.Error("json response is", rsp.Body.String())
+	}
+	var decoded negotiatedDoc
+	rsp := get("/HGET-!negotiatedDoc?F=d1", "application/json;q=0.5, application/msgpack")
+	if rsp.Header().Get("Content-Type") != https.ContentTypeMsgpack || msgpack.Unmarshal(rsp.Body.Bytes(), &decoded) != nil || decoded != *doc {
+		t.Error("msgpack response is", rsp.Header().Get("Content-Type"), decoded)
+	}
+	decoded = negotiatedDoc{}
+	rsp = get("/HGET-!negotiatedDoc-!CBOR?F=d1", "application/msgpack")
+	if rsp.Header().Get("Content-Type") != https.ContentTypeCbor || cbor.Unmarshal(rsp.Body.Bytes(), &decoded) != nil || decoded.Count != doc.Count {
+		t.Error("cbor response is", rsp.Header().Get("Content-Type"), decoded)
+	}
+	if rsp = get("/HGET-!negotiatedDoc?F=none", "application/msgpack"); rsp.Code == http.StatusOK || rsp.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Error("error response is", rsp.Code, rsp.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/conformance"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestProtocolGoldenVectors fails when the encoding of stream messages changes.
+// if the change is intended, bump specification.ProtocolVersion, and run go test -run Golden -update
+func TestProtocolGoldenVectors(t *testing.T) {
+	const golden = "testdata/protocol.json"
+	vectors, err := conformance.GoldenVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(vectors); err != nil {
+		t.Fatal(err)
+	}
+	if b := buf.Bytes(); *updateGolden {
+		if err = os.WriteFile(golden, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected, err := os.ReadFile(golden); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected, buf.Bytes()) {
+		t.Errorf("protocol vectors differ from %s:\n%s", golden, buf.Bytes())
+	}
+}
+
+type ConformanceEcho struct {
+	Text              string
+	Count             int64
+	HeaderTraceparent string
+}
+
+var ApiConformanceEcho = api.Api(func(in *ConformanceEcho) (*ConformanceEcho, error) {
+	return in, nil
+}, api.ApiOption{Name: conformance.EchoApiName})
+
+// TestProtocolConformance runs the conformance harness against the go worker itself
+func TestProtocolConformance(t *testing.T) {
+	rds, ok := config.Rds[""]
+	if !ok {
+		return
+	}
+	for _, check := range conformance.Run(context.Background(), rds, conformance.EchoApiName) {
+		if check.Err != nil {
+			t.Errorf("%s: %v", check.Name, check.Err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+)
+
+type InRegisteredLate struct {
+	Text string
+}
+
+// TestRegisterAfterStart registers an api once the stream readers are blocked reading, as TestMain starts them
+func TestRegisterAfterStart(t *testing.T) {
+	const apiName = "api:registeredLate"
+	api.Api(func(in *InRegisteredLate) (string, error) { return in.Text + " late", nil }, *api.Option.WithName("registeredLate"))
+	defer api.Unregister(apiName)
+	if !api.ApiServices.Has(apiName) {
+		t.Fatal("api registered after Start is not in ApiServices")
+	}
+
+	//http calls of the registered api are local
+	body, _ := msgpack.Marshal(&InRegisteredLate{Text: "http"})
+	req, rsp := httptest.NewRequest("POST", "/API-!registeredLate", bytes.NewReader(body)), httptest.NewRecorder()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	https.NewHandler().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK || rsp.Body.String() != "http late" {
+		t.Error("http call of api registered after Start responds", rsp.Code, rsp.Body.String())
+	}
+
+	//the reader blocked on the streams of earlier apis is unblocked by CLIENT UNBLOCK, and reads the new stream at once
+	if err := config.Rds[""].ClientID(context.Background()).Err(); err != nil {
+		t.Skip("the redis server does not support CLIENT ID, so the new stream is read once the blocking read times out:", err)
+	}
+	start := time.Now()
+	result, err := api.Rpc[*InRegisteredLate, string](api.Option.WithName("registeredLate"))(&InRegisteredLate{Text: "rpc"})
+	if err != nil || result != "rpc late" {
+		t.Fatal("rpc of api registered after Start should be replied, but", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Error("new stream is read after", elapsed, "rather than at once")
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	const apiName = "api:unregisteredLate"
+	api.Api(func(in *InRegisteredLate) (string, error) { return in.Text, nil }, *api.Option.WithName("unregisteredLate"))
+	api.Unregister(apiName)
+	if api.ApiServices.Has(apiName) {
+		t.Error("unregistered api is still in ApiServices")
+	}
+	//registering it again makes it callable again
+	api.Api(func(in *InRegisteredLate) (string, error) { return in.Text + " again", nil }, *api.Option.WithName("unregisteredLate"))
+	defer api.Unregister(apiName)
+	if ret, err := api.CallByHTTP(apiName, map[string]interface{}{"Text": "registered"}, httptest.NewRequest("POST", "/", nil)); err != nil || ret != "registered again" {
+		t.Error("api registered again should be called, but", ret, err)
+	}
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/data"
+)
+
+// pagedScan serves HSCAN and SSCAN replies in pages of COUNT, cursor being the page number.
+// redis replies small hashes and sets at once whatever COUNT is, and so does HSCAN of miniredis, so the cursors would never be followed
+type pagedScan struct {
+	calls int
+}
+
+func (h *pagedScan) DialHook(next redis.DialHook) redis.DialHook { return next }
+func (h *pagedScan) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+func (h *pagedScan) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		scan, ok := cmd.(*redis.ScanCmd)
+		if name := cmd.Name(); !ok || name != "hscan" && name != "sscan" {
+			return next(ctx, cmd)
+		}
+		//i.g. hscan key cursor [match pattern] count n
+		args := cmd.Args()
+		page, count := int(args[2].(uint64)), int(args[len(args)-1].(int64))
+		if cmd.Name() == "hscan" {
+			count *= 2
+		}
+		//the whole key is read, and paged here
+		args[2], args[len(args)-1] = uint64(0), int64(1<<20)
+		if err := next(ctx, cmd); err != nil {
+			return err
+		}
+		h.calls++
+		all, _ := scan.Val()
+		from, to, nextPage := page*count, (page+1)*count, uint64(page+1)
+		if to >= len(all) {
+			to, nextPage = len(all), 0
+		}
+		scan.SetVal(all[from:to], nextPage)
+		return nil
+	}
+}
+
+// pagedScanKey is the data key, with its client paging HSCAN and SSCAN
+func pagedScanKey[k comparable, v any](t *testing.T, key string) (*data.Ctx[k, v], *pagedScan) {
+	rds, ok := config.Rds[""]
+	if !ok {
+		t.Skip("redis not configured")
+	}
+	var (
+		db    = data.New[k, v](&data.DataOption{Key: key})
+		hook  = &pagedScan{}
+		paged = redis.NewClient(rds.Options())
+	)
+	paged.AddHook(hook)
+	t.Cleanup(func() { paged.Close() })
+	db.Rds = paged
+	db.Rds.Del(db.Ctx, db.Key)
+	t.Cleanup(func() { rds.Del(context.Background(), db.Key) })
+	return db, hook
+}
+
+func TestHScanIterPages(t *testing.T) {
+	db, hook := pagedScanKey[int64, *TestHash](t, "scanHash")
+	for i := int64(0); i < 25; i++ {
+		if err := db.HSet(i, &TestHash{Name: strconv.FormatInt(i, 10)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	visited := map[int64]bool{}
+	it := db.HScan("", 4)
+	for it.Next() {
+		if visited[it.Key()] {
+			t.Error("field visited twice", it.Key())
+		}
+		if visited[it.Key()] = true; it.Value() == nil || it.Value().Name != strconv.FormatInt(it.Key(), 10) {
+			t.Error("value of field", it.Key(), "is", it.Value())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 25 || hook.calls != 7 {
+		t.Errorf("25 fields should be visited in 7 pages, but %d in %d", len(visited), hook.calls)
+	}
+	//an exhausted iterator stays exhausted
+	if it.Next() || hook.calls != 7 {
+		t.Error("Next after the last page should be false, without scanning again")
+	}
+
+	//the pattern is applied by redis, pages may hold fewer fields than count
+	hook.calls = 0
+	var matched []int64
+	for it = db.HScan("1*", 4); it.Next(); {
+		matched = append(matched, it.Key())
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i] < matched[j] })
+	if fmt.Sprint(matched) != "[1 10 11 12 13 14 15 16 17 18 19]" || hook.calls != 3 {
+		t.Errorf("fields matching 1* should be visited in 3 pages, but %v in %d", matched, hook.calls)
+	}
+}
+
+func TestSScanIterPages(t *testing.T) {
+	db, hook := pagedScanKey[string, *TestHash](t, "scanSet")
+	for i := 0; i < 10; i++ {
+		if err := db.SAdd(&TestHash{Name: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	visited := map[string]bool{}
+	it := db.SScan("", 3)
+	for it.Next() {
+		if visited[it.Value().Name] {
+			t.Error("member visited twice", it.Value().Name)
+		}
+		visited[it.Value().Name] = true
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 10 || hook.calls != 4 {
+		t.Errorf("10 members should be visited in 4 pages, but %d in %d", len(visited), hook.calls)
+	}
+}
+
+func TestHScanPage(t *testing.T) {
+	db, hook := pagedScanKey[string, *TestHash](t, "scanPage")
+	for i := 0; i < 12; i++ {
+		if err := db.HSet(fmt.Sprintf("f%d", i), &TestHash{Name: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		fields = map[string]*TestHash{}
+		pages  int
+	)
+	for cursor := uint64(0); ; {
+		page, next, err := db.HScanPage(cursor, "", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages++; next != 0 && len(page) != 5 || next == 0 && len(page) != 2 {
+			t.Errorf("page %d has %d fields, next cursor %d", pages, len(page), next)
+		}
+		for field, value := range page {
+			if _, ok := fields[field]; ok {
+				t.Error("field returned twice", field)
+			}
+			fields[field] = value
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(fields) != 12 || pages != 3 || hook.calls != 3 {
+		t.Errorf("12 fields should be returned in 3 pages, but %d in %d", len(fields), pages)
+	}
+	if fields["f11"] == nil || fields["f11"].Name != "11" {
+		t.Error("value of f11 is", fields["f11"])
+	}
+}
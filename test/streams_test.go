@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/yangkequn/saavuu/specification"
+)
+
+func TestApiStreamName(t *testing.T) {
+	for _, c := range []struct {
+		apiName  string
+		priority int
+		want     string
+	}{
+		{"api:demo", 0, "api:demo"},
+		{"api:demo", -1, "api:demo"},
+		{"api:demo", 1, "api:demo:p1"},
+		{"api:demo", 12, "api:demo:p12"},
+		{"acme:api:demo", 2, "acme:api:demo:p2"},
+	} {
+		if got := specification.ApiStreamName(c.apiName, c.priority); got != c.want {
+			t.Errorf("ApiStreamName(%q, %d) = %q, want %q", c.apiName, c.priority, got, c.want)
+		}
+	}
+}
+
+func TestApiNameOfStream(t *testing.T) {
+	for _, c := range []struct {
+		stream   string
+		apiName  string
+		priority int
+	}{
+		{"api:demo", "api:demo", 0},
+		{"api:demo:p1", "api:demo", 1},
+		{"api:demo:p12", "api:demo", 12},
+		{"acme:api:demo:p2", "acme:api:demo", 2},
+		//the lane suffix should follow the service name
+		{"api:p1", "api:p1", 0},
+		{"acme:api:p1", "acme:api:p1", 0},
+		{"x:api:api:p1", "x:api:api:p1", 0},
+		{"api:api:p1", "api:api:p1", 0},
+		//not a lane
+		{"api:demo:p0", "api:demo:p0", 0},
+		{"api:demo:px", "api:demo:px", 0},
+		{"api:demo:p-1", "api:demo:p-1", 0},
+		{"api:myapi:p2", "api:myapi", 2},
+		{"api:p:p1", "api:p", 1},
+	} {
+		if apiName, priority := specification.ApiNameOfStream(c.stream); apiName != c.apiName || priority != c.priority {
+			t.Errorf("ApiNameOfStream(%q) = %q, %d, want %q, %d", c.stream, apiName, priority, c.apiName, c.priority)
+		}
+	}
+	//the inverse of ApiStreamName
+	for _, apiName := range []string{"api:demo", "acme:api:demo", "api:p", "acme:api:user:profile"} {
+		for priority := 0; priority < 3; priority++ {
+			if got, p := specification.ApiNameOfStream(specification.ApiStreamName(apiName, priority)); got != apiName || p != priority {
+				t.Errorf("ApiNameOfStream(ApiStreamName(%q, %d)) = %q, %d", apiName, priority, got, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yangkequn/saavuu/api"
+	"github.com/yangkequn/saavuu/config"
+	"github.com/yangkequn/saavuu/https"
+	"github.com/yangkequn/saavuu/specification"
+	"github.com/yangkequn/saavuu/tracing"
+)
+
+// spanRecorder keeps the exported spans
+type spanRecorder struct {
+	mut   sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) Export(span *tracing.Span) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// span is the exported span of the name in the trace, nil if none
+func (r *spanRecorder) span(name string, traceID [16]byte) *tracing.Span {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name && span.Context.TraceID == traceID {
+			return span
+		}
+	}
+	return nil
+}
+
+type InTraceWorker struct {
+	Text              string
+	HeaderTraceparent string
+}
+
+// registered before Start, so that its stream is read from the start
+var _ = api.Api(func(in *InTraceWorker) (string, error) {
+	return in.HeaderTraceparent, nil
+}, *api.Option.WithName("traceWorker"))
+
+// remoteCall posts to api:traceRemote, which no instance serves, with the traceparent header.
+// it returns the traceparent of the stream message, replying the call as a worker would
+func remoteCall(t *testing.T, traceParent string) (messageTraceParent string) {
+	var (
+		rds     = config.Rds[""]
+		ctx     = context.Background()
+		stream  = "api:traceRemote"
+		replied = make(chan string, 1)
+	)
+	rds.Del(ctx, stream)
+	defer rds.Del(ctx, stream)
+	go func() {
+		for deadline := time.Now().Add(specification.CallTimeout); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if messages, err := rds.XRange(ctx, stream, "-", "+").Result(); err == nil && len(messages) > 0 {
+				reply, _ := msgpack.Marshal("ok")
+				rds.RPush(ctx, messages[0].ID, reply)
+				tp, _ := messages[0].Values[specification.FieldTraceParent].(string)
+				replied <- tp
+				return
+			}
+		}
+		replied <- ""
+	}()
+	body, _ := msgpack.Marshal(&InTraceWorker{Text: "trace"})
+	req, rsp := httptest.NewRequest("POST", "/API-!traceRemote", bytes.NewReader(body)), httptest.NewRecorder()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("traceparent", traceParent)
+	https.NewHandler().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Fatal("call of api:traceRemote responds", rsp.Code, rsp.Body.String())
+	}
+	return <-replied
+}
+
+func TestTraceParentPropagation(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	var (
+		recorder = &spanRecorder{}
+		rds      = config.Rds[""]
+		ctx      = context.Background()
+		caller   tracing.SpanContext
+		message  tracing.SpanContext
+		ok       bool
+	)
+	tracing.SetExporter(recorder)
+	defer tracing.SetExporter(nil)
+	caller, _ = tracing.ParseTraceParent(traceParent)
+
+	//http -> stream message: the message carries the enqueue span, child of the http span, child of the caller
+	if message, ok = tracing.ParseTraceParent(remoteCall(t, traceParent)); !ok || message.TraceID != caller.TraceID {
+		t.Fatal("stream message should be in the trace of the caller, but", message.TraceParent())
+	}
+	httpSpan, enqueue := recorder.span("HTTP POST API", caller.TraceID), recorder.span("enqueue api:traceRemote", caller.TraceID)
+	if httpSpan == nil || httpSpan.ParentSpanID != caller.SpanID {
+		t.Fatal("http span should be child of the caller", httpSpan)
+	}
+	if enqueue == nil || enqueue.ParentSpanID != httpSpan.Context.SpanID || enqueue.Context.SpanID != message.SpanID {
+		t.Fatal("stream message should carry the enqueue span, child of the http span", enqueue)
+	}
+
+	//stream message -> worker: the handler span is child of the message, and the api receives the traceparent of the handler span
+	data, _ := specification.MarshalApiInput(&InTraceWorker{Text: "trace"})
+	id, err := rds.XAdd(ctx, &redis.XAddArgs{Stream: "api:traceWorker", Values: specification.CallFields(data, "", message.TraceParent())}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := rds.BLPop(ctx, specification.CallTimeout, id).Result()
+	if err != nil {
+		t.Fatal("worker does not reply", err)
+	}
+	var received string
+	msgpack.Unmarshal([]byte(results[1]), &received)
+	handler := recorder.span("handler api:traceWorker", caller.TraceID)
+	if handler == nil || handler.ParentSpanID != message.SpanID {
+		t.Fatal("handler span should be child of the stream message", handler)
+	}
+	if received != handler.Context.TraceParent() {
+		t.Errorf("api should receive the traceparent of the handler span %s, but %s", handler.Context.TraceParent(), received)
+	}
+	if queue := recorder.span("queue api:traceWorker", caller.TraceID); queue == nil || queue.ParentSpanID != message.SpanID {
+		t.Error("queue span should be child of the stream message", queue)
+	}
+}
+
+func TestMalformedTraceParent(t *testing.T) {
+	const malformed = "00-not-a-traceparent-01"
+	recorder := &spanRecorder{}
+	tracing.SetExporter(recorder)
+	defer tracing.SetExporter(nil)
+	message, ok := tracing.ParseTraceParent(remoteCall(t, malformed))
+	if !ok {
+		t.Fatal("stream message should carry a valid traceparent of a new trace, but", message.TraceParent())
+	}
+	if httpSpan := recorder.span("HTTP POST API", message.TraceID); httpSpan == nil || httpSpan.ParentSpanID != [8]byte{} {
+		t.Error("malformed traceparent should start a new trace at the http span, but", httpSpan)
+	}
+}
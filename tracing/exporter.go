@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yangkequn/saavuu/config"
+)
+
+// otlpJsonExporter writes every span as a line of OTLP/JSON (ExportTraceServiceRequest),
+// the format written by the file exporter of the OpenTelemetry collector
+type otlpJsonExporter struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+// NewOTLPJsonExporter writes spans to w, one OTLP/JSON line per span
+func NewOTLPJsonExporter(w io.Writer) Exporter {
+	return &otlpJsonExporter{w: w}
+}
+
+// NewStdoutExporter writes spans to stdout, for local testing
+func NewStdoutExporter() Exporter {
+	return NewOTLPJsonExporter(os.Stdout)
+}
+
+// NewFileExporter appends spans to the file, which can be replayed by the otlpjsonfile receiver of the OpenTelemetry collector
+func NewFileExporter(path string) (Exporter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewOTLPJsonExporter(file), nil
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+type otlpStatus struct {
+	//1 ok, 2 error
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+func keyValues(attributes map[string]string) (kvs []otlpKeyValue) {
+	for key, value := range attributes {
+		kv := otlpKeyValue{Key: key}
+		kv.Value.StringValue = value
+		kvs = append(kvs, kv)
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+func (e *otlpJsonExporter) Export(span *Span) {
+	s := otlpSpan{
+		TraceId:           hex.EncodeToString(span.Context.TraceID[:]),
+		SpanId:            hex.EncodeToString(span.Context.SpanID[:]),
+		Name:              span.Name,
+		Kind:              1,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        keyValues(span.Attributes),
+		Status:            otlpStatus{Code: 1},
+	}
+	if span.ParentSpanID != [8]byte{} {
+		s.ParentSpanId = hex.EncodeToString(span.ParentSpanID[:])
+	}
+	if len(span.Err) > 0 {
+		s.Status = otlpStatus{Code: 2, Message: span.Err}
+	}
+	request := map[string]interface{}{"resourceSpans": []interface{}{map[string]interface{}{
+		"resource":   map[string]interface{}{"attributes": keyValues(map[string]string{"service.name": config.Cfg.Tracing.ServiceName})},
+		"scopeSpans": []interface{}{map[string]interface{}{"scope": map[string]string{"name": "saavuu"}, "spans": []otlpSpan{s}}},
+	}}}
+	b, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	e.w.Write(append(b, '\n'))
+}
+
+// the exporter is chosen by config.Cfg.Tracing.Exporter
+func init() {
+	switch config.Cfg.Tracing.Exporter {
+	case "stdout":
+		SetExporter(NewStdoutExporter())
+	case "file":
+		if e, err := NewFileExporter(config.Cfg.Tracing.File); err != nil {
+			log.Error().Err(err).Str("file", config.Cfg.Tracing.File).Msg("tracing file exporter not created")
+		} else {
+			SetExporter(e)
+		}
+	}
+}
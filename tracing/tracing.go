@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext is the part of a span propagated to other processes, as W3C traceparent:
+//
+//	00-<32 hex trace id>-<16 hex parent span id>-<2 hex flags>
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// FlagSampled is set if the span is recorded by the caller
+const FlagSampled byte = 0x01
+
+// ParseTraceParent parses the traceparent header. ok is false if s is empty or malformed
+func ParseTraceParent(s string) (sc SpanContext, ok bool) {
+	var flags [1]byte
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	//version ff is invalid. future versions may append fields, which are ignored
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// IsValid is false if trace id or span id is all zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats the span context as W3C traceparent. empty if not valid
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	Name         string
+	Context      SpanContext
+	ParentSpanID [8]byte
+	Start, End   time.Time
+	Attributes   map[string]string
+	// Err is the error the operation ended with. empty means ok
+	Err string
+}
+
+// Exporter receives ended spans
+type Exporter interface {
+	Export(span *Span)
+}
+
+var (
+	exporterMut sync.RWMutex
+	exporter    Exporter
+)
+
+// SetExporter sets where ended spans go. nil disables exporting, trace context is still propagated
+func SetExporter(e Exporter) {
+	exporterMut.Lock()
+	defer exporterMut.Unlock()
+	exporter = e
+}
+
+func currentExporter() Exporter {
+	exporterMut.RLock()
+	defer exporterMut.RUnlock()
+	return exporter
+}
+
+// Start starts a span as child of parent traceparent. a new trace is started if parent is empty or malformed
+func Start(parent string, name string) *Span {
+	return StartAt(parent, name, time.Now())
+}
+
+// StartAt starts a span which began at start, such as the time a message was queued
+func StartAt(parent string, name string, start time.Time) *Span {
+	span := &Span{Name: name, Start: start, Attributes: map[string]string{}}
+	if sc, ok := ParseTraceParent(parent); ok {
+		span.Context.TraceID, span.ParentSpanID, span.Context.Flags = sc.TraceID, sc.SpanID, sc.Flags
+	} else {
+		rand.Read(span.Context.TraceID[:])
+		span.Context.Flags = FlagSampled
+	}
+	rand.Read(span.Context.SpanID[:])
+	return span
+}
+
+// TraceParent is the traceparent to propagate, so that spans of callees are children of this span
+func (span *Span) TraceParent() string {
+	return span.Context.TraceParent()
+}
+
+// SetAttr sets an attribute of the span
+func (span *Span) SetAttr(key, value string) *Span {
+	span.Attributes[key] = value
+	return span
+}
+
+// Finish ends the span with the error of the operation, and exports it
+func (span *Span) Finish(err error) {
+	span.FinishAt(err, time.Now())
+}
+
+// FinishAt ends the span at end, and exports it
+func (span *Span) FinishAt(err error, end time.Time) {
+	if span.End = end; err != nil {
+		span.Err = err.Error()
+	}
+	if e := currentExporter(); e != nil && span.Context.Flags&FlagSampled != 0 {
+		e.Export(span)
+	}
+}
@@ -1,19 +1,20 @@
 package saavuu
 
 import (
-	"errors"
+	"github.com/yangkequn/saavuu/specification"
 )
 
-var ErrJWT error = errors.New("JWT error")
-var ErrParm error = errors.New("parameter error")
-var ErrInvalidData error = errors.New("invalid data")
-var ErrInvalidInput error = errors.New("invalid input")
-var ErrInvalidField error = errors.New("invalid field")
-var ErrInvalidJwtField error = errors.New("invalid jwt field")
-var ErrInvalidJwt error = errors.New("invalid jwt")
-var ErrInvalidKey error = errors.New("invalid key")
-var ErrInvalidValue error = errors.New("invalid value")
-var ErrInvalidType error = errors.New("invalid type")
-var ErrInvalidMethod error = errors.New("invalid method")
-var ErrInvalidAuth error = errors.New("invalid auth")
-var ErrInvalidUserOrPassword error = errors.New("invalid user or password")
+// errors are defined in package specification, so that they can be used by packages imported here
+var ErrJWT error = specification.ErrJWT
+var ErrParm error = specification.ErrParm
+var ErrInvalidData error = specification.ErrInvalidData
+var ErrInvalidInput error = specification.ErrInvalidInput
+var ErrInvalidField error = specification.ErrInvalidField
+var ErrInvalidJwtField error = specification.ErrInvalidJwtField
+var ErrInvalidJwt error = specification.ErrInvalidJwt
+var ErrInvalidKey error = specification.ErrInvalidKey
+var ErrInvalidValue error = specification.ErrInvalidValue
+var ErrInvalidType error = specification.ErrInvalidType
+var ErrInvalidMethod error = specification.ErrInvalidMethod
+var ErrInvalidAuth error = specification.ErrInvalidAuth
+var ErrInvalidUserOrPassword error = specification.ErrInvalidUserOrPassword